@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
 	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/config"
 	"github.com/harikb/dovetail/internal/util"
 )
 
@@ -22,9 +28,26 @@ the actions you've specified in the action file.
 WARNING: This command will modify your filesystem. Always run 'dry-run'
 first to preview the actions that will be taken.
 
+Unless --no-journal is given, every change is recorded in an undo journal
+(dovetail_undo_<session>.jsonl) and overwritten or deleted content is backed
+up next to it, so the session can be reversed with 'dovetail undo'. Journals
+go to $XDG_STATE_HOME/dovetail (~/.local/state/dovetail) unless --journal-dir
+names another directory outside the compared trees.
+
+With --interactive, every delete, every move and every copy that would
+overwrite an existing file is confirmed individually instead of once up front: answer
+y (yes), n (skip it), a (yes to all remaining) or q (stop here). Copies that
+create new files run without asking.
+
+With --report, the outcome of every action and the totals are also written
+to a file for automation: CSV when the name ends in .csv, JSON otherwise.
+Each entry has a status of ok, skipped or failed.
+
 Examples:
   dovetail apply actions.txt --left /path/to/source --right /path/to/target
-  dovetail apply my_sync.txt -l ./src -r ./backup --force`,
+  dovetail apply my_sync.txt -l ./src -r ./backup --force
+  dovetail apply actions.txt -l ./src -r ./backup --interactive
+  dovetail apply actions.txt -l ./src -r ./backup --force --report apply.json`,
 	Args: cobra.ExactArgs(1),
 	RunE: runApply,
 }
@@ -33,6 +56,15 @@ var (
 	applyLeftDir  string
 	applyRightDir string
 	forceApply    bool
+	preserveApply bool
+	newerOnly     bool
+	journalDir    string
+	noJournal     bool
+	applyReport   string
+	applyParallel int
+	interactive   bool
+	backupDir     string
+	postApplyHook string
 )
 
 func init() {
@@ -42,6 +74,16 @@ func init() {
 	applyCmd.Flags().StringVarP(&applyLeftDir, "left", "l", "", "left directory path (required)")
 	applyCmd.Flags().StringVarP(&applyRightDir, "right", "r", "", "right directory path (required)")
 	applyCmd.Flags().BoolVar(&forceApply, "force", false, "skip confirmation prompt")
+	applyCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "confirm each delete, move and overwrite individually")
+	applyCmd.Flags().BoolVar(&preserveApply, "preserve", false, "preserve modification times and ownership of copied files")
+
+	applyCmd.Flags().BoolVar(&newerOnly, "newer-only", false, "only overwrite files whose destination is older than the source")
+	applyCmd.Flags().StringVar(&journalDir, "journal-dir", "", "directory for the undo journal and backups (default $XDG_STATE_HOME/dovetail)")
+	applyCmd.Flags().BoolVar(&noJournal, "no-journal", false, "do not record an undo journal or back up overwritten files")
+	applyCmd.Flags().StringVar(&backupDir, "backup-dir", "", "copy files about to be overwritten or deleted here first, mirrored under left/ and right/")
+	applyCmd.Flags().IntVar(&applyParallel, "parallel", 1, "run up to this many independent actions at once (0 = number of CPUs)")
+	applyCmd.Flags().StringVar(&postApplyHook, "post-apply", "", "shell command to run after all actions succeed, e.g. to reload a service")
+	applyCmd.Flags().StringVar(&applyReport, "report", "", "write a JSON (or .csv) report of each action's outcome to this file")
 
 	// Mark as required
 	applyCmd.MarkFlagRequired("left")
@@ -54,35 +96,71 @@ func runApply(cmd *cobra.Command, args []string) error {
 	// Validate action file exists
 	if _, err := os.Stat(actionFile); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("action file does not exist: %s", actionFile)
+			return validationErrorf("action file does not exist: %s", actionFile)
 		}
-		return fmt.Errorf("failed to access action file %s: %w", actionFile, err)
+		return validationErrorf("failed to access action file %s: %w", actionFile, err)
 	}
 
 	// Validate directories exist
 	if err := validateDirectory(applyLeftDir); err != nil {
-		return fmt.Errorf("left directory: %w", err)
+		return validationErrorf("left directory: %w", err)
 	}
 	if err := validateDirectory(applyRightDir); err != nil {
-		return fmt.Errorf("right directory: %w", err)
+		return validationErrorf("right directory: %w", err)
 	}
 
 	// Convert to absolute paths
 	leftDir, err := filepath.Abs(applyLeftDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve left directory path: %w", err)
+		return executionErrorf("failed to resolve left directory path: %w", err)
 	}
 	rightDir, err := filepath.Abs(applyRightDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve right directory path: %w", err)
+		return executionErrorf("failed to resolve right directory path: %w", err)
 	}
 	actionFile, err = filepath.Abs(actionFile)
 	if err != nil {
-		return fmt.Errorf("failed to resolve action file path: %w", err)
+		return executionErrorf("failed to resolve action file path: %w", err)
+	}
+	if backupDir != "" {
+		if backupDir, err = filepath.Abs(backupDir); err != nil {
+			return executionErrorf("failed to resolve backup directory path: %w", err)
+		}
+		// Backups inside a compared tree would show up in later comparisons
+		if isWithin(leftDir, backupDir) || isWithin(rightDir, backupDir) {
+			return usageErrorf("--backup-dir must be outside the left and right directories")
+		}
+	}
+	if !noJournal {
+		if journalDir, err = resolveJournalDir(journalDir, leftDir, rightDir); err != nil {
+			return err
+		}
 	}
 
-	// Safety confirmation unless --force is used
-	if !forceApply {
+	// Load configuration
+	loader := config.NewLoader(GetVerboseLevel())
+	cfg, err := loader.Load("")
+	if err != nil {
+		return validationErrorf("failed to load configuration: %w", err)
+	}
+	config.ApplyCLIOverrides(cfg, config.CLIConfig{
+		VerboseLevel:     GetVerboseLevel(),
+		PreserveMetadata: preserveApply,
+		PostApplyHook:    postApplyHook,
+	})
+
+	if interactive && forceApply {
+		return usageErrorf("cannot use both --interactive and --force")
+	}
+	if applyParallel < 0 {
+		return usageErrorf("--parallel must be 0 or more, got %d", applyParallel)
+	}
+	if applyParallel == 0 {
+		applyParallel = runtime.NumCPU()
+	}
+
+	// Safety confirmation unless --force is used; --interactive asks per action instead
+	if !forceApply && !interactive {
 		fmt.Printf("WARNING: This will execute file operations that may modify or delete files.\n")
 		fmt.Printf("Action file: %s\n", actionFile)
 		fmt.Printf("Left dir:    %s\n", leftDir)
@@ -105,34 +183,76 @@ func runApply(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	// Parse action file
-	file, err := os.Open(actionFile)
+	// Parse and validate action file
+	actionFileData, err := readActionFile(actionFile, leftDir, rightDir)
 	if err != nil {
-		return fmt.Errorf("failed to open action file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	parser := action.NewParser()
-	actionFileData, err := parser.ParseActionFile(file)
-	if err != nil {
-		return fmt.Errorf("failed to parse action file: %w", err)
+	// Execute actions
+	executor := action.NewConfiguredExecutor(cfg.General)
+	executor.SetNewerOnly(newerOnly)
+	executor.SetParallelActions(applyParallel)
+	executor.SetBackupDir(backupDir)
+	executor.SetVerboseLevel(cfg.General.Verbose)
+	if interactive {
+		executor.SetConfirm(newActionPrompt(bufio.NewReader(os.Stdin)))
+	}
+
+	// Verbose output logs each action; otherwise keep one live line so long
+	// copies don't look frozen. Prompts from --interactive would overwrite it,
+	// and redirected stderr would fill up with escape sequences.
+	showedProgress := false
+	if cfg.General.Verbose == 0 && !interactive && isatty.IsTerminal(os.Stderr.Fd()) {
+		executor.SetProgressFunc(func(done, total int, elapsed time.Duration) {
+			fmt.Fprintf(os.Stderr, "\r\033[KApplying %s", util.FormatProgress(done, total, elapsed))
+			showedProgress = true
+		})
 	}
 
-	// Validate action file
-	validationErrors := parser.ValidateActionFile(actionFileData, leftDir, rightDir)
-	if len(validationErrors) > 0 {
-		fmt.Printf("Validation errors found:\n")
-		for _, err := range validationErrors {
-			fmt.Printf("  %s\n", err.Error())
+	var journal *action.Journal
+	if !noJournal {
+		journal, err = action.NewJournal(journalDir, leftDir, rightDir)
+		if err != nil {
+			return executionErrorf("failed to create undo journal: %w", err)
 		}
-		return fmt.Errorf("action file contains validation errors")
+		executor.SetJournal(journal)
+	}
+
+	// Create the report before changing anything, so a bad path fails early
+	var reportFile *os.File
+	if applyReport != "" {
+		reportFile, err = os.Create(applyReport)
+		if err != nil {
+			return executionErrorf("failed to create report file: %w", err)
+		}
+		defer reportFile.Close()
 	}
 
-	// Execute actions
-	executor := action.NewExecutor(false) // false for real execution
 	summary, results, err := executor.ExecuteActions(actionFileData, leftDir, rightDir)
+	if showedProgress {
+		fmt.Fprintln(os.Stderr)
+	}
 	if err != nil {
-		return fmt.Errorf("execution failed: %w", err)
+		return executionErrorf("execution failed: %w", err)
+	}
+
+	if reportFile != nil {
+		report := action.NewReport(actionFile, leftDir, rightDir, summary, results)
+		if err := report.Write(reportFile, action.ReportFormatForPath(applyReport)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write report %s: %v\n", applyReport, err)
+		} else {
+			defer fmt.Printf("\nReport: %s\n", applyReport)
+		}
+	}
+
+	if journal != nil {
+		if len(journal.Entries) == 0 {
+			// Nothing changed, so there is nothing to undo
+			os.Remove(journal.Path())
+		} else {
+			defer fmt.Printf("\nUndo journal: %s\n  dovetail undo %s  # to reverse these changes\n", journal.Path(), journal.Path())
+		}
 	}
 
 	// Display results
@@ -144,6 +264,10 @@ func runApply(cmd *cobra.Command, args []string) error {
 	fmt.Printf("\n")
 
 	if len(results) == 0 {
+		if summary.Aborted {
+			fmt.Printf("Stopped before any actions were performed.\n")
+			return nil
+		}
 		fmt.Printf("No actions were performed (all actions were set to ignore).\n")
 		return nil
 	}
@@ -152,7 +276,9 @@ func runApply(cmd *cobra.Command, args []string) error {
 	successCount := 0
 	for _, result := range results {
 		if result.Success {
-			if GetVerboseLevel() >= 1 {
+			if result.Skipped {
+				fmt.Printf("- %s\n", result.Message)
+			} else if GetVerboseLevel() >= 1 {
 				fmt.Printf("✓ %s\n", result.Message)
 			}
 			successCount++
@@ -167,6 +293,7 @@ func runApply(cmd *cobra.Command, args []string) error {
 	fmt.Printf("\nExecution Summary:\n")
 	fmt.Printf("==================\n")
 	fmt.Printf("Total actions attempted: %d\n", len(results))
+	printActionTally(actionFileData.Actions)
 	fmt.Printf("Successful actions: %d\n", successCount)
 	fmt.Printf("Failed actions: %d\n", len(results)-successCount)
 
@@ -179,18 +306,129 @@ func runApply(cmd *cobra.Command, args []string) error {
 	if summary.FilesDeleted > 0 {
 		fmt.Printf("Files deleted: %d\n", summary.FilesDeleted)
 	}
+	if summary.FilesChmodded > 0 {
+		fmt.Printf("Permissions changed: %d\n", summary.FilesChmodded)
+	}
+	if summary.FilesMoved > 0 {
+		fmt.Printf("Files moved: %d\n", summary.FilesMoved)
+	}
+	if summary.FilesSkipped > 0 {
+		fmt.Printf("Files skipped (destination not older): %d\n", summary.FilesSkipped)
+	}
+	if summary.ActionsDeclined > 0 {
+		fmt.Printf("Actions declined: %d\n", summary.ActionsDeclined)
+	}
 	if summary.BytesCopied > 0 {
 		fmt.Printf("Data copied: %s\n", util.FormatSize(summary.BytesCopied))
 	}
 
+	if len(summary.Warnings) > 0 {
+		fmt.Printf("\nWarnings:\n")
+		for _, warning := range summary.Warnings {
+			fmt.Printf("  %s\n", warning)
+		}
+	}
+
 	if len(summary.Errors) > 0 {
 		fmt.Printf("\nErrors encountered:\n")
 		for _, errMsg := range summary.Errors {
 			fmt.Printf("  %s\n", errMsg)
 		}
-		return fmt.Errorf("execution completed with %d errors", len(summary.Errors))
+		if successCount > 0 {
+			return partialFailureErrorf("execution completed with %d errors", len(summary.Errors))
+		}
+		return executionErrorf("execution completed with %d errors", len(summary.Errors))
+	}
+
+	if summary.Aborted {
+		fmt.Printf("\nStopped at user request; the remaining actions were not performed.\n")
+		return nil
 	}
 
 	fmt.Printf("\nExecution completed successfully!\n")
+
+	if hook := cfg.General.PostApplyHook; hook != "" {
+		fmt.Printf("\nRunning post-apply hook: %s\n", hook)
+		env := action.PostApplyEnv(leftDir, rightDir, actionFile, len(results), summary, journal)
+		if err := action.RunPostApplyHook(hook, env, os.Stdin, os.Stdout, os.Stderr); err != nil {
+			return executionErrorf("post-apply hook failed: %w", err)
+		}
+	}
 	return nil
 }
+
+// newActionPrompt returns a ConfirmFunc that asks on stdout and reads answers
+// from input. End of input stops execution, like q.
+func newActionPrompt(input *bufio.Reader) action.ConfirmFunc {
+	return func(item action.ActionItem, overwrite bool) action.ConfirmResponse {
+		var effect string
+		switch item.Action {
+		case action.ActionCopyToRight:
+			effect = "overwrites right"
+		case action.ActionCopyToLeft:
+			effect = "overwrites left"
+		case action.ActionDeleteLeft:
+			effect = "deletes from left"
+		case action.ActionDeleteRight:
+			effect = "deletes from right"
+		case action.ActionDeleteBoth:
+			effect = "deletes from both sides"
+		case action.ActionChmodToRight:
+			effect = "changes right's permissions"
+		case action.ActionChmodToLeft:
+			effect = "changes left's permissions"
+		case action.ActionSymlinkToRight:
+			effect = "creates a symlink on right"
+		case action.ActionSymlinkToLeft:
+			effect = "creates a symlink on left"
+		case action.ActionMoveRight:
+			effect = "moves right's file"
+		case action.ActionMoveLeft:
+			effect = "moves left's file"
+		}
+		target := item.RelativePath
+		if item.Destination != "" {
+			target += " -> " + item.Destination
+		}
+
+		for {
+			fmt.Printf("[%s] %s (%s) - apply? [y/n/a/q]: ", item.Action, target, effect)
+			line, err := input.ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Println()
+				return action.ConfirmQuit
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "y", "yes":
+				return action.ConfirmYes
+			case "n", "no":
+				return action.ConfirmNo
+			case "a", "all":
+				return action.ConfirmAll
+			case "q", "quit":
+				return action.ConfirmQuit
+			}
+			fmt.Printf("Please answer y, n, a or q.\n")
+		}
+	}
+}
+
+// resolveJournalDir returns the absolute undo journal directory: dir, or the
+// default when empty. Like backups, journals must stay out of the compared
+// trees, or they would show up in later comparisons and could be overwritten.
+func resolveJournalDir(dir, leftDir, rightDir string) (string, error) {
+	if dir == "" {
+		var err error
+		if dir, err = action.DefaultJournalDir(); err != nil {
+			return "", executionErrorf("failed to find a directory for the undo journal: %w; use --journal-dir", err)
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", executionErrorf("failed to resolve journal directory path: %w", err)
+	}
+	if isWithin(leftDir, dir) || isWithin(rightDir, dir) {
+		return "", usageErrorf("undo journal directory %s must be outside the left and right directories; use --journal-dir", dir)
+	}
+	return dir, nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+)
+
+// validateSource accepts a directory or a supported archive file
+func validateSource(path string) error {
+	if compare.IsArchive(path) {
+		return nil
+	}
+	return validateDirectory(path)
+}
+
+// checkTempDir fails early if a configured temporary directory can't be
+// written to, rather than partway through extracting or exporting
+func checkTempDir(cfg *config.Config) error {
+	dir := cfg.General.TempRoot()
+	if dir == "" {
+		return nil
+	}
+	probe, err := os.CreateTemp(dir, ".dovetail-probe-")
+	if err != nil {
+		return validationErrorf("temporary directory %s (from %s or general.temp_dir) is not writable: %w", dir, config.TempDirEnv, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// contentDir returns a directory holding the files of source that diffs need.
+// Directories are returned unchanged; for archives the modified entries are
+// extracted to a temporary directory under tempRoot that cleanup removes.
+func contentDir(source string, results []compare.ComparisonResult, tempRoot string) (dir string, cleanup func(), err error) {
+	if !compare.IsArchive(source) {
+		return source, func() {}, nil
+	}
+
+	modified := make(map[string]bool)
+	for _, result := range results {
+		if result.Status == compare.StatusModified {
+			modified[result.LeftPath()] = true
+			modified[result.RightPath()] = true
+		}
+	}
+
+	tempDir, err := os.MkdirTemp(tempRoot, "dovetail-archive-")
+	if err != nil {
+		return "", nil, executionErrorf("failed to create temporary directory: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(tempDir) }
+
+	if err := compare.ExtractArchive(source, tempDir, func(relPath string) bool { return modified[relPath] }); err != nil {
+		cleanup()
+		return "", nil, executionErrorf("failed to extract %s: %w", source, err)
+	}
+	return tempDir, cleanup, nil
+}
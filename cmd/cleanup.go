@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/action"
+)
+
+// cleanupCmd represents the cleanup command
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup [DIR] (--list | --session ID)",
+	Short: "List or remove the action and patch files of past sessions",
+	Long: `Find the action files saved from the TUI (dovetail_actions_<ID>.txt) and the
+saved patches (<file>.<ID>.patch) under DIR, the current directory by default,
+and group them by session ID, the YYYYMMDD_HHMMSS time they were written.
+
+--list prints each session with its file counts. --session removes only the
+files of one session, leaving the others in place. Undo journals are never
+touched; they are needed by 'undo'.
+
+Examples:
+  dovetail cleanup --list
+  dovetail cleanup --session 20240115_143000
+  dovetail cleanup ./project --session 20240115_143000 --force`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCleanup,
+}
+
+var (
+	cleanupList    bool
+	cleanupSession string
+	forceCleanup   bool
+)
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+
+	cleanupCmd.Flags().BoolVar(&cleanupList, "list", false, "list the sessions found with their file counts")
+	cleanupCmd.Flags().StringVar(&cleanupSession, "session", "", "remove the action and patch files of this session (YYYYMMDD_HHMMSS)")
+	cleanupCmd.Flags().BoolVar(&forceCleanup, "force", false, "skip confirmation prompt")
+}
+
+func runCleanup(cmd *cobra.Command, args []string) error {
+	if cleanupList == (cleanupSession != "") {
+		return usageErrorf("specify exactly one of --list or --session")
+	}
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+		if err := validateDirectory(dir); err != nil {
+			return validationErrorf("%w", err)
+		}
+	}
+
+	files, err := action.FindSessionFiles(dir)
+	if err != nil {
+		return executionErrorf("failed to search %s: %w", dir, err)
+	}
+
+	if cleanupList {
+		printSessions(files)
+		return nil
+	}
+
+	session := cleanupSession
+	if !action.IsSessionID(session) {
+		return usageErrorf("invalid session ID %q: expected YYYYMMDD_HHMMSS", cleanupSession)
+	}
+	var matched []action.SessionFile
+	for _, file := range files {
+		if file.Session == session {
+			matched = append(matched, file)
+		}
+	}
+	if len(matched) == 0 {
+		fmt.Printf("No files found for session %s.\n", session)
+		return nil
+	}
+
+	fmt.Printf("Files of session %s:\n", session)
+	for _, file := range matched {
+		fmt.Printf("  %s\n", file.Path)
+	}
+	if !forceCleanup {
+		fmt.Printf("\nRemove these %d file(s)? [y/N]: ", len(matched))
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" && response != "yes" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	removed := 0
+	for _, file := range matched {
+		if err := os.Remove(file.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		removed++
+	}
+	fmt.Printf("Removed %d of %d file(s).\n", removed, len(matched))
+	if removed < len(matched) {
+		return executionErrorf("%d file(s) could not be removed", len(matched)-removed)
+	}
+	return nil
+}
+
+// printSessions prints one row per session, oldest first, with the number of
+// action and patch files it left behind
+func printSessions(files []action.SessionFile) {
+	if len(files) == 0 {
+		fmt.Println("No sessions found.")
+		return
+	}
+	fmt.Printf("%-17s %7s %7s\n", "SESSION", "ACTIONS", "PATCHES")
+	for i := 0; i < len(files); {
+		session := files[i].Session
+		actions, patches := 0, 0
+		for ; i < len(files) && files[i].Session == session; i++ {
+			if files[i].IsPatch {
+				patches++
+			} else {
+				actions++
+			}
+		}
+		fmt.Printf("%-17s %7d %7d\n", session, actions, patches)
+	}
+}
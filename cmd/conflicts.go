@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/diff"
+)
+
+// conflictsCmd represents the conflicts command
+var conflictsCmd = &cobra.Command{
+	Use:   "conflicts <DIR_LEFT> <DIR_RIGHT> -o <OUTPUT_DIR>",
+	Short: "Write modified text files with conflict markers for manual merging",
+	Long: `Compare two directories and, for every modified text file, write a merged
+version to the output directory in which each changed region holds both sides
+between git-style conflict markers:
+
+  <<<<<<< LEFT_PATH
+  left lines
+  =======
+  right lines
+  >>>>>>> RIGHT_PATH
+
+Nothing in either directory is changed. Resolve the markers in your editor,
+then copy the results where they belong. Binary files are skipped.
+
+Examples:
+  dovetail conflicts ./src ./backup -o ./merged
+  dovetail conflicts ./src ./backup -o ./merged --exclude-name node_modules`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConflicts,
+}
+
+var (
+	conflictsOutputDir         string
+	conflictsExcludeNames      []string
+	conflictsExcludePaths      []string
+	conflictsExcludeExtensions []string
+	conflictsExcludeRegex      []string
+	conflictsContentRegex      []string
+	conflictsIncludePaths      []string
+	conflictsUseGitignore      bool
+	conflictsIgnoreCase        bool
+	conflictsNoDefaultExcludes bool
+	conflictsHashAlgorithm     string
+)
+
+func init() {
+	rootCmd.AddCommand(conflictsCmd)
+
+	conflictsCmd.Flags().StringVarP(&conflictsOutputDir, "output", "o", "", "directory to write conflict-marked files to (required)")
+	conflictsCmd.MarkFlagRequired("output")
+
+	// Exclusion options
+	conflictsCmd.Flags().StringSliceVar(&conflictsExcludeNames, "exclude-name", []string{}, "exclude files/directories by name or glob pattern")
+	conflictsCmd.Flags().StringSliceVar(&conflictsExcludePaths, "exclude-path", []string{}, "exclude files/directories by relative path")
+	conflictsCmd.Flags().StringSliceVar(&conflictsExcludeExtensions, "exclude-ext", []string{}, "exclude files by extension (without dot)")
+	conflictsCmd.Flags().StringSliceVar(&conflictsExcludeRegex, "exclude-regex", []string{}, "exclude files/directories whose relative path matches a regular expression")
+	conflictsCmd.Flags().StringSliceVar(&conflictsContentRegex, "exclude-content-regex", []string{}, "exclude files whose first 4 KiB match a regular expression, e.g. a generated-code marker")
+	conflictsCmd.Flags().StringSliceVar(&conflictsIncludePaths, "include-path", []string{}, "only compare these relative paths and their contents")
+	conflictsCmd.Flags().BoolVar(&conflictsUseGitignore, "use-gitignore", false, "read and apply .gitignore rules from both directories")
+	conflictsCmd.Flags().BoolVar(&conflictsIgnoreCase, "ignore-case", false, "match paths that differ only in letter case")
+	conflictsCmd.Flags().BoolVar(&conflictsNoDefaultExcludes, "no-default-excludes", false, "don't apply the built-in exclusions enabled by exclusions.use_defaults")
+
+	// Comparison options
+	conflictsCmd.Flags().StringVar(&conflictsHashAlgorithm, "hash-algo", compare.DefaultHashAlgorithm, "hash algorithm for content comparison (sha256, blake3, xxhash)")
+}
+
+func runConflicts(cmd *cobra.Command, args []string) error {
+	leftDir := args[0]
+	rightDir := args[1]
+
+	// Both sides are read as files on disk, so archives aren't supported
+	if err := validateDirectory(leftDir); err != nil {
+		return validationErrorf("left directory: %w", err)
+	}
+	if err := validateDirectory(rightDir); err != nil {
+		return validationErrorf("right directory: %w", err)
+	}
+	if _, err := exec.LookPath("diff"); err != nil {
+		return executionErrorf("conflicts requires the Unix 'diff' command: %w", err)
+	}
+
+	// Convert to absolute paths
+	leftDir, err := filepath.Abs(leftDir)
+	if err != nil {
+		return executionErrorf("failed to resolve left directory path: %w", err)
+	}
+	rightDir, err = filepath.Abs(rightDir)
+	if err != nil {
+		return executionErrorf("failed to resolve right directory path: %w", err)
+	}
+	outputDir, err := filepath.Abs(conflictsOutputDir)
+	if err != nil {
+		return executionErrorf("failed to resolve output directory path: %w", err)
+	}
+	// Output inside a compared tree would show up in the next comparison
+	if isWithin(leftDir, outputDir) || isWithin(rightDir, outputDir) {
+		return usageErrorf("--output must be outside the left and right directories")
+	}
+
+	// Load configuration
+	loader := config.NewLoader(GetVerboseLevel())
+	cfg, err := loader.Load("")
+	if err != nil {
+		return validationErrorf("failed to load configuration: %w", err)
+	}
+
+	cliConfig := config.CLIConfig{
+		VerboseLevel:      GetVerboseLevel(),
+		ExcludeNames:      conflictsExcludeNames,
+		ExcludePaths:      conflictsExcludePaths,
+		ExcludeExtensions: conflictsExcludeExtensions,
+		ExcludeRegex:      conflictsExcludeRegex,
+		ContentRegex:      conflictsContentRegex,
+		IncludePaths:      conflictsIncludePaths,
+		UseGitignore:      conflictsUseGitignore,
+		IgnoreCase:        conflictsIgnoreCase,
+		NoDefaultExcludes: conflictsNoDefaultExcludes,
+	}
+	config.ApplyCLIOverrides(cfg, cliConfig)
+
+	// Process gitignore if enabled
+	ignoreRules, err := gitignoreRules(cfg, leftDir, rightDir)
+	if err != nil {
+		return err
+	}
+
+	options := comparisonOptions(cfg, ignoreRules)
+	options.HashAlgorithm = resolveHashAlgorithm(conflictsHashAlgorithm)
+
+	engine, err := compare.NewEngine(options)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
+	engine.SetVerboseLevel(cfg.General.Verbose)
+
+	results, _, err := engine.Compare(leftDir, rightDir)
+	if err != nil {
+		return executionErrorf("comparison failed: %w", err)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].RelativePath < results[j].RelativePath
+	})
+
+	written, regions, skipped := 0, 0, 0
+	for _, result := range results {
+		if result.Status != compare.StatusModified || !result.Changes.Has(compare.ChangeContent) {
+			continue
+		}
+		if result.LeftInfo.IsDir || result.RightInfo.IsDir || result.LeftInfo.IsSymlink || result.RightInfo.IsSymlink {
+			continue
+		}
+
+		leftPath := filepath.Join(leftDir, result.LeftPath())
+		rightPath := filepath.Join(rightDir, result.RightPath())
+		if binary, err := isBinaryPair(leftPath, rightPath); err != nil {
+			return executionErrorf("failed to read %s: %w", result.RelativePath, err)
+		} else if binary {
+			fmt.Fprintf(os.Stderr, "Skipping binary file: %s\n", result.RelativePath)
+			skipped++
+			continue
+		}
+
+		count, err := writeConflictFile(leftPath, rightPath, filepath.Join(outputDir, result.RelativePath))
+		if err != nil {
+			return executionErrorf("%s: %w", result.RelativePath, err)
+		}
+		fmt.Printf("  %s (%d conflict(s))\n", result.RelativePath, count)
+		written++
+		regions += count
+	}
+
+	if written == 0 {
+		fmt.Println("No modified text files found.")
+	} else {
+		fmt.Printf("Wrote %d file(s) with %d conflict(s) to %s\n", written, regions, outputDir)
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped %d binary file(s)\n", skipped)
+	}
+	return nil
+}
+
+// writeConflictFile writes the conflict-marked merge of two files to outPath
+// and returns the number of conflict blocks
+func writeConflictFile(leftPath, rightPath, outPath string) (int, error) {
+	left, err := os.ReadFile(leftPath)
+	if err != nil {
+		return 0, err
+	}
+
+	// Without context lines every hunk is exactly one changed region
+	output, err := exec.Command("diff", "-U", "0", leftPath, rightPath).Output()
+	if err != nil {
+		// diff exits 1 when the files differ
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return 0, fmt.Errorf("diff failed: %w", err)
+		}
+	}
+
+	merged, count := diff.ConflictMarked(string(left), diff.ParseHunks(string(output)), leftPath, rightPath)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(outPath, []byte(merged), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write conflict file: %w", err)
+	}
+	return count, nil
+}
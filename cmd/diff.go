@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -12,6 +15,9 @@ import (
 	"github.com/harikb/dovetail/internal/action"
 	"github.com/harikb/dovetail/internal/compare"
 	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/diff"
+	"github.com/harikb/dovetail/internal/theme"
+	"github.com/harikb/dovetail/internal/util"
 )
 
 // diffCmd represents the diff command
@@ -21,11 +27,18 @@ var diffCmd = &cobra.Command{
 	Long: `Compare two directories recursively and generate an action file that can be 
 used to synchronize them. The action file will contain all differences with default
 'ignore' actions, which you can then edit to specify the desired synchronization actions.
+Either side may also be a .tar, .tar.gz/.tgz or .zip archive, compared as if unpacked.
 
 Examples:
   dovetail diff /path/to/source /path/to/target -o actions.txt
   dovetail diff ./src ./backup --show-diff --ignore-whitespace
-  dovetail diff dir1 dir2 --exclude-name "*.log" "*.tmp" --exclude-path "build/"`,
+  dovetail diff dir1 dir2 --exclude-name "*.log" "*.tmp" --exclude-path "build/"
+  dovetail diff release-1.2.tar.gz ./src --show-diff
+  find . -name '*.go' | dovetail diff . ../other --left-list - --show-diff
+
+Exit status (like git diff --exit-code):
+  With --exit-code, exits 0 when the directories are identical and 1 when
+  differences exist. Any status of 2 or higher indicates an error.`,
 	Args: cobra.ExactArgs(2),
 	RunE: runDiff,
 }
@@ -39,26 +52,124 @@ var (
 	excludeNames      []string
 	excludePaths      []string
 	excludeExtensions []string
+	excludeRegex      []string
+	contentRegex      []string
+	includePaths      []string
 	useGitignore      bool
+	ignoreCase        bool
+	noDefaultExcludes bool
+	noIgnoreFiles     bool
+	hashAlgorithm     string
+	exitCode          bool
+	wordDiff          bool
+	patchOutFile      string
+	diffContext       int
+	mirrorSide        string
+	preferSide        string
+	binaryStat        bool
+	quickCompare      bool
+	minSize           string
+	maxSize           string
+	timeTolerance     int
+	outputFormat      string
+	maxDepth          int
+	ignoreLineEndings bool
+	detectHardlinks   bool
+	detectRenames     bool
+	pagerCommand      string
+	noPager           bool
+	sampledHash       string
+	sampleBlockSize   string
+	noHashCache       bool
+	pathsFrom         string
+	leftList          string
+	reproducible      bool
+	maxFiles          int
 )
 
+// diffDisplayOptions controls how file differences are printed
+type diffDisplayOptions struct {
+	Palette  theme.Palette // Colors for headings and side labels
+	NoColor  bool          // Disable ANSI colors
+	WordDiff bool          // Highlight changed words within modified lines
+	Context  int           // Lines of unified diff context
+
+	DiffCommand string // External diff program and arguments (empty = diff or colordiff)
+
+	BinaryStat  bool  // Summarize binary files byte by byte instead of "Binary files differ"
+	MaxFileSize int64 // Files larger than this are not read for the binary summary
+
+	MaxFiles int // Modified files shown by --show-diff, in path order (0 = all)
+
+	// Names shown for each side when the content is read from elsewhere,
+	// e.g. the archive whose entries were extracted to a temporary directory
+	LeftName  string
+	RightName string
+}
+
+// sideNames returns the names to display for the directories diffs read from
+func (o diffDisplayOptions) sideNames(leftDir, rightDir string) (string, string) {
+	if o.LeftName != "" {
+		leftDir = o.LeftName
+	}
+	if o.RightName != "" {
+		rightDir = o.RightName
+	}
+	return leftDir, rightDir
+}
+
 func init() {
 	rootCmd.AddCommand(diffCmd)
 
 	// Output options
 	diffCmd.Flags().StringVarP(&outputFile, "output", "o", "", "output action file path (required unless --show-diff)")
+	diffCmd.Flags().StringVar(&outputFormat, "format", "action", "output file format: action, csv, tsv (one row per compared path) or jsonl (one JSON object per result, streamed to stdout without -o)")
 	diffCmd.Flags().BoolVar(&includeIdentical, "include-identical", false, "include identical files in action file (default: only show different files)")
+	diffCmd.Flags().BoolVar(&exitCode, "exit-code", false, "exit with status 1 if differences were found")
+	diffCmd.Flags().StringVar(&patchOutFile, "patch-out", "", "write one unified diff of all modified text files (for git apply)")
+	diffCmd.Flags().BoolVar(&reproducible, "reproducible", false, "leave the generation time out of the action file so identical comparisons produce identical files")
+	diffCmd.Flags().StringVar(&mirrorSide, "mirror", "", "pre-fill actions that make the other side an exact copy of this side (left or right)")
+	diffCmd.Flags().StringVar(&preferSide, "prefer", "", "pre-fill actions that copy the preferred version of each differing file (newer, older, left or right)")
 
 	// Display options
 	diffCmd.Flags().BoolVar(&showDiff, "show-diff", false, "display inline diffs instead of generating action file")
 	diffCmd.Flags().StringVar(&showDiffFile, "show-diff-file", "", "show diff for specific file (relative path from either directory)")
 	diffCmd.Flags().BoolVar(&ignoreWhitespace, "ignore-whitespace", false, "ignore whitespace differences in diffs")
+	diffCmd.Flags().BoolVar(&wordDiff, "word-diff", false, "highlight changed words within modified lines")
+	diffCmd.Flags().BoolVar(&binaryStat, "binary-stat", false, "summarize differing binary files: first differing offset, differing bytes and size delta")
+	diffCmd.Flags().IntVar(&diffContext, "context", config.DefaultContextLines, "lines of context around each change in diffs")
+	diffCmd.Flags().IntVar(&maxFiles, "max-files", 0, "show the diffs of at most this many modified files with --show-diff, first by path (0 = all)")
 
 	// Exclusion options
 	diffCmd.Flags().StringSliceVar(&excludeNames, "exclude-name", []string{}, "exclude files/directories by name or glob pattern")
 	diffCmd.Flags().StringSliceVar(&excludePaths, "exclude-path", []string{}, "exclude files/directories by relative path")
 	diffCmd.Flags().StringSliceVar(&excludeExtensions, "exclude-ext", []string{}, "exclude files by extension (without dot)")
+	diffCmd.Flags().StringSliceVar(&excludeRegex, "exclude-regex", []string{}, "exclude files/directories whose relative path matches a regular expression")
+	diffCmd.Flags().StringSliceVar(&contentRegex, "exclude-content-regex", []string{}, "exclude files whose first 4 KiB match a regular expression, e.g. a generated-code marker")
+	diffCmd.Flags().StringSliceVar(&includePaths, "include-path", []string{}, "only compare these relative paths and their contents")
 	diffCmd.Flags().BoolVar(&useGitignore, "use-gitignore", false, "read and apply .gitignore rules from both directories")
+	diffCmd.Flags().BoolVar(&ignoreCase, "ignore-case", false, "match paths that differ only in letter case")
+	diffCmd.Flags().BoolVar(&noDefaultExcludes, "no-default-excludes", false, "don't apply the built-in exclusions enabled by exclusions.use_defaults")
+	diffCmd.Flags().BoolVar(&noIgnoreFiles, "no-dovetailignore", false, "don't read .dovetailignore files found while scanning")
+
+	// Comparison options
+	diffCmd.Flags().BoolVar(&ignoreLineEndings, "ignore-line-endings", false, "treat files that differ only in CRLF vs LF line endings as identical")
+	diffCmd.Flags().StringVar(&pagerCommand, "pager", "", "page --show-diff output through this program when stdout is a terminal (default $PAGER, then less)")
+	diffCmd.Flags().BoolVar(&noPager, "no-pager", false, "print --show-diff output directly, without a pager")
+	diffCmd.Flags().BoolVar(&detectHardlinks, "detect-hardlinks", false, "report files hardlinked together on one side but not the other")
+	diffCmd.Flags().BoolVar(&detectRenames, "detect-renames", false, "report a left-only and a right-only file with the same content as one RENAMED entry")
+	diffCmd.Flags().IntVar(&maxDepth, "max-depth", 0, "compare only paths up to this many levels deep (0 = unlimited)")
+	diffCmd.Flags().BoolVar(&quickCompare, "quick", false, "compare files by size and modification time only, without hashing content")
+	diffCmd.Flags().StringVar(&minSize, "min-size", "", "exclude files smaller than this size, e.g. 1K")
+	diffCmd.Flags().StringVar(&maxSize, "max-size", "", "exclude files larger than this size, e.g. 10M")
+	diffCmd.Flags().IntVar(&timeTolerance, "time-tolerance", 0, "treat modification times at most this many seconds apart as equal, e.g. 2 for FAT")
+	diffCmd.Flags().StringVar(&sampledHash, "sampled-hash", "", "hash files of at least this size (default 64M) from their size and first, middle and last blocks only")
+	diffCmd.Flags().Lookup("sampled-hash").NoOptDefVal = defaultSampledHashThreshold
+	diffCmd.Flags().StringVar(&sampleBlockSize, "sample-block-size", "1M", "size of each block read by --sampled-hash")
+	diffCmd.Flags().BoolVar(&noHashCache, "no-cache", false, "hash every file instead of reusing hashes cached by earlier runs")
+	diffCmd.Flags().StringVar(&pathsFrom, "paths-from", "", "compare only the relative paths listed in this file, one per line, without walking the directories (- reads stdin)")
+	diffCmd.Flags().StringVar(&leftList, "left-list", "", "take the left side's files from this list of paths under DIR_LEFT instead of walking it (- reads stdin)")
+	diffCmd.Flags().StringVar(&hashAlgorithm, "hash-algo", compare.DefaultHashAlgorithm, "hash algorithm for content comparison (sha256, blake3, xxhash)")
 
 	// Note: output requirement is handled dynamically in runDiff based on other flags
 }
@@ -68,67 +179,132 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	rightDir := args[1]
 
 	// Validate directories exist
-	if err := validateDirectory(leftDir); err != nil {
-		return fmt.Errorf("left directory: %w", err)
+	if err := validateSource(leftDir); err != nil {
+		return validationErrorf("left directory: %w", err)
 	}
-	if err := validateDirectory(rightDir); err != nil {
-		return fmt.Errorf("right directory: %w", err)
+	if err := validateSource(rightDir); err != nil {
+		return validationErrorf("right directory: %w", err)
 	}
 
 	// Convert to absolute paths
 	leftDir, err := filepath.Abs(leftDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve left directory path: %w", err)
+		return executionErrorf("failed to resolve left directory path: %w", err)
 	}
 	rightDir, err = filepath.Abs(rightDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve right directory path: %w", err)
+		return executionErrorf("failed to resolve right directory path: %w", err)
 	}
 
 	// Validate output requirements
-	if !showDiff && showDiffFile == "" && outputFile == "" {
-		return fmt.Errorf("output file (-o) is required when not using --show-diff or --show-diff-file")
+	streaming := outputFormat == "jsonl"
+	if !showDiff && showDiffFile == "" && outputFile == "" && patchOutFile == "" && !streaming {
+		return usageErrorf("output file (-o) is required when not using --show-diff, --show-diff-file or --patch-out")
 	}
 	if showDiff && showDiffFile != "" {
-		return fmt.Errorf("cannot use both --show-diff and --show-diff-file")
+		return usageErrorf("cannot use both --show-diff and --show-diff-file")
 	}
 	if showDiffFile != "" && outputFile != "" {
-		return fmt.Errorf("cannot use both --show-diff-file and output file (-o)")
+		return usageErrorf("cannot use both --show-diff-file and output file (-o)")
+	}
+	if maxFiles < 0 {
+		return usageErrorf("--max-files must be 0 or greater, got %d", maxFiles)
+	}
+	if maxFiles > 0 && !showDiff {
+		return usageErrorf("--max-files requires --show-diff")
+	}
+
+	var exportDelimiter rune
+	switch outputFormat {
+	case "action":
+	case "csv":
+		exportDelimiter = ','
+	case "tsv":
+		exportDelimiter = '\t'
+	case "jsonl":
+		if showDiff || showDiffFile != "" || patchOutFile != "" {
+			return usageErrorf("--format jsonl can't be combined with --show-diff, --show-diff-file or --patch-out")
+		}
+	default:
+		return usageErrorf("--format must be action, csv, tsv or jsonl, got %q", outputFormat)
+	}
+	if exportDelimiter != 0 && outputFile == "" {
+		return usageErrorf("--format %s requires an output file (-o)", outputFormat)
+	}
+
+	mirror := action.MirrorNone
+	if mirrorSide != "" {
+		var ok bool
+		if mirror, ok = action.ParseMirrorSource(mirrorSide); !ok {
+			return usageErrorf("--mirror must be left or right, got %q", mirrorSide)
+		}
+		if outputFile == "" || exportDelimiter != 0 || streaming {
+			return usageErrorf("--mirror requires an action file output (-o)")
+		}
+	}
+
+	prefer := action.PreferNone
+	if preferSide != "" {
+		var ok bool
+		if prefer, ok = action.ParsePreference(preferSide); !ok {
+			return usageErrorf("--prefer must be newer, older, left or right, got %q", preferSide)
+		}
+		if mirror != action.MirrorNone {
+			return usageErrorf("--prefer and --mirror can't be combined")
+		}
+		if outputFile == "" || exportDelimiter != 0 || streaming {
+			return usageErrorf("--prefer requires an action file output (-o)")
+		}
+	}
+
+	contextOverride, err := contextFlag(cmd, diffContext)
+	if err != nil {
+		return err
 	}
 
 	// Load configuration
 	loader := config.NewLoader(GetVerboseLevel())
 	cfg, err := loader.Load("")
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return validationErrorf("failed to load configuration: %w", err)
 	}
 
 	// Apply CLI overrides
 	cliConfig := config.CLIConfig{
 		VerboseLevel:      GetVerboseLevel(),
-		NoColor:           false, // We'll get this from viper later
+		NoColor:           GetNoColor(),
 		ExcludeNames:      excludeNames,
 		ExcludePaths:      excludePaths,
 		ExcludeExtensions: excludeExtensions,
+		ExcludeRegex:      excludeRegex,
+		ContentRegex:      contentRegex,
+		IncludePaths:      includePaths,
 		UseGitignore:      useGitignore,
+		IgnoreCase:        ignoreCase,
+		NoDefaultExcludes: noDefaultExcludes,
+		Context:           contextOverride,
+		DiffCommand:       diffCommand,
 	}
 	config.ApplyCLIOverrides(cfg, cliConfig)
+	if err := diff.CheckCommand(cfg.General.DiffCommand); err != nil {
+		return validationErrorf("%w", err)
+	}
+	if err := checkTempDir(cfg); err != nil {
+		return err
+	}
+	actionHeader, err := cfg.General.ActionHeader()
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
 
 	// Process gitignore if enabled
-	if cfg.Gitignore.Enabled {
-		gitignoreParser := config.NewGitignoreParser(cfg.General.Verbose)
-		gitignoreResult, err := gitignoreParser.ParseGitignoreFiles(leftDir, rightDir, cfg.Gitignore.CheckBothSides)
-		if err != nil {
-			return fmt.Errorf("failed to process .gitignore: %w", err)
-		}
-
-		// Add gitignore patterns to exclusions
-		cfg.Exclusions.Names = append(cfg.Exclusions.Names, gitignoreResult.Names...)
-		cfg.Exclusions.Paths = append(cfg.Exclusions.Paths, gitignoreResult.Paths...)
-		cfg.Exclusions.Extensions = append(cfg.Exclusions.Extensions, gitignoreResult.Extensions...)
+	ignoreRules, err := gitignoreRules(cfg, leftDir, rightDir)
+	if err != nil {
+		return err
 	}
 
-	if cfg.General.Verbose >= 1 {
+	// JSON lines on stdout must not be mixed with anything else
+	if cfg.General.Verbose >= 1 && !(streaming && outputFile == "") {
 		fmt.Printf("Comparing directories:\n")
 		fmt.Printf("  Left:  %s\n", leftDir)
 		fmt.Printf("  Right: %s\n", rightDir)
@@ -141,74 +317,216 @@ func runDiff(cmd *cobra.Command, args []string) error {
 		if len(cfg.Exclusions.Extensions) > 0 {
 			fmt.Printf("  Excluding extensions: %s\n", strings.Join(cfg.Exclusions.Extensions, ", "))
 		}
+		if len(cfg.Exclusions.Include) > 0 {
+			fmt.Printf("  Including only paths: %s\n", strings.Join(cfg.Exclusions.Include, ", "))
+		}
 		fmt.Println()
 	}
 
 	// Create comparison options from config
-	options := compare.ComparisonOptions{
-		ExcludeNames:      cfg.Exclusions.Names,
-		ExcludePaths:      cfg.Exclusions.Paths,
-		ExcludeExtensions: cfg.Exclusions.Extensions,
-		FollowSymlinks:    cfg.General.FollowSymlinks,
-		IgnorePermissions: cfg.General.IgnorePermissions,
-		MaxFileSize:       cfg.Performance.MaxFileSize,
-		ParallelWorkers:   cfg.Performance.ParallelWorkers,
+	options := comparisonOptions(cfg, ignoreRules)
+	options.HashAlgorithm = resolveHashAlgorithm(hashAlgorithm)
+	options.MetadataOnly = quickCompare
+	options.TimeToleranceSeconds = timeTolerance
+	options.MaxDepth = maxDepth
+	options.IgnoreLineEndings = ignoreLineEndings
+	options.DetectHardlinks = detectHardlinks
+	options.DetectRenames = detectRenames
+	options.NoIgnoreFiles = noIgnoreFiles
+	if options.SampledHashThreshold, options.SampleBlockSize, err = sampledHashSizes(sampledHash, sampleBlockSize); err != nil {
+		return err
+	}
+	if options.MinFileSize, options.MaxCompareSize, err = sizeRange(minSize, maxSize); err != nil {
+		return err
+	}
+	if pathsFrom != "" {
+		if options.ListedPaths, err = readPathList(pathsFrom); err != nil {
+			return validationErrorf("--paths-from: %w", err)
+		}
+	}
+
+	left := compare.NewSource(leftDir)
+	if leftList != "" {
+		if pathsFrom != "" {
+			return usageErrorf("cannot use both --paths-from and --left-list")
+		}
+		if compare.IsArchive(leftDir) {
+			return usageErrorf("--left-list requires the left side to be a directory")
+		}
+		paths, err := readPathList(leftList)
+		if err != nil {
+			return validationErrorf("--left-list: %w", err)
+		}
+		left = compare.ListSource{Dir: leftDir, Paths: relativeToDir(leftDir, paths)}
 	}
 
 	// Create comparison engine
-	engine := compare.NewEngine(options)
+	engine, err := compare.NewEngine(options)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
 	engine.SetVerboseLevel(cfg.General.Verbose)
+	hashCache := openHashCache(cfg, noHashCache || quickCompare)
+	engine.SetHashCache(hashCache)
+
+	if streaming {
+		return streamJSONLines(engine, left, compare.NewSource(rightDir), outputFile, hashCache)
+	}
 
 	// Perform comparison
-	results, summary, err := engine.Compare(leftDir, rightDir)
+	results := []compare.ComparisonResult{}
+	summary, err := engine.CompareSources(left, compare.NewSource(rightDir), func(result compare.ComparisonResult) {
+		results = append(results, result)
+	})
 	if err != nil {
-		return fmt.Errorf("comparison failed: %w", err)
+		return executionErrorf("comparison failed: %w", err)
 	}
+	saveHashCache(hashCache)
+	printMissingPaths(summary.MissingPaths)
+	printHardlinkDifferences(summary.HardlinkDifferences)
+	printSkippedPaths(summary.SkippedPaths, cfg.General.Verbose)
 
 	if cfg.General.Verbose >= 1 {
 		fmt.Printf("Comparison completed:\n")
 		fmt.Printf("  Files - Total: %d, Identical: %d, Modified: %d, Left only: %d, Right only: %d\n",
 			summary.TotalFiles, summary.IdenticalFiles, summary.ModifiedFiles,
 			summary.OnlyLeftFiles, summary.OnlyRightFiles)
+		if summary.RenamedFiles > 0 {
+			fmt.Printf("  Renamed files: %d\n", summary.RenamedFiles)
+		}
 		fmt.Printf("  Directories - Total: %d, Identical: %d, Left only: %d, Right only: %d\n",
 			summary.TotalDirs, summary.IdenticalDirs, summary.OnlyLeftDirs, summary.OnlyRightDirs)
+		fmt.Printf("  Sizes - Left only: %s, Right only: %s, Modified: %s (left minus right)\n",
+			util.FormatSize(summary.OnlyLeftBytes), util.FormatSize(summary.OnlyRightBytes),
+			util.FormatSizeDelta(summary.ModifiedBytesDelta))
 		if len(summary.ErrorsEncountered) > 0 {
 			fmt.Printf("  Errors encountered: %d\n", len(summary.ErrorsEncountered))
 		}
 		fmt.Println()
+		printPatchFiles(summary.DetectedPatchFiles)
+	}
+
+	palette, err := resolvePalette(cfg)
+	if err != nil {
+		return err
+	}
+	displayOpts := diffDisplayOptions{
+		Palette:  palette,
+		NoColor:  palette.Plain,
+		WordDiff: wordDiff,
+		Context:  cfg.General.ContextLines(),
+
+		DiffCommand: cfg.General.DiffCommand,
+
+		BinaryStat:  binaryStat,
+		MaxFileSize: cfg.Performance.MaxFileSize,
+
+		MaxFiles: maxFiles,
+	}
+
+	// Diffs read file content, so archive sides are extracted first
+	leftContent, rightContent := leftDir, rightDir
+	if showDiff || showDiffFile != "" || patchOutFile != "" {
+		var cleanupLeft, cleanupRight func()
+		if leftContent, cleanupLeft, err = contentDir(leftDir, results, cfg.General.TempRoot()); err != nil {
+			return err
+		}
+		defer cleanupLeft()
+		if rightContent, cleanupRight, err = contentDir(rightDir, results, cfg.General.TempRoot()); err != nil {
+			return err
+		}
+		defer cleanupRight()
+
+		if leftContent != leftDir {
+			displayOpts.LeftName = leftDir
+		}
+		if rightContent != rightDir {
+			displayOpts.RightName = rightDir
+		}
+	}
+
+	if patchOutFile != "" {
+		if err := writePatchFile(results, leftContent, rightContent, patchOutFile, displayOpts.Context); err != nil {
+			return err
+		}
+	}
+
+	if (showDiff || showDiffFile != "") && !noPager {
+		stopPager, err := startPager(pagerCommand)
+		if err != nil {
+			return validationErrorf("%w", err)
+		}
+		defer stopPager()
 	}
 
 	if showDiff {
 		// Display checksum-based diffs for all modified files
-		return showAllDifferences(results, leftDir, rightDir, cfg.General.NoColor)
+		if err := showAllDifferences(results, leftContent, rightContent, displayOpts); err != nil {
+			return err
+		}
 	} else if showDiffFile != "" {
 		// Display diff for single specific file
-		return showSingleFileDiff(results, leftDir, rightDir, showDiffFile, cfg.General.NoColor)
-	} else {
-		// Generate action file
+		if err := showSingleFileDiff(results, leftContent, rightContent, showDiffFile, displayOpts); err != nil {
+			return err
+		}
+	} else if outputFile != "" {
+		// Generate the action file, or the table export with --format csv/tsv
 		outputFile, err := filepath.Abs(outputFile)
 		if err != nil {
-			return fmt.Errorf("failed to resolve output file path: %w", err)
+			return executionErrorf("failed to resolve output file path: %w", err)
 		}
 
-		file, err := os.Create(outputFile)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
-		}
-		defer file.Close()
+		// Written to a temporary file first, so an interrupted run never
+		// leaves a truncated action file behind
+		if exportDelimiter != 0 {
+			err := util.WriteFileAtomic(outputFile, 0644, func(w io.Writer) error {
+				return compare.WriteResultsTable(w, results, exportDelimiter, includeIdentical)
+			})
+			if err != nil {
+				return executionErrorf("failed to write %s export: %w", outputFormat, err)
+			}
+			fmt.Printf("Comparison exported: %s\n", outputFile)
+		} else {
+			generator := action.NewGenerator(rootCmd.Version)
+			generator.SetMirror(mirror)
+			generator.SetPreference(prefer)
+			generator.SetHeaderTemplate(actionHeader)
+			generator.SetReproducible(reproducible)
+			err := util.WriteFileAtomic(outputFile, 0644, func(w io.Writer) error {
+				return generator.GenerateActionFile(w, results, leftDir, rightDir, summary, includeIdentical)
+			})
+			if err != nil {
+				return executionErrorf("failed to generate action file: %w", err)
+			}
 
-		generator := action.NewGenerator(rootCmd.Version)
-		if err := generator.GenerateActionFile(file, results, leftDir, rightDir, summary, includeIdentical); err != nil {
-			return fmt.Errorf("failed to generate action file: %w", err)
+			fmt.Printf("Action file generated: %s\n", outputFile)
+			if mirror != action.MirrorNone {
+				fmt.Printf("WARNING: this is a destructive mirror of the %s side; entries only on the other side will be deleted.\n", mirrorSide)
+			}
+			fmt.Printf("Edit this file to specify the actions you want to take, then run:\n")
+			fmt.Printf("  dovetail dry-run %s -l %s -r %s  # to preview actions\n", outputFile, leftDir, rightDir)
+			fmt.Printf("  dovetail apply %s -l %s -r %s    # to execute actions\n", outputFile, leftDir, rightDir)
 		}
+	}
 
-		fmt.Printf("Action file generated: %s\n", outputFile)
-		fmt.Printf("Edit this file to specify the actions you want to take, then run:\n")
-		fmt.Printf("  dovetail dry-run %s -l %s -r %s  # to preview actions\n", outputFile, leftDir, rightDir)
-		fmt.Printf("  dovetail apply %s -l %s -r %s    # to execute actions\n", outputFile, leftDir, rightDir)
-
-		return nil
+	if exitCode {
+		// With --show-diff-file only the requested file determines the status
+		differs := summary.HasDifferences()
+		if showDiffFile != "" {
+			differs = false
+			for _, result := range results {
+				if result.RelativePath == showDiffFile {
+					differs = result.Status != compare.StatusIdentical
+					break
+				}
+			}
+		}
+		if differs {
+			return errDifferencesFound
+		}
 	}
+
+	return nil
 }
 
 func validateDirectory(path string) error {
@@ -225,46 +543,263 @@ func validateDirectory(path string) error {
 	return nil
 }
 
-// showAllDifferences displays checksum-based differences for all modified files
-func showAllDifferences(results []compare.ComparisonResult, leftDir, rightDir string, noColor bool) error {
-	if noColor {
-		fmt.Printf("Comparison Results:\n")
-		fmt.Printf("==================\n")
+// contextFlag returns the --context value as a config override, or nil when
+// the flag was not given so general.context from the config file applies
+func contextFlag(cmd *cobra.Command, value int) (*int, error) {
+	if !cmd.Flags().Changed("context") {
+		return nil, nil
+	}
+	if value < 0 {
+		return nil, usageErrorf("--context must be 0 or greater, got %d", value)
+	}
+	return &value, nil
+}
+
+// resolveHashAlgorithm validates the requested hash algorithm, warning and
+// falling back to the default when it is not supported
+func resolveHashAlgorithm(name string) string {
+	if compare.IsSupportedHashAlgorithm(name) {
+		return strings.ToLower(strings.TrimSpace(name))
+	}
+	fmt.Fprintf(os.Stderr, "Warning: unknown hash algorithm %q, falling back to %s\n", name, compare.DefaultHashAlgorithm)
+	return compare.DefaultHashAlgorithm
+}
+
+// resolvePalette returns the configured color theme, or the plain palette
+// when colors are turned off
+func resolvePalette(cfg *config.Config) (theme.Palette, error) {
+	if cfg.General.NoColor {
+		return theme.Palette{Plain: true}, nil
+	}
+	palette, err := theme.New(cfg.General.Theme, cfg.Colors)
+	if err != nil {
+		return theme.Palette{}, validationErrorf("%w", err)
+	}
+	return palette, nil
+}
+
+// normalizeRules converts the [[normalize]] tables of the configuration
+func normalizeRules(cfg *config.Config) []compare.NormalizeRule {
+	var rules []compare.NormalizeRule
+	for _, rule := range cfg.Normalize {
+		rules = append(rules, compare.NormalizeRule{Pattern: rule.Pattern, Replacement: rule.Replacement})
+	}
+	return rules
+}
+
+// defaultSampledHashThreshold is the threshold of a bare --sampled-hash
+const defaultSampledHashThreshold = "64M"
+
+// sampledHashSizes parses the --sampled-hash threshold (empty = off) and the
+// --sample-block-size flag
+func sampledHashSizes(threshold, blockSize string) (int64, int64, error) {
+	if threshold == "" {
+		return 0, 0, nil
+	}
+	thresholdBytes, err := util.ParseSize(threshold)
+	if err != nil {
+		return 0, 0, usageErrorf("--sampled-hash: %w", err)
+	}
+	blockBytes, err := util.ParseSize(blockSize)
+	if err != nil || blockBytes == 0 {
+		return 0, 0, usageErrorf("--sample-block-size: invalid size %q", blockSize)
+	}
+	return thresholdBytes, blockBytes, nil
+}
+
+// streamJSONLines writes each result to outputFile, or stdout if empty, as
+// the engine produces it, followed by the summary, for --format jsonl
+func streamJSONLines(engine *compare.Engine, left, right compare.Source, outputFile string, hashCache *compare.HashCache) error {
+	out := os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return executionErrorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer := compare.NewJSONLinesWriter(out, includeIdentical)
+	summary, err := engine.CompareSources(left, right, writer.WriteResult)
+	if err != nil {
+		return executionErrorf("comparison failed: %w", err)
+	}
+	saveHashCache(hashCache)
+	if err := writer.WriteSummary(summary); err != nil {
+		return executionErrorf("failed to write jsonl output: %w", err)
+	}
+	printMissingPaths(summary.MissingPaths)
+	printSkippedPaths(summary.SkippedPaths, GetVerboseLevel())
+
+	if exitCode && summary.HasDifferences() {
+		return errDifferencesFound
+	}
+	return nil
+}
+
+// sizeRange parses the --min-size and --max-size flags (empty = no limit)
+func sizeRange(minSize, maxSize string) (int64, int64, error) {
+	var minBytes, maxBytes int64
+	var err error
+	if minSize != "" {
+		if minBytes, err = util.ParseSize(minSize); err != nil {
+			return 0, 0, usageErrorf("--min-size: %w", err)
+		}
+	}
+	if maxSize != "" {
+		if maxBytes, err = util.ParseSize(maxSize); err != nil {
+			return 0, 0, usageErrorf("--max-size: %w", err)
+		}
+	}
+	return minBytes, maxBytes, nil
+}
+
+// readPathList reads a --paths-from file, or standard input for "-": one
+// relative path per line, with blank lines and lines starting with # ignored
+func readPathList(path string) ([]string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+		path = "standard input"
 	} else {
-		fmt.Printf("\033[1;36mComparison Results:\033[0m\n")
-		fmt.Printf("\033[1;36m==================\033[0m\n")
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s lists no paths", path)
+	}
+	return paths, nil
+}
+
+// relativeToDir rewrites absolute paths inside dir, as printed by
+// "find /abs/dir", relative to it. Other paths are returned unchanged.
+func relativeToDir(dir string, paths []string) []string {
+	rewritten := make([]string, len(paths))
+	for i, path := range paths {
+		rewritten[i] = path
+		if filepath.IsAbs(path) && isWithin(dir, path) {
+			if relPath, err := filepath.Rel(dir, path); err == nil {
+				rewritten[i] = relPath
+			}
+		}
+	}
+	return rewritten
+}
+
+// printMissingPaths warns about --paths-from entries found on neither side
+func printMissingPaths(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %d listed path(s) exist on neither side:\n", len(paths))
+	for _, path := range paths {
+		fmt.Fprintf(os.Stderr, "  %s\n", path)
+	}
+}
+
+// printSkippedPaths warns that entries could not be read, listing them when
+// verbose
+func printSkippedPaths(skipped []compare.SkippedPath, verbose int) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %d path(s) could not be read; the comparison is incomplete there\n", len(skipped))
+	if verbose < 1 {
+		fmt.Fprintf(os.Stderr, "  (run with -v to list them)\n")
+		return
+	}
+	for _, skip := range skipped {
+		path := skip.Path
+		if skip.IsDir {
+			path += "/ (contents not compared)"
+		}
+		fmt.Fprintf(os.Stderr, "  %-5s %s: %s\n", skip.Side, path, skip.Reason)
+	}
+}
+
+// openHashCache loads the on-disk hash cache, or returns nil when disabled or
+// when no cache directory can be determined
+func openHashCache(cfg *config.Config, disabled bool) *compare.HashCache {
+	if disabled {
+		return nil
 	}
-	fmt.Printf("Left:  %s\n", leftDir)
-	fmt.Printf("Right: %s\n", rightDir)
+	dir := cfg.Performance.HashCacheDir
+	if dir == "" {
+		var err error
+		if dir, err = compare.DefaultHashCacheDir(); err != nil {
+			util.VerbosePrintf(cfg.General.Verbose, 1, "Hash cache disabled: %v", err)
+			return nil
+		}
+	}
+	cache := compare.LoadHashCache(dir)
+	util.VerbosePrintf(cfg.General.Verbose, 2, "Loaded %d cached hashes from %s", cache.Len(), dir)
+	return cache
+}
+
+// saveHashCache writes the hash cache back, warning if it can't be saved
+func saveHashCache(cache *compare.HashCache) {
+	if cache == nil {
+		return
+	}
+	if err := cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// showAllDifferences displays checksum-based differences for all modified files
+func showAllDifferences(results []compare.ComparisonResult, leftDir, rightDir string, opts diffDisplayOptions) error {
+	fmt.Println(opts.Palette.Paint(opts.Palette.Header, "Comparison Results:", true))
+	fmt.Println(opts.Palette.Paint(opts.Palette.Header, "==================", true))
+	leftName, rightName := opts.sideNames(leftDir, rightDir)
+	fmt.Printf("Left:  %s\n", leftName)
+	fmt.Printf("Right: %s\n", rightName)
 	fmt.Printf("\n")
 
-	modifiedCount := 0
+	// Sorted so that --max-files always shows the same files
+	var modified []compare.ComparisonResult
 	for _, result := range results {
 		if result.Status == compare.StatusModified {
-			modifiedCount++
+			modified = append(modified, result)
 		}
 	}
+	compare.SortResults(modified)
 
-	if modifiedCount == 0 {
+	if len(modified) == 0 {
 		fmt.Printf("No modified files found.\n")
 		return nil
 	}
 
-	fmt.Printf("Modified files (%d):\n\n", modifiedCount)
-
-	for _, result := range results {
-		if result.Status != compare.StatusModified {
-			continue
-		}
+	fmt.Printf("Modified files (%d):\n\n", len(modified))
 
-		showFileStatus(result, leftDir, rightDir, noColor)
+	shown := modified
+	if opts.MaxFiles > 0 && len(shown) > opts.MaxFiles {
+		shown = shown[:opts.MaxFiles]
+	}
+	for _, result := range shown {
+		showFileStatus(result, leftDir, rightDir, opts)
 	}
 
+	if hidden := len(modified) - len(shown); hidden > 0 {
+		fmt.Printf("... and %d more modified files not shown (limited by --max-files %d)\n", hidden, opts.MaxFiles)
+	}
 	return nil
 }
 
 // showSingleFileDiff displays diff for a single specific file
-func showSingleFileDiff(results []compare.ComparisonResult, leftDir, rightDir, targetFile string, noColor bool) error {
+func showSingleFileDiff(results []compare.ComparisonResult, leftDir, rightDir, targetFile string, opts diffDisplayOptions) error {
 	// Find the specific file in results
 	var targetResult *compare.ComparisonResult
 	for _, result := range results {
@@ -275,7 +810,7 @@ func showSingleFileDiff(results []compare.ComparisonResult, leftDir, rightDir, t
 	}
 
 	if targetResult == nil {
-		return fmt.Errorf("file not found in comparison results: %s", targetFile)
+		return validationErrorf("file not found in comparison results: %s", targetFile)
 	}
 
 	if targetResult.Status == compare.StatusIdentical {
@@ -283,32 +818,27 @@ func showSingleFileDiff(results []compare.ComparisonResult, leftDir, rightDir, t
 		return nil
 	}
 
-	if noColor {
-		fmt.Printf("File Difference:\n")
-		fmt.Printf("================\n")
-	} else {
-		fmt.Printf("\033[1;36mFile Difference:\033[0m\n")
-		fmt.Printf("\033[1;36m================\033[0m\n")
-	}
+	fmt.Println(opts.Palette.Paint(opts.Palette.Header, "File Difference:", true))
+	fmt.Println(opts.Palette.Paint(opts.Palette.Header, "================", true))
 
-	showFileStatus(*targetResult, leftDir, rightDir, noColor)
+	showFileStatus(*targetResult, leftDir, rightDir, opts)
 	return nil
 }
 
 // showFileStatus displays the status of a single file with checksum information
-func showFileStatus(result compare.ComparisonResult, leftDir, rightDir string, noColor bool) {
-	if noColor {
-		fmt.Printf("=== %s ===\n", result.RelativePath)
-	} else {
-		fmt.Printf("\033[1;33m=== %s ===\033[0m\n", result.RelativePath)
-	}
+func showFileStatus(result compare.ComparisonResult, leftDir, rightDir string, opts diffDisplayOptions) {
+	fmt.Println(opts.Palette.Paint(opts.Palette.Path, fmt.Sprintf("=== %s ===", result.RelativePath), true))
 
 	switch result.Status {
 	case compare.StatusModified:
 		if result.LeftInfo != nil && result.RightInfo != nil {
 			if result.LeftInfo.IsDir && result.RightInfo.IsDir {
 				fmt.Printf("Type: Directory (both sides)\n")
-				fmt.Printf("Status: Directory structure differs\n")
+				if result.Changes.Has(compare.ChangeCase) {
+					fmt.Printf("Status: Name case differs (right: %s)\n", result.RightPath())
+				} else {
+					fmt.Printf("Status: Directory structure differs\n")
+				}
 			} else if result.LeftInfo.IsDir || result.RightInfo.IsDir {
 				fmt.Printf("Type mismatch: ")
 				if result.LeftInfo.IsDir {
@@ -316,25 +846,57 @@ func showFileStatus(result compare.ComparisonResult, leftDir, rightDir string, n
 				} else {
 					fmt.Printf("File (left) vs Directory (right)\n")
 				}
+			} else if result.LeftInfo.IsSymlink || result.RightInfo.IsSymlink {
+				fmt.Printf("Type: Symlink\n")
+				fmt.Printf("Status: Symlink target differs\n")
+				fmt.Printf("Left:  %s\n", describeLink(result.LeftInfo))
+				fmt.Printf("Right: %s\n", describeLink(result.RightInfo))
+			} else if result.Changes.PermsOnly() {
+				fmt.Printf("Type: File\n")
+				fmt.Printf("Status: Permissions differ (content identical)\n")
+				fmt.Printf("Left:  %s\n", result.LeftInfo.Permissions)
+				fmt.Printf("Right: %s\n", result.RightInfo.Permissions)
+			} else if !result.Changes.Has(compare.ChangeContent) {
+				fmt.Printf("Type: File\n")
+				fmt.Printf("Status: Name case differs (content identical)\n")
+				fmt.Printf("Left:  %s\n", result.LeftPath())
+				fmt.Printf("Right: %s\n", result.RightPath())
+				if result.Changes.Has(compare.ChangePerms) {
+					fmt.Printf("Permissions: %s (left) vs %s (right)\n", result.LeftInfo.Permissions, result.RightInfo.Permissions)
+				}
 			} else {
 				// Both are files with different content - show Unix diff
-				leftPath := filepath.Join(leftDir, result.RelativePath)
-				rightPath := filepath.Join(rightDir, result.RelativePath)
+				leftPath := filepath.Join(leftDir, result.LeftPath())
+				rightPath := filepath.Join(rightDir, result.RightPath())
+				leftName, rightName := opts.sideNames(leftDir, rightDir)
 
 				fmt.Printf("Type: File\n")
-				fmt.Printf("Status: Content differs (checksum mismatch)\n")
-				fmt.Printf("Left:  %s  Size: %s  Hash: %s\n",
-					leftPath,
-					formatBytes(result.LeftInfo.Size),
-					result.LeftInfo.Hash[:8]+"...")
-				fmt.Printf("Right: %s  Size: %s  Hash: %s\n",
-					rightPath,
-					formatBytes(result.RightInfo.Size),
-					result.RightInfo.Hash[:8]+"...")
+				if result.Method == compare.ComparisonMetadata {
+					fmt.Printf("Status: Size or modification time differs (--quick)\n")
+				} else if result.Method == compare.ComparisonSampled {
+					fmt.Printf("Status: Size or sampled blocks differ (--sampled-hash)\n")
+				} else if result.Changes.Has(compare.ChangeLineEndings) {
+					fmt.Printf("Status: Line endings differ only (CRLF vs LF; --ignore-line-endings treats these as identical)\n")
+				} else {
+					fmt.Printf("Status: Content differs (checksum mismatch)\n")
+				}
+				printSideColumns(opts,
+					filepath.Join(leftName, result.LeftPath()), result.LeftInfo,
+					filepath.Join(rightName, result.RightPath()), result.RightInfo)
+				if result.Changes.Has(compare.ChangePerms) {
+					fmt.Printf("Permissions: %s (left) vs %s (right)\n", result.LeftInfo.Permissions, result.RightInfo.Permissions)
+				}
 				fmt.Printf("\nDifferences:\n")
 
+				if opts.BinaryStat {
+					if binary, err := isBinaryPair(leftPath, rightPath); err == nil && binary {
+						showBinaryStat(leftPath, rightPath, opts)
+						break
+					}
+				}
+
 				// Use Unix diff to show actual content differences
-				if err := showUnixDiff(leftPath, rightPath, result.RelativePath, noColor); err != nil {
+				if err := showUnixDiff(leftPath, rightPath, result.RelativePath, opts); err != nil {
 					fmt.Printf("Error generating diff: %v\n", err)
 				}
 			}
@@ -344,10 +906,12 @@ func showFileStatus(result compare.ComparisonResult, leftDir, rightDir string, n
 		if result.LeftInfo != nil {
 			if result.LeftInfo.IsDir {
 				fmt.Printf("Type: Directory\n")
+			} else if result.LeftInfo.IsSymlink {
+				fmt.Printf("Type: %s\n", describeLink(result.LeftInfo))
 			} else {
 				fmt.Printf("Type: File  Size: %s  Hash: %s\n",
 					formatBytes(result.LeftInfo.Size),
-					result.LeftInfo.Hash[:8]+"...")
+					shortHash(result.LeftInfo.Hash))
 			}
 		}
 	case compare.StatusOnlyRight:
@@ -355,10 +919,12 @@ func showFileStatus(result compare.ComparisonResult, leftDir, rightDir string, n
 		if result.RightInfo != nil {
 			if result.RightInfo.IsDir {
 				fmt.Printf("Type: Directory\n")
+			} else if result.RightInfo.IsSymlink {
+				fmt.Printf("Type: %s\n", describeLink(result.RightInfo))
 			} else {
 				fmt.Printf("Type: File  Size: %s  Hash: %s\n",
 					formatBytes(result.RightInfo.Size),
-					result.RightInfo.Hash[:8]+"...")
+					shortHash(result.RightInfo.Hash))
 			}
 		}
 	}
@@ -366,6 +932,54 @@ func showFileStatus(result compare.ComparisonResult, leftDir, rightDir string, n
 	fmt.Printf("\n")
 }
 
+// printSideColumns prints the Left and Right lines of a modified file with
+// the path, size and hash columns aligned, coloring the labels like diff's
+// - and + lines
+func printSideColumns(opts diffDisplayOptions, leftPath string, leftInfo *compare.FileInfo, rightPath string, rightInfo *compare.FileInfo) {
+	leftSize, rightSize := formatBytes(leftInfo.Size), formatBytes(rightInfo.Size)
+	pathWidth := max(len(leftPath), len(rightPath))
+	sizeWidth := max(len(leftSize), len(rightSize))
+
+	for _, side := range []struct {
+		label, color, path, size, hash string
+	}{
+		{"Left: ", opts.Palette.Removed, leftPath, leftSize, shortHash(leftInfo.Hash)},
+		{"Right:", opts.Palette.Added, rightPath, rightSize, shortHash(rightInfo.Hash)},
+	} {
+		label := opts.Palette.Paint(side.color, side.label, false)
+		fmt.Printf("%s %-*s  Size: %*s  Hash: %s\n", label, pathWidth, side.path, sizeWidth, side.size, side.hash)
+	}
+}
+
+// shortHash abbreviates a content hash for display. The placeholders stored
+// instead of a hash are spelled out, and hashes of any length are safe.
+func shortHash(hash string) string {
+	switch {
+	case hash == "":
+		return "(none)"
+	case hash == "ERROR_CALCULATING_HASH":
+		return "(unreadable: hashing failed)"
+	case strings.HasPrefix(hash, "LARGE_FILE_"):
+		return "(not hashed: larger than max_file_size)"
+	case strings.HasPrefix(hash, "METADATA_"):
+		return "(not hashed: --quick)"
+	case compare.IsSampledHash(hash):
+		return shortHash(strings.TrimPrefix(hash, "SAMPLED_")) + " (sampled)"
+	case len(hash) <= 8:
+		return hash
+	default:
+		return hash[:8] + "..."
+	}
+}
+
+// describeLink describes a file as either a symlink with its target or a regular file
+func describeLink(info *compare.FileInfo) string {
+	if info.IsSymlink {
+		return fmt.Sprintf("Symlink -> %s", info.LinkTarget)
+	}
+	return fmt.Sprintf("File  Size: %s", formatBytes(info.Size))
+}
+
 // formatBytes formats bytes in human-readable format
 func formatBytes(bytes int64) string {
 	const unit = 1024
@@ -380,8 +994,160 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// writePatchFile writes a single unified diff of every modified text file,
+// with a/ and b/ paths relative to the compared directories. Binary files
+// are reported on stderr and left out of the patch.
+func writePatchFile(results []compare.ComparisonResult, leftDir, rightDir, patchPath string, context int) error {
+	if _, err := exec.LookPath("diff"); err != nil {
+		return executionErrorf("--patch-out requires the Unix 'diff' command: %w", err)
+	}
+
+	patchPath, err := filepath.Abs(patchPath)
+	if err != nil {
+		return executionErrorf("failed to resolve patch file path: %w", err)
+	}
+
+	// Results arrive in worker order; sorting a copy keeps the patch stable
+	// across runs
+	results = append([]compare.ComparisonResult(nil), results...)
+	compare.SortResults(results)
+
+	// The patch replaces patchPath only once complete, so a failed or
+	// interrupted run never leaves a truncated patch behind
+	patched, skipped := 0, 0
+	err = util.WriteFileAtomic(patchPath, 0644, func(w io.Writer) error {
+		for _, result := range results {
+			if result.Status != compare.StatusModified || result.LeftInfo == nil || result.RightInfo == nil {
+				continue
+			}
+			if result.LeftInfo.IsDir || result.RightInfo.IsDir || result.LeftInfo.IsSymlink || result.RightInfo.IsSymlink {
+				continue
+			}
+			if !result.Changes.Has(compare.ChangeContent) {
+				continue // Permission-only changes can't be expressed in the patch
+			}
+
+			relPath := filepath.ToSlash(result.RelativePath)
+			leftPath := filepath.Join(leftDir, result.LeftPath())
+			rightPath := filepath.Join(rightDir, result.RightPath())
+
+			if binary, err := isBinaryPair(leftPath, rightPath); err != nil {
+				return fmt.Errorf("failed to read %s: %w", relPath, err)
+			} else if binary {
+				fmt.Fprintf(os.Stderr, "Skipping binary file in patch: %s\n", relPath)
+				skipped++
+				continue
+			}
+
+			cmd := exec.Command("diff", "-U", strconv.Itoa(context), "--label", "a/"+relPath, "--label", "b/"+filepath.ToSlash(result.RightPath()), leftPath, rightPath)
+			output, err := cmd.Output()
+			if err != nil {
+				// diff exits 1 when the files differ
+				if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+					return fmt.Errorf("diff failed for %s: %w", relPath, err)
+				}
+			}
+
+			if _, err := w.Write(output); err != nil {
+				return err
+			}
+			patched++
+		}
+		return nil
+	})
+	if err != nil {
+		return executionErrorf("failed to write patch file: %w", err)
+	}
+
+	fmt.Printf("Patch file generated: %s (%d files", patchPath, patched)
+	if skipped > 0 {
+		fmt.Printf(", %d binary skipped", skipped)
+	}
+	fmt.Printf(")\n")
+	return nil
+}
+
+// printPatchFiles lists leftover .orig and .rej files by side, since they
+// show up as differences until removed
+func printPatchFiles(patchFiles []compare.PatchFile) {
+	if len(patchFiles) == 0 {
+		return
+	}
+
+	fmt.Printf("Leftover patch files detected (%d):\n", len(patchFiles))
+	side := ""
+	for _, patchFile := range patchFiles {
+		if patchFile.Side != side {
+			side = patchFile.Side
+			fmt.Printf("  %s:\n", strings.ToUpper(side[:1])+side[1:])
+		}
+		fmt.Printf("    %s\n", patchFile.Path)
+	}
+	fmt.Printf("Review and delete them, or skip them with --exclude-ext orig,rej\n\n")
+}
+
+// printHardlinkDifferences lists the hardlink groups of each side that the
+// other side doesn't link the same way
+func printHardlinkDifferences(differences []compare.HardlinkDifference) {
+	if len(differences) == 0 {
+		return
+	}
+
+	fmt.Printf("Hardlink differences (%d):\n", len(differences))
+	side := ""
+	for _, difference := range differences {
+		if difference.Side != side {
+			side = difference.Side
+			fmt.Printf("  Linked only on the %s:\n", side)
+		}
+		fmt.Printf("    %s\n", strings.Join(difference.Paths, " = "))
+	}
+	fmt.Println()
+}
+
+// showBinaryStat prints where and by how much two binary files differ
+func showBinaryStat(leftPath, rightPath string, opts diffDisplayOptions) {
+	stat, err := diff.CompareBinary(leftPath, rightPath, opts.MaxFileSize)
+	if errors.Is(err, diff.ErrTooLarge) {
+		fmt.Printf("Binary files differ (larger than %s, not compared byte by byte)\n", formatBytes(opts.MaxFileSize))
+		return
+	} else if err != nil {
+		fmt.Printf("Error comparing binary files: %v\n", err)
+		return
+	}
+
+	delta := stat.RightSize - stat.LeftSize
+	sign := "+"
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	fmt.Printf("Binary files differ\n")
+	fmt.Printf("  Size:             %s -> %s (%s%s)\n", formatBytes(stat.LeftSize), formatBytes(stat.RightSize), sign, formatBytes(delta))
+	if stat.FirstDiffOffset >= 0 {
+		fmt.Printf("  First difference: offset %d (0x%x)\n", stat.FirstDiffOffset, stat.FirstDiffOffset)
+	} else {
+		fmt.Printf("  First difference: offset %d (0x%x), where the shorter file ends\n", stat.Compared, stat.Compared)
+	}
+	percent := 0.0
+	if stat.Compared > 0 {
+		percent = float64(stat.DifferingBytes) * 100 / float64(stat.Compared)
+	}
+	fmt.Printf("  Differing bytes:  %d of %d compared (%.2f%%)\n", stat.DifferingBytes, stat.Compared, percent)
+}
+
+// isBinaryPair reports whether either side of a modified file is binary
+func isBinaryPair(leftPath, rightPath string) (bool, error) {
+	for _, path := range []string{leftPath, rightPath} {
+		binary, err := diff.IsBinary(path)
+		if err != nil || binary {
+			return binary, err
+		}
+	}
+	return false, nil
+}
+
 // showUnixDiff uses the Unix diff command to show actual line-by-line differences
-func showUnixDiff(leftPath, rightPath, relativePath string, noColor bool) error {
+func showUnixDiff(leftPath, rightPath, relativePath string, opts diffDisplayOptions) error {
 	// Check if diff command exists
 	if _, err := exec.LookPath("diff"); err != nil {
 		fmt.Printf("Unix 'diff' command not available: %v\n", err)
@@ -389,16 +1155,33 @@ func showUnixDiff(leftPath, rightPath, relativePath string, noColor bool) error
 	}
 
 	// Prepare diff command with unified format
+	args := []string{"-U", strconv.Itoa(opts.Context)}
+	leftName, rightName := opts.sideNames("", "")
+	if leftName != "" || rightName != "" {
+		// Label extracted archive entries with the archive they came from
+		leftLabel, rightLabel := leftPath, rightPath
+		if leftName != "" {
+			leftLabel = filepath.Join(leftName, relativePath)
+		}
+		if rightName != "" {
+			rightLabel = filepath.Join(rightName, relativePath)
+		}
+		args = append(args, "--label", leftLabel, "--label", rightLabel)
+	}
+	args = append(args, leftPath, rightPath)
 	var cmd *exec.Cmd
-	if noColor {
-		// Standard unified diff
-		cmd = exec.Command("diff", "-u", leftPath, rightPath)
+	if configured := diff.ConfiguredCommand(opts.DiffCommand, args...); configured != nil && !opts.WordDiff {
+		// Word diff parses the output, so it always needs plain diff
+		cmd = configured
+	} else if opts.NoColor || opts.WordDiff {
+		// Standard unified diff (word diff applies its own coloring)
+		cmd = exec.Command("diff", args...)
 	} else {
 		// Try to use colordiff if available, fallback to regular diff
 		if _, err := exec.LookPath("colordiff"); err == nil {
-			cmd = exec.Command("colordiff", "-u", leftPath, rightPath)
+			cmd = exec.Command("colordiff", args...)
 		} else {
-			cmd = exec.Command("diff", "-u", leftPath, rightPath)
+			cmd = exec.Command("diff", args...)
 		}
 	}
 
@@ -424,7 +1207,11 @@ func showUnixDiff(leftPath, rightPath, relativePath string, noColor bool) error
 	// Print the diff output
 	if len(output) > 0 {
 		fmt.Printf("```diff\n")
-		fmt.Print(string(output))
+		if opts.WordDiff {
+			fmt.Print(diff.HighlightWords(string(output), opts.NoColor))
+		} else {
+			fmt.Print(string(output))
+		}
 		fmt.Printf("```\n")
 	} else {
 		fmt.Printf("Files are identical (unexpected - checksum difference detected)\n")
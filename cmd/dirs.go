@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+)
+
+// dirsCmd represents the dirs command
+var dirsCmd = &cobra.Command{
+	Use:   "dirs <DIR_LEFT> <DIR_RIGHT>",
+	Short: "Show which directories contain differences",
+	Long: `Compare two directories and print one line per directory that has differing
+entries directly inside it, with counts of modified, left-only and right-only
+children. Directories are sorted by total changes, most first, so the noisiest
+parts of the tree come first. Directories whose children are all identical
+are omitted.
+
+Examples:
+  dovetail dirs ./src ./backup
+  dovetail dirs ./src ./backup --exclude-name node_modules`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDirs,
+}
+
+var (
+	dirsExcludeNames      []string
+	dirsExcludePaths      []string
+	dirsExcludeExtensions []string
+	dirsExcludeRegex      []string
+	dirsContentRegex      []string
+	dirsIncludePaths      []string
+	dirsUseGitignore      bool
+	dirsIgnoreCase        bool
+	dirsNoDefaultExcludes bool
+	dirsHashAlgorithm     string
+	dirsQuickCompare      bool
+	dirsMinSize           string
+	dirsMaxSize           string
+	dirsTimeTolerance     int
+)
+
+func init() {
+	rootCmd.AddCommand(dirsCmd)
+
+	// Exclusion options
+	dirsCmd.Flags().StringSliceVar(&dirsExcludeNames, "exclude-name", []string{}, "exclude files/directories by name or glob pattern")
+	dirsCmd.Flags().StringSliceVar(&dirsExcludePaths, "exclude-path", []string{}, "exclude files/directories by relative path")
+	dirsCmd.Flags().StringSliceVar(&dirsExcludeExtensions, "exclude-ext", []string{}, "exclude files by extension (without dot)")
+	dirsCmd.Flags().StringSliceVar(&dirsExcludeRegex, "exclude-regex", []string{}, "exclude files/directories whose relative path matches a regular expression")
+	dirsCmd.Flags().StringSliceVar(&dirsContentRegex, "exclude-content-regex", []string{}, "exclude files whose first 4 KiB match a regular expression, e.g. a generated-code marker")
+	dirsCmd.Flags().StringSliceVar(&dirsIncludePaths, "include-path", []string{}, "only compare these relative paths and their contents")
+	dirsCmd.Flags().BoolVar(&dirsUseGitignore, "use-gitignore", false, "read and apply .gitignore rules from both directories")
+	dirsCmd.Flags().BoolVar(&dirsIgnoreCase, "ignore-case", false, "match paths that differ only in letter case")
+	dirsCmd.Flags().BoolVar(&dirsNoDefaultExcludes, "no-default-excludes", false, "don't apply the built-in exclusions enabled by exclusions.use_defaults")
+
+	// Comparison options
+	dirsCmd.Flags().BoolVar(&dirsQuickCompare, "quick", false, "compare files by size and modification time only, without hashing content")
+	dirsCmd.Flags().StringVar(&dirsMinSize, "min-size", "", "exclude files smaller than this size, e.g. 1K")
+	dirsCmd.Flags().StringVar(&dirsMaxSize, "max-size", "", "exclude files larger than this size, e.g. 10M")
+	dirsCmd.Flags().IntVar(&dirsTimeTolerance, "time-tolerance", 0, "treat modification times at most this many seconds apart as equal, e.g. 2 for FAT")
+	dirsCmd.Flags().StringVar(&dirsHashAlgorithm, "hash-algo", compare.DefaultHashAlgorithm, "hash algorithm for content comparison (sha256, blake3, xxhash)")
+}
+
+func runDirs(cmd *cobra.Command, args []string) error {
+	leftDir := args[0]
+	rightDir := args[1]
+
+	if err := validateSource(leftDir); err != nil {
+		return validationErrorf("left directory: %w", err)
+	}
+	if err := validateSource(rightDir); err != nil {
+		return validationErrorf("right directory: %w", err)
+	}
+
+	// Convert to absolute paths
+	leftDir, err := filepath.Abs(leftDir)
+	if err != nil {
+		return executionErrorf("failed to resolve left directory path: %w", err)
+	}
+	rightDir, err = filepath.Abs(rightDir)
+	if err != nil {
+		return executionErrorf("failed to resolve right directory path: %w", err)
+	}
+
+	// Load configuration
+	loader := config.NewLoader(GetVerboseLevel())
+	cfg, err := loader.Load("")
+	if err != nil {
+		return validationErrorf("failed to load configuration: %w", err)
+	}
+
+	cliConfig := config.CLIConfig{
+		VerboseLevel:      GetVerboseLevel(),
+		ExcludeNames:      dirsExcludeNames,
+		ExcludePaths:      dirsExcludePaths,
+		ExcludeExtensions: dirsExcludeExtensions,
+		ExcludeRegex:      dirsExcludeRegex,
+		ContentRegex:      dirsContentRegex,
+		IncludePaths:      dirsIncludePaths,
+		UseGitignore:      dirsUseGitignore,
+		IgnoreCase:        dirsIgnoreCase,
+		NoDefaultExcludes: dirsNoDefaultExcludes,
+	}
+	config.ApplyCLIOverrides(cfg, cliConfig)
+
+	// Process gitignore if enabled
+	ignoreRules, err := gitignoreRules(cfg, leftDir, rightDir)
+	if err != nil {
+		return err
+	}
+
+	options := comparisonOptions(cfg, ignoreRules)
+	options.HashAlgorithm = resolveHashAlgorithm(dirsHashAlgorithm)
+	options.MetadataOnly = dirsQuickCompare
+	options.TimeToleranceSeconds = dirsTimeTolerance
+	if options.MinFileSize, options.MaxCompareSize, err = sizeRange(dirsMinSize, dirsMaxSize); err != nil {
+		return err
+	}
+
+	engine, err := compare.NewEngine(options)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
+	engine.SetVerboseLevel(cfg.General.Verbose)
+
+	results, _, err := engine.Compare(leftDir, rightDir)
+	if err != nil {
+		return executionErrorf("comparison failed: %w", err)
+	}
+
+	rollups := compare.RollupByDirectory(results)
+	if len(rollups) == 0 {
+		fmt.Println("No differences found.")
+		return nil
+	}
+
+	fmt.Printf("%7s %8s %9s %10s  %s\n", "TOTAL", "MODIFIED", "LEFT_ONLY", "RIGHT_ONLY", "DIRECTORY")
+	for _, rollup := range rollups {
+		dir := rollup.Path
+		if dir != "." {
+			dir = filepath.ToSlash(dir) + "/"
+		}
+		fmt.Printf("%7d %8d %9d %10d  %s\n", rollup.Total(), rollup.Modified, rollup.OnlyLeft, rollup.OnlyRight, dir)
+	}
+	return nil
+}
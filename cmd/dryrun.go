@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -22,7 +25,8 @@ before running 'dovetail apply'.
 
 Examples:
   dovetail dry-run actions.txt --left /path/to/source --right /path/to/target
-  dovetail dry-run my_sync.txt -l ./src -r ./backup`,
+  dovetail dry-run my_sync.txt -l ./src -r ./backup
+  dovetail dry-run actions.txt -l ./src -r ./backup --tree`,
 	Args: cobra.ExactArgs(1),
 	RunE: runDryRun,
 }
@@ -30,6 +34,7 @@ Examples:
 var (
 	dryRunLeftDir  string
 	dryRunRightDir string
+	dryRunTree     bool
 )
 
 func init() {
@@ -38,6 +43,7 @@ func init() {
 	// Required directory flags
 	dryrunCmd.Flags().StringVarP(&dryRunLeftDir, "left", "l", "", "left directory path (required)")
 	dryrunCmd.Flags().StringVarP(&dryRunRightDir, "right", "r", "", "right directory path (required)")
+	dryrunCmd.Flags().BoolVar(&dryRunTree, "tree", false, "show actions as a directory tree instead of a flat list")
 
 	// Mark as required
 	dryrunCmd.MarkFlagRequired("left")
@@ -50,31 +56,31 @@ func runDryRun(cmd *cobra.Command, args []string) error {
 	// Validate action file exists
 	if _, err := os.Stat(actionFile); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("action file does not exist: %s", actionFile)
+			return validationErrorf("action file does not exist: %s", actionFile)
 		}
-		return fmt.Errorf("failed to access action file %s: %w", actionFile, err)
+		return validationErrorf("failed to access action file %s: %w", actionFile, err)
 	}
 
 	// Validate directories exist
 	if err := validateDirectory(dryRunLeftDir); err != nil {
-		return fmt.Errorf("left directory: %w", err)
+		return validationErrorf("left directory: %w", err)
 	}
 	if err := validateDirectory(dryRunRightDir); err != nil {
-		return fmt.Errorf("right directory: %w", err)
+		return validationErrorf("right directory: %w", err)
 	}
 
 	// Convert to absolute paths
 	leftDir, err := filepath.Abs(dryRunLeftDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve left directory path: %w", err)
+		return executionErrorf("failed to resolve left directory path: %w", err)
 	}
 	rightDir, err := filepath.Abs(dryRunRightDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve right directory path: %w", err)
+		return executionErrorf("failed to resolve right directory path: %w", err)
 	}
 	actionFile, err = filepath.Abs(actionFile)
 	if err != nil {
-		return fmt.Errorf("failed to resolve action file path: %w", err)
+		return executionErrorf("failed to resolve action file path: %w", err)
 	}
 
 	if GetVerboseLevel() >= 1 {
@@ -85,34 +91,17 @@ func runDryRun(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	// Parse action file
-	file, err := os.Open(actionFile)
+	// Parse and validate action file
+	actionFileData, err := readActionFile(actionFile, leftDir, rightDir)
 	if err != nil {
-		return fmt.Errorf("failed to open action file: %w", err)
-	}
-	defer file.Close()
-
-	parser := action.NewParser()
-	actionFileData, err := parser.ParseActionFile(file)
-	if err != nil {
-		return fmt.Errorf("failed to parse action file: %w", err)
-	}
-
-	// Validate action file
-	validationErrors := parser.ValidateActionFile(actionFileData, leftDir, rightDir)
-	if len(validationErrors) > 0 {
-		fmt.Printf("Validation errors found:\n")
-		for _, err := range validationErrors {
-			fmt.Printf("  %s\n", err.Error())
-		}
-		return fmt.Errorf("action file contains validation errors")
+		return err
 	}
 
 	// Execute in dry-run mode
 	executor := action.NewExecutor(true) // true for dry-run mode
 	summary, results, err := executor.ExecuteActions(actionFileData, leftDir, rightDir)
 	if err != nil {
-		return fmt.Errorf("dry-run execution failed: %w", err)
+		return executionErrorf("dry-run execution failed: %w", err)
 	}
 
 	// Display results
@@ -130,13 +119,18 @@ func runDryRun(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("Actions to be performed:\n")
 	fmt.Printf("========================\n")
-	for _, result := range results {
-		fmt.Printf("%s\n", result.Message)
+	if dryRunTree {
+		printActionTree(actionFileData.Actions)
+	} else {
+		for _, result := range results {
+			fmt.Printf("%s\n", result.Message)
+		}
 	}
 
 	fmt.Printf("\nSummary:\n")
 	fmt.Printf("--------\n")
 	fmt.Printf("Total actions: %d\n", len(results))
+	printActionTally(actionFileData.Actions)
 	if summary.FilesCreated > 0 {
 		fmt.Printf("Files to be created: %d\n", summary.FilesCreated)
 	}
@@ -146,6 +140,12 @@ func runDryRun(cmd *cobra.Command, args []string) error {
 	if summary.FilesDeleted > 0 {
 		fmt.Printf("Files to be deleted: %d\n", summary.FilesDeleted)
 	}
+	if summary.FilesChmodded > 0 {
+		fmt.Printf("Permissions to be changed: %d\n", summary.FilesChmodded)
+	}
+	if summary.FilesMoved > 0 {
+		fmt.Printf("Files to be moved: %d\n", summary.FilesMoved)
+	}
 	if summary.BytesCopied > 0 {
 		fmt.Printf("Data to be copied: %s\n", util.FormatSize(summary.BytesCopied))
 	}
@@ -155,3 +155,151 @@ func runDryRun(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// readActionFile parses and validates an action file for dry-run and apply.
+// Every malformed or invalid line is printed as path:line: message, in file
+// order, before a validation error is returned.
+func readActionFile(path, leftDir, rightDir string) (*action.ActionFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, validationErrorf("failed to open action file: %w", err)
+	}
+	defer file.Close()
+
+	parser := action.NewParser()
+	actionFile, parseErrors, err := parser.ParseActionFileAll(file)
+	if err != nil {
+		return nil, validationErrorf("failed to parse action file: %w", err)
+	}
+
+	type lineProblem struct {
+		line int
+		text string
+	}
+	var problems []lineProblem
+	for _, parseErr := range parseErrors {
+		var validationErr action.ValidationError
+		if errors.As(parseErr.Err, &validationErr) {
+			problems = append(problems, lineProblem{parseErr.Line, validationErr.ErrorAt(path)})
+		} else {
+			problems = append(problems, lineProblem{parseErr.Line, fmt.Sprintf("%s:%d: %s", path, parseErr.Line, parseErr.Message)})
+		}
+	}
+	for _, validationErr := range parser.ValidateActionFile(actionFile, leftDir, rightDir) {
+		problems = append(problems, lineProblem{validationErr.LineNumber, validationErr.ErrorAt(path)})
+	}
+	if len(problems) == 0 {
+		return actionFile, nil
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].line < problems[j].line
+	})
+	fmt.Printf("Validation errors found:\n")
+	for _, problem := range problems {
+		fmt.Printf("  %s\n", problem.text)
+	}
+	return nil, validationErrorf("action file contains %d error(s)", len(problems))
+}
+
+// printActionTally prints how many entries of the action file use each action
+// type, ignored ones last
+func printActionTally(actions []action.ActionItem) {
+	counts := action.CountByType(actions)
+	printRow := func(actionType action.ActionType) {
+		if counts[actionType] > 0 {
+			fmt.Printf("  %-4s %6d  %s\n", "["+actionType.String()+"]", counts[actionType], actionType.Description())
+		}
+	}
+	for _, actionType := range action.ActionTypes {
+		if actionType != action.ActionIgnore {
+			printRow(actionType)
+		}
+	}
+	printRow(action.ActionIgnore)
+}
+
+// actionTreeNode is a path component in the dry-run tree view
+type actionTreeNode struct {
+	name     string
+	action   *action.ActionItem // nil for directories that only contain actions
+	children map[string]*actionTreeNode
+}
+
+// printActionTree prints non-ignored actions as an indented directory tree.
+// Directories without actions of their own that contain a single
+// subdirectory are collapsed into one "a/b/c/" entry.
+func printActionTree(actions []action.ActionItem) {
+	sorted := make([]action.ActionItem, 0, len(actions))
+	for _, item := range actions {
+		if item.Action != action.ActionIgnore {
+			sorted = append(sorted, item)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].RelativePath < sorted[j].RelativePath
+	})
+
+	root := &actionTreeNode{children: make(map[string]*actionTreeNode)}
+	for i := range sorted {
+		node := root
+		for _, part := range strings.Split(filepath.ToSlash(sorted[i].RelativePath), "/") {
+			if part == "" {
+				continue
+			}
+			child, ok := node.children[part]
+			if !ok {
+				child = &actionTreeNode{name: part, children: make(map[string]*actionTreeNode)}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.action = &sorted[i]
+	}
+
+	fmt.Printf("./\n")
+	printActionTreeChildren(root, "")
+}
+
+// printActionTreeChildren prints the children of node with the given line prefix
+func printActionTreeChildren(node *actionTreeNode, prefix string) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := node.children[name]
+		label := child.name
+
+		// Collapse chains of directories that have no actions of their own
+		for child.action == nil && len(child.children) == 1 {
+			var only *actionTreeNode
+			for _, grandchild := range child.children {
+				only = grandchild
+			}
+			if len(only.children) == 0 {
+				break
+			}
+			label += "/" + only.name
+			child = only
+		}
+		if len(child.children) > 0 {
+			label += "/"
+		}
+
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		if child.action != nil {
+			marker := fmt.Sprintf("%-4s", "["+child.action.Action.String()+"]")
+			fmt.Printf("%s%s%s %s\n", prefix, branch, marker, label)
+		} else {
+			fmt.Printf("%s%s%s\n", prefix, branch, label)
+		}
+		printActionTreeChildren(child, prefix+indent)
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes returned by dovetail. Scripts may rely on these values.
+const (
+	ExitSuccess        = 0 // Command completed successfully
+	ExitDifferences    = 1 // diff --exit-code, sync --check: differences were found
+	ExitUsage          = 2 // Invalid arguments, flags or flag combinations
+	ExitValidation     = 3 // Invalid input: missing directories, bad config or action file
+	ExitExecution      = 4 // Runtime failure while comparing or executing actions
+	ExitPartialFailure = 5 // apply: some actions succeeded and some failed
+)
+
+// ExitError is an error carrying the process exit code it should map to
+type ExitError struct {
+	Code   int   // Process exit code
+	Err    error // Underlying error (may be nil for silent exits)
+	Silent bool  // Whether the error should be reported on stderr
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// usageErrorf creates an error for invalid arguments or flag combinations
+func usageErrorf(format string, args ...interface{}) error {
+	return &ExitError{Code: ExitUsage, Err: fmt.Errorf(format, args...)}
+}
+
+// validationErrorf creates an error for invalid input data
+func validationErrorf(format string, args ...interface{}) error {
+	return &ExitError{Code: ExitValidation, Err: fmt.Errorf(format, args...)}
+}
+
+// executionErrorf creates an error for failures while performing work
+func executionErrorf(format string, args ...interface{}) error {
+	return &ExitError{Code: ExitExecution, Err: fmt.Errorf(format, args...)}
+}
+
+// partialFailureErrorf creates an error for runs where only some actions succeeded
+func partialFailureErrorf(format string, args ...interface{}) error {
+	return &ExitError{Code: ExitPartialFailure, Err: fmt.Errorf(format, args...)}
+}
+
+// errDifferencesFound signals that a comparison found differences (not a failure)
+var errDifferencesFound = &ExitError{Code: ExitDifferences, Silent: true}
+
+// ExitCode maps an error returned by Execute to a process exit code.
+// Errors not produced by a command (such as cobra argument and flag
+// parsing errors) are treated as usage errors.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	return ExitUsage
+}
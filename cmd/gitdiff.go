@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/diff"
+	"github.com/harikb/dovetail/internal/tui"
+)
+
+// gitdiffCmd represents the gitdiff command
+var gitdiffCmd = &cobra.Command{
+	Use:   "gitdiff <REF1> <REF2> [PATH]",
+	Short: "Compare two git refs of a repository without checking them out",
+	Long: `Compare the trees of two git refs (commits, branches or tags) in the TUI,
+without touching the working tree. Each ref is exported with git archive to a
+temporary directory that is removed on exit, and the export is compared like
+an archive: only modified files are extracted for their diffs. With PATH, only
+that directory of each ref is compared, as if it were the root.
+
+Examples:
+  dovetail gitdiff HEAD main
+  dovetail gitdiff v1.2.0 v1.3.0 internal/compare
+  dovetail gitdiff HEAD~5 HEAD --list -C ~/src/project`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: runGitDiff,
+}
+
+var (
+	gitdiffRepo              string
+	gitdiffList              bool
+	gitdiffExcludeNames      []string
+	gitdiffExcludePaths      []string
+	gitdiffExcludeExtensions []string
+	gitdiffExcludeRegex      []string
+	gitdiffNoDefaultExcludes bool
+	gitdiffHashAlgorithm     string
+	gitdiffIgnoreWhitespace  bool
+	gitdiffContext           int
+)
+
+func init() {
+	rootCmd.AddCommand(gitdiffCmd)
+
+	gitdiffCmd.Flags().StringVarP(&gitdiffRepo, "repo", "C", ".", "git repository to read the refs from")
+	gitdiffCmd.Flags().BoolVar(&gitdiffList, "list", false, "print the differing paths instead of opening the TUI")
+
+	// Exclusion options
+	gitdiffCmd.Flags().StringSliceVar(&gitdiffExcludeNames, "exclude-name", []string{}, "exclude files/directories by name or glob pattern")
+	gitdiffCmd.Flags().StringSliceVar(&gitdiffExcludePaths, "exclude-path", []string{}, "exclude files/directories by relative path")
+	gitdiffCmd.Flags().StringSliceVar(&gitdiffExcludeExtensions, "exclude-ext", []string{}, "exclude files by extension (without dot)")
+	gitdiffCmd.Flags().StringSliceVar(&gitdiffExcludeRegex, "exclude-regex", []string{}, "exclude files/directories whose relative path matches a regular expression")
+	gitdiffCmd.Flags().BoolVar(&gitdiffNoDefaultExcludes, "no-default-excludes", false, "don't apply the built-in exclusions enabled by exclusions.use_defaults")
+
+	// Display options
+	gitdiffCmd.Flags().BoolVar(&gitdiffIgnoreWhitespace, "ignore-whitespace", false, "ignore whitespace differences in diffs")
+	gitdiffCmd.Flags().IntVar(&gitdiffContext, "context", config.DefaultContextLines, "lines of context around each change in diffs (adjust live with +/-)")
+	gitdiffCmd.Flags().StringVar(&gitdiffHashAlgorithm, "hash-algo", compare.DefaultHashAlgorithm, "hash algorithm for content comparison (sha256, blake3, xxhash)")
+}
+
+func runGitDiff(cmd *cobra.Command, args []string) error {
+	leftRef, rightRef := args[0], args[1]
+	subdir := ""
+	if len(args) == 3 {
+		subdir = strings.Trim(path.Clean(filepath.ToSlash(args[2])), "/")
+		if subdir == "." {
+			subdir = ""
+		} else if subdir == ".." || strings.HasPrefix(subdir, "../") {
+			return usageErrorf("path %q must be inside the repository", args[2])
+		}
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return validationErrorf("git not found in PATH")
+	}
+	for _, ref := range []string{leftRef, rightRef} {
+		if _, err := runGit(gitdiffRepo, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+			return validationErrorf("%q is not a commit in %s", ref, gitdiffRepo)
+		}
+	}
+
+	contextOverride, err := contextFlag(cmd, gitdiffContext)
+	if err != nil {
+		return err
+	}
+
+	loader := config.NewLoader(GetVerboseLevel())
+	cfg, err := loader.Load("")
+	if err != nil {
+		return validationErrorf("failed to load configuration: %w", err)
+	}
+	config.ApplyCLIOverrides(cfg, config.CLIConfig{
+		VerboseLevel:      GetVerboseLevel(),
+		NoColor:           GetNoColor(),
+		ExcludeNames:      gitdiffExcludeNames,
+		ExcludePaths:      gitdiffExcludePaths,
+		ExcludeExtensions: gitdiffExcludeExtensions,
+		ExcludeRegex:      gitdiffExcludeRegex,
+		NoDefaultExcludes: gitdiffNoDefaultExcludes,
+		Context:           contextOverride,
+		DiffCommand:       diffCommand,
+	})
+	if err := diff.CheckCommand(cfg.General.DiffCommand); err != nil {
+		return validationErrorf("%w", err)
+	}
+	if err := checkTempDir(cfg); err != nil {
+		return err
+	}
+	palette, err := resolvePalette(cfg)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp(cfg.General.TempRoot(), "dovetail-gitdiff-")
+	if err != nil {
+		return executionErrorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	leftArchive, err := exportRef(gitdiffRepo, leftRef, subdir, filepath.Join(tempDir, "left"))
+	if err != nil {
+		return executionErrorf("%w", err)
+	}
+	rightArchive, err := exportRef(gitdiffRepo, rightRef, subdir, filepath.Join(tempDir, "right"))
+	if err != nil {
+		return executionErrorf("%w", err)
+	}
+
+	options := comparisonOptions(cfg, nil)
+	options.HashAlgorithm = resolveHashAlgorithm(gitdiffHashAlgorithm)
+	engine, err := compare.NewEngine(options)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
+	engine.SetVerboseLevel(cfg.General.Verbose)
+	results, summary, err := engine.Compare(leftArchive, rightArchive)
+	if err != nil {
+		return executionErrorf("comparison failed: %w", err)
+	}
+
+	leftLabel, rightLabel := leftRef, rightRef
+	if subdir != "" {
+		leftLabel, rightLabel = leftRef+":"+subdir, rightRef+":"+subdir
+	}
+
+	if gitdiffList {
+		compare.SortResults(results)
+		differs := false
+		for _, result := range results {
+			if result.Status != compare.StatusIdentical {
+				fmt.Printf("%-13s %s\n", result.Status, filepath.ToSlash(result.RelativePath))
+				differs = true
+			}
+		}
+		if !differs {
+			fmt.Printf("No differences between %s and %s.\n", leftLabel, rightLabel)
+		}
+		return nil
+	}
+
+	leftContent, cleanupLeft, err := contentDir(leftArchive, results, cfg.General.TempRoot())
+	if err != nil {
+		return err
+	}
+	defer cleanupLeft()
+	rightContent, cleanupRight, err := contentDir(rightArchive, results, cfg.General.TempRoot())
+	if err != nil {
+		return err
+	}
+	defer cleanupRight()
+
+	// The refs are shown in place of the temporary archives; since their
+	// content differs from these names, the TUI treats them as read-only
+	tuiApp := tui.NewApp(results, summary, leftLabel, rightLabel)
+	tuiApp.SetContentDirs(leftContent, rightContent)
+	tuiApp.SetComparisonOptions(options)
+	tuiApp.SetIgnoreWhitespace(gitdiffIgnoreWhitespace)
+	tuiApp.SetContextLines(cfg.General.ContextLines())
+	tuiApp.SetDiffCommand(cfg.General.DiffCommand)
+	tuiApp.SetTheme(palette)
+	tuiApp.SetVersion(rootCmd.Version)
+	if err := tuiApp.Run(); err != nil {
+		return executionErrorf("TUI failed: %w", err)
+	}
+	return nil
+}
+
+// exportRef writes the tree of ref, or of its subdirectory subdir, to a tar
+// archive in dir named after the ref, and returns the archive's path
+func exportRef(repo, ref, subdir, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	archive := filepath.Join(dir, strings.ReplaceAll(ref, "/", "_")+".tar")
+
+	treeish := ref
+	if subdir != "" {
+		treeish = ref + ":" + subdir
+	}
+	if _, err := runGit(repo, "archive", "--format=tar", "-o", archive, treeish); err != nil {
+		return "", fmt.Errorf("failed to export %s: %w", treeish, err)
+	}
+	return archive, nil
+}
+
+// runGit runs git in repo and returns its output. Errors carry git's own
+// message when there is one.
+func runGit(repo string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s", message)
+		}
+		return nil, err
+	}
+	return output, nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/action"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint <ACTION_FILE> [DIR_LEFT DIR_RIGHT]",
+	Short: "Check an action file for problems without executing it",
+	Long: `Check an action file for problems without executing or previewing it.
+Reports every malformed line and unknown action, actions that don't fit the
+recorded status, paths that no longer exist, and copy actions whose source is
+missing. Deleting from both sides a path that exists on both is reported as a
+warning.
+
+The directories default to the Left and Right recorded in the action file
+header. Exits with status 3 if any errors are found, which makes it suitable
+for pre-commit hooks guarding hand-edited action files.
+
+Examples:
+  dovetail lint actions.txt
+  dovetail lint actions.txt ./src ./backup`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 3 {
+			return usageErrorf("requires an action file, optionally followed by both directories")
+		}
+		return nil
+	},
+	RunE: runLint,
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	actionFile := args[0]
+
+	file, err := os.Open(actionFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return validationErrorf("action file does not exist: %s", actionFile)
+		}
+		return validationErrorf("failed to open action file: %w", err)
+	}
+	defer file.Close()
+
+	parser := action.NewParser()
+	actionFileData, parseErrors, err := parser.ParseActionFileAll(file)
+	if err != nil {
+		return validationErrorf("failed to read action file: %w", err)
+	}
+
+	// Directories from the arguments, or else from the header
+	leftDir, rightDir := actionFileData.Header.LeftDir, actionFileData.Header.RightDir
+	if len(args) == 3 {
+		leftDir, rightDir = args[1], args[2]
+	}
+	if leftDir == "" || rightDir == "" {
+		return usageErrorf("action file header does not record both directories; pass DIR_LEFT and DIR_RIGHT")
+	}
+	if err := validateDirectory(leftDir); err != nil {
+		return validationErrorf("left directory: %w", err)
+	}
+	if err := validateDirectory(rightDir); err != nil {
+		return validationErrorf("right directory: %w", err)
+	}
+	if leftDir, err = filepath.Abs(leftDir); err != nil {
+		return executionErrorf("failed to resolve left directory path: %w", err)
+	}
+	if rightDir, err = filepath.Abs(rightDir); err != nil {
+		return executionErrorf("failed to resolve right directory path: %w", err)
+	}
+
+	// Collect every problem, then report them in file order
+	type lintProblem struct {
+		line int
+		text string
+	}
+	var problems []lintProblem
+	for _, parseErr := range parseErrors {
+		var validationErr action.ValidationError
+		if errors.As(parseErr.Err, &validationErr) {
+			problems = append(problems, lintProblem{parseErr.Line, validationErr.Error()}) // Already includes the line
+		} else {
+			problems = append(problems, lintProblem{parseErr.Line, fmt.Sprintf("line %d: %s", parseErr.Line, parseErr.Message)})
+		}
+	}
+	pathErrors, warnings := parser.CheckPaths(actionFileData, leftDir, rightDir)
+	for _, validationErr := range append(parser.ValidateActionFile(actionFileData, leftDir, rightDir), pathErrors...) {
+		problems = append(problems, lintProblem{validationErr.LineNumber, validationErr.Error()})
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].line < problems[j].line
+	})
+
+	for _, problem := range problems {
+		fmt.Printf("error: %s\n", problem.text)
+	}
+	for _, warning := range warnings {
+		fmt.Printf("warning: %s\n", warning.Error())
+	}
+
+	if len(problems) > 0 {
+		return validationErrorf("%s: %d error(s), %d warning(s)", actionFile, len(problems), len(warnings))
+	}
+
+	fmt.Printf("%s: %d actions OK", actionFile, len(actionFileData.Actions))
+	if len(warnings) > 0 {
+		fmt.Printf(", %d warning(s)", len(warnings))
+	}
+	fmt.Printf("\n")
+	return nil
+}
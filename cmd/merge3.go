@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// merge3Cmd represents the merge3 command
+var merge3Cmd = &cobra.Command{
+	Use:   "merge3 <BASE> <LEFT> <RIGHT>",
+	Short: "Three-way compare two directories against a common base",
+	Long: `Compare LEFT and RIGHT against a common BASE directory and generate an action
+file for reconciling them. Each path is classified as unchanged, changed in left
+only, changed in right only, or conflicting (changed differently on both sides).
+
+Changes made on only one side are pre-filled with the action that propagates
+them to the other side. Conflicts default to [i] (ignore) and are marked with a
+CONFLICT comment so they can be resolved by hand. BASE may be a .tar, .tar.gz/.tgz
+or .zip archive of the original release.
+
+Examples:
+  dovetail merge3 ./base ./mine ./theirs -o merge-actions.txt
+  dovetail merge3 base left right -o actions.txt --exclude-name "*.log"`,
+	Args: cobra.ExactArgs(3),
+	RunE: runMerge3,
+}
+
+var (
+	merge3OutputFile        string
+	merge3ExcludeNames      []string
+	merge3ExcludePaths      []string
+	merge3ExcludeExtensions []string
+	merge3ExcludeRegex      []string
+	merge3ContentRegex      []string
+	merge3IncludePaths      []string
+	merge3UseGitignore      bool
+	merge3IgnoreCase        bool
+	merge3NoDefaultExcludes bool
+	merge3HashAlgorithm     string
+)
+
+func init() {
+	rootCmd.AddCommand(merge3Cmd)
+
+	merge3Cmd.Flags().StringVarP(&merge3OutputFile, "output", "o", "", "output action file path (required)")
+	merge3Cmd.MarkFlagRequired("output")
+
+	merge3Cmd.Flags().StringSliceVar(&merge3ExcludeNames, "exclude-name", []string{}, "exclude files/directories by name or glob pattern")
+	merge3Cmd.Flags().StringSliceVar(&merge3ExcludePaths, "exclude-path", []string{}, "exclude files/directories by relative path")
+	merge3Cmd.Flags().StringSliceVar(&merge3ExcludeExtensions, "exclude-ext", []string{}, "exclude files by extension (without dot)")
+	merge3Cmd.Flags().StringSliceVar(&merge3ExcludeRegex, "exclude-regex", []string{}, "exclude files/directories whose relative path matches a regular expression")
+	merge3Cmd.Flags().StringSliceVar(&merge3ContentRegex, "exclude-content-regex", []string{}, "exclude files whose first 4 KiB match a regular expression, e.g. a generated-code marker")
+	merge3Cmd.Flags().StringSliceVar(&merge3IncludePaths, "include-path", []string{}, "only compare these relative paths and their contents")
+	merge3Cmd.Flags().BoolVar(&merge3UseGitignore, "use-gitignore", false, "read and apply .gitignore rules from left and right directories")
+	merge3Cmd.Flags().BoolVar(&merge3IgnoreCase, "ignore-case", false, "match paths that differ only in letter case")
+	merge3Cmd.Flags().BoolVar(&merge3NoDefaultExcludes, "no-default-excludes", false, "don't apply the built-in exclusions enabled by exclusions.use_defaults")
+
+	merge3Cmd.Flags().StringVar(&merge3HashAlgorithm, "hash-algo", compare.DefaultHashAlgorithm, "hash algorithm for content comparison (sha256, blake3, xxhash)")
+}
+
+func runMerge3(cmd *cobra.Command, args []string) error {
+	names := []string{"base", "left", "right"}
+	dirs := make([]string, len(args))
+	for i, dir := range args {
+		// Only the base may be an archive; the action file is applied to left and right
+		validate := validateDirectory
+		if i == 0 {
+			validate = validateSource
+		}
+		if err := validate(dir); err != nil {
+			return validationErrorf("%s directory: %w", names[i], err)
+		}
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return executionErrorf("failed to resolve %s directory path: %w", names[i], err)
+		}
+		dirs[i] = absDir
+	}
+	baseDir, leftDir, rightDir := dirs[0], dirs[1], dirs[2]
+
+	// Load configuration
+	loader := config.NewLoader(GetVerboseLevel())
+	cfg, err := loader.Load("")
+	if err != nil {
+		return validationErrorf("failed to load configuration: %w", err)
+	}
+	actionHeader, err := cfg.General.ActionHeader()
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
+
+	cliConfig := config.CLIConfig{
+		VerboseLevel:      GetVerboseLevel(),
+		ExcludeNames:      merge3ExcludeNames,
+		ExcludePaths:      merge3ExcludePaths,
+		ExcludeExtensions: merge3ExcludeExtensions,
+		ExcludeRegex:      merge3ExcludeRegex,
+		ContentRegex:      merge3ContentRegex,
+		IncludePaths:      merge3IncludePaths,
+		UseGitignore:      merge3UseGitignore,
+		IgnoreCase:        merge3IgnoreCase,
+		NoDefaultExcludes: merge3NoDefaultExcludes,
+	}
+	config.ApplyCLIOverrides(cfg, cliConfig)
+
+	// Process gitignore if enabled
+	ignoreRules, err := gitignoreRules(cfg, leftDir, rightDir)
+	if err != nil {
+		return err
+	}
+
+	if cfg.General.Verbose >= 1 {
+		fmt.Printf("Three-way comparison:\n")
+		fmt.Printf("  Base:  %s\n", baseDir)
+		fmt.Printf("  Left:  %s\n", leftDir)
+		fmt.Printf("  Right: %s\n", rightDir)
+		fmt.Println()
+	}
+
+	options := comparisonOptions(cfg, ignoreRules)
+	options.HashAlgorithm = resolveHashAlgorithm(merge3HashAlgorithm)
+
+	engine, err := compare.NewEngine(options)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
+	engine.SetVerboseLevel(cfg.General.Verbose)
+
+	baseLeft, leftSummary, err := engine.Compare(baseDir, leftDir)
+	if err != nil {
+		return executionErrorf("comparison of base and left failed: %w", err)
+	}
+	baseRight, rightSummary, err := engine.Compare(baseDir, rightDir)
+	if err != nil {
+		return executionErrorf("comparison of base and right failed: %w", err)
+	}
+
+	results := compare.ClassifyThreeWay(baseLeft, baseRight)
+
+	counts := make(map[compare.MergeClass]int)
+	for _, result := range results {
+		counts[result.Class]++
+	}
+
+	if cfg.General.Verbose >= 1 {
+		fmt.Printf("Three-way comparison completed:\n")
+		fmt.Printf("  Unchanged: %d, Changed in left: %d, Changed in right: %d, Conflicts: %d\n",
+			counts[compare.MergeUnchanged], counts[compare.MergeChangedLeft],
+			counts[compare.MergeChangedRight], counts[compare.MergeConflict])
+		if errCount := len(leftSummary.ErrorsEncountered) + len(rightSummary.ErrorsEncountered); errCount > 0 {
+			fmt.Printf("  Errors encountered: %d\n", errCount)
+		}
+		fmt.Println()
+	}
+
+	outputFile, err := filepath.Abs(merge3OutputFile)
+	if err != nil {
+		return executionErrorf("failed to resolve output file path: %w", err)
+	}
+
+	generator := action.NewGenerator(rootCmd.Version)
+	generator.SetHeaderTemplate(actionHeader)
+	err = util.WriteFileAtomic(outputFile, 0644, func(w io.Writer) error {
+		return generator.GenerateMergeActionFile(w, results, baseDir, leftDir, rightDir)
+	})
+	if err != nil {
+		return executionErrorf("failed to generate action file: %w", err)
+	}
+
+	fmt.Printf("Action file generated: %s\n", outputFile)
+	if counts[compare.MergeConflict] > 0 {
+		fmt.Printf("%d conflict(s) default to ignore; edit the file to resolve them.\n", counts[compare.MergeConflict])
+	}
+	fmt.Printf("Review the pre-filled actions, then run:\n")
+	fmt.Printf("  dovetail dry-run %s -l %s -r %s  # to preview actions\n", outputFile, leftDir, rightDir)
+	fmt.Printf("  dovetail apply %s -l %s -r %s    # to execute actions\n", outputFile, leftDir, rightDir)
+
+	return nil
+}
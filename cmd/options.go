@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+)
+
+// gitignoreRules returns the rules of the compared directories' .gitignore
+// files when gitignore support is enabled
+func gitignoreRules(cfg *config.Config, leftDir, rightDir string) ([]config.IgnoreRule, error) {
+	if !cfg.Gitignore.Enabled {
+		return nil, nil
+	}
+	gitignoreParser := config.NewGitignoreParser(cfg.General.Verbose)
+	gitignoreResult, err := gitignoreParser.ParseGitignoreFiles(leftDir, rightDir, cfg.Gitignore.CheckBothSides)
+	if err != nil {
+		return nil, validationErrorf("failed to process .gitignore: %w", err)
+	}
+	return gitignoreResult.Rules, nil
+}
+
+// comparisonOptions returns the comparison options that come from the
+// configuration, with CLI overrides already applied, and the gitignore rules.
+// Every command starts from these and sets its own flags on top, so
+// configured exclusions and settings apply everywhere alike.
+func comparisonOptions(cfg *config.Config, ignoreRules []config.IgnoreRule) compare.ComparisonOptions {
+	return compare.ComparisonOptions{
+		ExcludeNames:         cfg.Exclusions.Names,
+		ExcludePaths:         cfg.Exclusions.Paths,
+		ExcludeExtensions:    cfg.Exclusions.Extensions,
+		ExcludeRegex:         cfg.Exclusions.Regex,
+		ExcludeContentRegex:  cfg.Exclusions.Content,
+		IncludePaths:         cfg.Exclusions.Include,
+		GitignoreRules:       ignoreRules,
+		FollowSymlinks:       cfg.General.FollowSymlinks,
+		IgnorePermissions:    cfg.General.IgnorePermissions,
+		CaseInsensitivePaths: cfg.General.IgnoreCase,
+		NormalizeRules:       normalizeRules(cfg),
+		MaxFileSize:          cfg.Performance.MaxFileSize,
+		ParallelWorkers:      cfg.Performance.ParallelWorkers,
+	}
+}
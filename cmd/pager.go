@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// defaultPager is used when neither --pager nor $PAGER names one
+const defaultPager = "less"
+
+// startPager sends standard output through a pager, like git does, when it
+// is a terminal. command overrides $PAGER. The returned function restores
+// standard output and waits for the pager to exit.
+func startPager(command string) (func(), error) {
+	if !isatty.IsTerminal(os.Stdout.Fd()) {
+		return func() {}, nil
+	}
+
+	explicit := command != ""
+	if !explicit {
+		command = os.Getenv("PAGER")
+	}
+	if command == "" {
+		command = defaultPager
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 || fields[0] == "cat" {
+		return func() {}, nil
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		if explicit {
+			return nil, fmt.Errorf("pager %q not found", fields[0])
+		}
+		return func() {}, nil // No pager installed; print directly
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start pager: %w", err)
+	}
+	pager := exec.Command(fields[0], fields[1:]...)
+	pager.Stdin = reader
+	pager.Stdout = os.Stdout
+	pager.Stderr = os.Stderr
+	// Like git: quit if one screen, keep colors, don't clear the screen
+	if os.Getenv("LESS") == "" {
+		pager.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := pager.Start(); err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, fmt.Errorf("failed to start pager: %w", err)
+	}
+	reader.Close()
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	return func() {
+		os.Stdout = stdout
+		writer.Close()
+		pager.Wait()
+	}, nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/diff"
+)
+
+// patchCmd groups the commands that work with saved patch files
+var patchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Work with saved patch files",
+}
+
+// patchApplyCmd represents the patch apply command
+var patchApplyCmd = &cobra.Command{
+	Use:   "apply <PATCH_FILE>",
+	Short: "Apply a saved single-file patch to the file it was made from",
+	Long: `Apply a unified diff of one file to that file. The file is inferred from the
+patch's name, FILE.YYYYMMDD_HHMMSS.patch next to FILE, unless --target names it.
+
+The whole patch is checked before the file is touched: every context and
+removed line must match, though a hunk may apply a few lines away from where
+it was made. The file is then replaced atomically, keeping its permissions.
+
+Examples:
+  dovetail patch apply src/main.go.20240115_143000.patch --dry-run
+  dovetail patch apply src/main.go.20240115_143000.patch
+  dovetail patch apply fix.patch --target src/main.go`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPatchApply,
+}
+
+var (
+	patchDryRun bool
+	patchTarget string
+)
+
+func init() {
+	rootCmd.AddCommand(patchCmd)
+	patchCmd.AddCommand(patchApplyCmd)
+
+	patchApplyCmd.Flags().BoolVar(&patchDryRun, "dry-run", false, "check the patch and show what would change without writing")
+	patchApplyCmd.Flags().StringVar(&patchTarget, "target", "", "file to apply the patch to (default: inferred from the patch file name)")
+}
+
+func runPatchApply(cmd *cobra.Command, args []string) error {
+	patchPath := args[0]
+
+	basePath := patchTarget
+	if basePath == "" {
+		var ok bool
+		if basePath, ok = action.PatchBaseFile(patchPath); !ok {
+			return usageErrorf("can't infer the file to patch from %s (expected FILE.YYYYMMDD_HHMMSS.patch); use --target", patchPath)
+		}
+	}
+
+	patch, err := os.ReadFile(patchPath)
+	if err != nil {
+		return validationErrorf("failed to read patch file: %w", err)
+	}
+
+	result, err := action.ApplyPatchToFile(basePath, patch, patchDryRun)
+	if err != nil {
+		return validationErrorf("%s: %w", patchPath, err)
+	}
+
+	if patchDryRun {
+		fmt.Printf("DRY RUN: %s applies cleanly to %s\n\n", patchPath, basePath)
+		for _, hunk := range diff.ParseHunks(string(patch)) {
+			fmt.Print(hunk)
+		}
+		fmt.Printf("\nWould apply %d hunk(s) to %s (+%d -%d lines)\n", result.Hunks, basePath, result.LinesAdded, result.LinesRemoved)
+		return nil
+	}
+
+	fmt.Printf("Applied %d hunk(s) to %s (+%d -%d lines)\n", result.Hunks, basePath, result.LinesAdded, result.LinesRemoved)
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -11,6 +12,7 @@ import (
 var (
 	cfgFile      string
 	verboseLevel int
+	diffCommand  string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -24,14 +26,35 @@ It generates human-editable action files for controlled synchronization.
 The tool follows a three-stage workflow:
 1. Generate - Compare directories and create action file
 2. Review - Manually edit the action file to specify desired actions  
-3. Apply - Execute the actions in dry-run or real mode`,
+3. Apply - Execute the actions in dry-run or real mode
+
+Exit codes:
+  0  success
+  1  differences found (diff --exit-code)
+  2  usage error (invalid arguments or flags)
+  3  validation error (missing directories, invalid config or action file)
+  4  execution error
+  5  partial failure (apply: some actions failed)`,
 	Version: "1.0.0",
+	// Arguments and flags are valid once a command runs; don't print usage for runtime errors
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		cmd.SilenceUsage = true
+	},
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The returned error can be mapped to a process exit code with ExitCode.
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if err != nil {
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) || !exitErr.Silent {
+			fmt.Fprintln(os.Stderr, "Error:", err.Error())
+		}
+	}
+	return err
 }
 
 func init() {
@@ -41,6 +64,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.dovetail.yaml)")
 	rootCmd.PersistentFlags().CountVarP(&verboseLevel, "verbose", "v", "verbose output (-v basic, -vv detailed, -vvv debug)")
 	rootCmd.PersistentFlags().Bool("no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().StringVar(&diffCommand, "diff-cmd", "", "external program for showing file diffs, with any arguments (e.g. \"delta\")")
 
 	// Bind flags to viper
 	viper.BindPFlag("verbose-level", rootCmd.PersistentFlags().Lookup("verbose"))
@@ -86,3 +110,8 @@ func GetVerboseLevel() int {
 	// Fall back to viper (for config file support)
 	return viper.GetInt("verbose-level")
 }
+
+// GetNoColor reports whether colored output was turned off with --no-color
+func GetNoColor() bool {
+	return viper.GetBool("no-color")
+}
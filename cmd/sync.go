@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync <DIR_LEFT> <DIR_RIGHT> --policy POLICY",
+	Short: "Compare two directories and apply a policy's actions in one step",
+	Long: `Compare two directories, choose an action for every difference from a policy,
+preview the actions and apply them, all without an intermediate action file.
+This is diff --mirror or --prefer, dry-run and apply in one command for
+unattended use.
+
+Policies:
+  mirror-left    make right an exact copy of left (deletes right-only entries)
+  mirror-right   make left an exact copy of right (deletes left-only entries)
+  prefer-newer   copy the newer version of each differing file over the older
+  prefer-older   copy the older version of each differing file over the newer
+  prefer-left    copy left's version of each differing file to right
+  prefer-right   copy right's version of each differing file to left
+
+Nothing is applied if the preview finds a problem, or if the policy leaves a
+modified file unresolved (prefer-newer or prefer-older with equal modification
+times). Those conflicts need an action file from 'diff' instead.
+
+Unless --yes is given, the preview is followed by a confirmation prompt. With
+--check nothing is applied: the exit status is 0 when the directories are in
+sync and 1 when the policy would change something. Changes are recorded in
+an undo journal, as with 'apply', unless --no-journal is given.
+
+Examples:
+  dovetail sync ./src ./backup --policy mirror-left --yes
+  dovetail sync ./laptop ./desktop --policy prefer-newer
+  dovetail sync ./src ./backup --policy mirror-left --check`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSync,
+}
+
+var (
+	syncPolicy            string
+	syncYes               bool
+	syncCheck             bool
+	syncNoJournal         bool
+	syncJournalDir        string
+	syncExcludeNames      []string
+	syncExcludePaths      []string
+	syncExcludeExtensions []string
+	syncIncludePaths      []string
+	syncUseGitignore      bool
+	syncQuickCompare      bool
+	syncHashAlgorithm     string
+)
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVar(&syncPolicy, "policy", "", "how differences are resolved: mirror-left, mirror-right, prefer-newer, prefer-older, prefer-left or prefer-right (required)")
+	syncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "apply without asking for confirmation")
+	syncCmd.Flags().BoolVar(&syncCheck, "check", false, "only report whether changes are needed, exiting 1 if so")
+	syncCmd.Flags().BoolVar(&syncNoJournal, "no-journal", false, "do not record an undo journal or back up overwritten files")
+	syncCmd.Flags().StringVar(&syncJournalDir, "journal-dir", "", "directory for the undo journal and backups (default $XDG_STATE_HOME/dovetail)")
+
+	// Exclusion options
+	syncCmd.Flags().StringSliceVar(&syncExcludeNames, "exclude-name", []string{}, "exclude files/directories by name or glob pattern")
+	syncCmd.Flags().StringSliceVar(&syncExcludePaths, "exclude-path", []string{}, "exclude files/directories by relative path")
+	syncCmd.Flags().StringSliceVar(&syncExcludeExtensions, "exclude-ext", []string{}, "exclude files by extension (without dot)")
+	syncCmd.Flags().StringSliceVar(&syncIncludePaths, "include-path", []string{}, "only compare these relative paths and their contents")
+	syncCmd.Flags().BoolVar(&syncUseGitignore, "use-gitignore", false, "read and apply .gitignore rules from both directories")
+
+	// Comparison options
+	syncCmd.Flags().BoolVar(&syncQuickCompare, "quick", false, "compare files by size and modification time only, without hashing content")
+	syncCmd.Flags().StringVar(&syncHashAlgorithm, "hash-algo", compare.DefaultHashAlgorithm, "hash algorithm for content comparison (sha256, blake3, xxhash)")
+
+	syncCmd.MarkFlagRequired("policy")
+}
+
+// parseSyncPolicy parses a --policy value into the generator setting it
+// stands for; exactly one of the two results is set
+func parseSyncPolicy(policy string) (action.MirrorSource, action.Preference, bool) {
+	switch policy {
+	case "mirror-left":
+		return action.MirrorFromLeft, action.PreferNone, true
+	case "mirror-right":
+		return action.MirrorFromRight, action.PreferNone, true
+	case "prefer-newer":
+		return action.MirrorNone, action.PreferNewer, true
+	case "prefer-older":
+		return action.MirrorNone, action.PreferOlder, true
+	case "prefer-left":
+		return action.MirrorNone, action.PreferLeft, true
+	case "prefer-right":
+		return action.MirrorNone, action.PreferRight, true
+	default:
+		return action.MirrorNone, action.PreferNone, false
+	}
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	mirror, prefer, ok := parseSyncPolicy(syncPolicy)
+	if !ok {
+		return usageErrorf("--policy must be mirror-left, mirror-right, prefer-newer, prefer-older, prefer-left or prefer-right, got %q", syncPolicy)
+	}
+	if syncYes && syncCheck {
+		return usageErrorf("cannot use both --yes and --check")
+	}
+
+	// Actions run on the directories themselves, so archives can't be synced
+	if err := validateDirectory(args[0]); err != nil {
+		return validationErrorf("left directory: %w", err)
+	}
+	if err := validateDirectory(args[1]); err != nil {
+		return validationErrorf("right directory: %w", err)
+	}
+	leftDir, err := filepath.Abs(args[0])
+	if err != nil {
+		return executionErrorf("failed to resolve left directory path: %w", err)
+	}
+	rightDir, err := filepath.Abs(args[1])
+	if err != nil {
+		return executionErrorf("failed to resolve right directory path: %w", err)
+	}
+	journalDir := ""
+	if !syncNoJournal {
+		if journalDir, err = resolveJournalDir(syncJournalDir, leftDir, rightDir); err != nil {
+			return err
+		}
+	}
+
+	// Load configuration
+	loader := config.NewLoader(GetVerboseLevel())
+	cfg, err := loader.Load("")
+	if err != nil {
+		return validationErrorf("failed to load configuration: %w", err)
+	}
+	config.ApplyCLIOverrides(cfg, config.CLIConfig{
+		VerboseLevel:      GetVerboseLevel(),
+		ExcludeNames:      syncExcludeNames,
+		ExcludePaths:      syncExcludePaths,
+		ExcludeExtensions: syncExcludeExtensions,
+		IncludePaths:      syncIncludePaths,
+		UseGitignore:      syncUseGitignore,
+	})
+
+	// Process gitignore if enabled
+	ignoreRules, err := gitignoreRules(cfg, leftDir, rightDir)
+	if err != nil {
+		return err
+	}
+
+	options := comparisonOptions(cfg, ignoreRules)
+	options.HashAlgorithm = resolveHashAlgorithm(syncHashAlgorithm)
+	options.MetadataOnly = syncQuickCompare
+	engine, err := compare.NewEngine(options)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
+	engine.SetVerboseLevel(cfg.General.Verbose)
+	hashCache := openHashCache(cfg, syncQuickCompare)
+	engine.SetHashCache(hashCache)
+
+	results, summary, err := engine.Compare(leftDir, rightDir)
+	if err != nil {
+		return executionErrorf("comparison failed: %w", err)
+	}
+	saveHashCache(hashCache)
+	printSkippedPaths(summary.SkippedPaths, cfg.General.Verbose)
+
+	generator := action.NewGenerator(rootCmd.Version)
+	generator.SetMirror(mirror)
+	generator.SetPreference(prefer)
+	actionFile := generator.BuildActionFile(results, leftDir, rightDir)
+
+	// Modified files the policy couldn't decide between
+	var conflicts []string
+	for _, item := range actionFile.Actions {
+		if item.Action == action.ActionIgnore && item.Status == compare.StatusModified {
+			conflicts = append(conflicts, item.RelativePath)
+		}
+	}
+
+	parser := action.NewParser()
+	if problems := parser.ValidateActionFile(actionFile, leftDir, rightDir); len(problems) > 0 {
+		fmt.Printf("Validation errors found:\n")
+		for _, problem := range problems {
+			fmt.Printf("  %s (action: %s)\n", problem.Message, problem.Action)
+		}
+		return validationErrorf("%d action(s) failed validation; nothing was applied", len(problems))
+	}
+
+	preview := action.NewExecutor(true)
+	_, planned, err := preview.ExecuteActions(actionFile, leftDir, rightDir)
+	if err != nil {
+		return executionErrorf("dry-run execution failed: %w", err)
+	}
+	if len(planned) == 0 && len(conflicts) == 0 {
+		fmt.Println("Directories are already in sync.")
+		return nil
+	}
+
+	failed := 0
+	if len(planned) > 0 {
+		fmt.Printf("Actions for policy %s:\n", syncPolicy)
+		for _, result := range planned {
+			if result.Success {
+				fmt.Printf("  %s\n", result.Message)
+			} else {
+				failed++
+				fmt.Printf("  ✗ %s: %s\n", result.Action.RelativePath, result.Message)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		fmt.Printf("Conflicts the policy can't resolve (both sides modified at the same time):\n")
+		for _, path := range conflicts {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+
+	if syncCheck {
+		return errDifferencesFound
+	}
+	if failed > 0 {
+		return validationErrorf("%d action(s) would fail; nothing was applied", failed)
+	}
+	if len(conflicts) > 0 {
+		return validationErrorf("%d conflict(s) need an action file from 'dovetail diff'; nothing was applied", len(conflicts))
+	}
+
+	if !syncYes {
+		fmt.Printf("\nApply these %d action(s) to %s and %s? [y/N]: ", len(planned), leftDir, rightDir)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" && response != "yes" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	executor := action.NewConfiguredExecutor(cfg.General)
+	executor.SetVerboseLevel(cfg.General.Verbose)
+
+	var journal *action.Journal
+	if !syncNoJournal {
+		journal, err = action.NewJournal(journalDir, leftDir, rightDir)
+		if err != nil {
+			return executionErrorf("failed to create undo journal: %w", err)
+		}
+		executor.SetJournal(journal)
+	}
+
+	execSummary, executed, err := executor.ExecuteActions(actionFile, leftDir, rightDir)
+	if err != nil {
+		return executionErrorf("execution failed: %w", err)
+	}
+	if journal != nil {
+		if len(journal.Entries) == 0 {
+			os.Remove(journal.Path())
+		} else {
+			defer fmt.Printf("\nUndo journal: %s\n  dovetail undo %s  # to reverse these changes\n", journal.Path(), journal.Path())
+		}
+	}
+
+	fmt.Printf("\nApplied %d action(s): %d succeeded, %d failed", len(executed), execSummary.SuccessfulActions, execSummary.FailedActions)
+	if execSummary.BytesCopied > 0 {
+		fmt.Printf(", %s copied", util.FormatSize(execSummary.BytesCopied))
+	}
+	fmt.Println()
+	if len(execSummary.Errors) > 0 {
+		fmt.Printf("\nErrors encountered:\n")
+		for _, errMsg := range execSummary.Errors {
+			fmt.Printf("  %s\n", errMsg)
+		}
+		if execSummary.SuccessfulActions > 0 {
+			return partialFailureErrorf("sync completed with %d errors", len(execSummary.Errors))
+		}
+		return executionErrorf("sync completed with %d errors", len(execSummary.Errors))
+	}
+	return nil
+}
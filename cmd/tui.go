@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/harikb/dovetail/internal/action"
 	"github.com/harikb/dovetail/internal/compare"
 	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/diff"
 	"github.com/harikb/dovetail/internal/tui"
+	"github.com/harikb/dovetail/internal/util"
 )
 
 // tuiCmd represents the tui command
@@ -18,10 +23,29 @@ var tuiCmd = &cobra.Command{
 	Short: "Interactive TUI for directory comparison",
 	Long: `Launch an interactive terminal UI for comparing directories.
 Navigate through files with arrow keys and press Enter to view diffs.
+Set an action per file with >, <, i and x (or on every visible file with
+}, {, I and X), then press s to save them as an action file, or Z (Ctrl+S)
+to save and quit in one step. Press a twice within two seconds (or A once)
+to apply the actions directly, with an undo journal written as apply does.
+In the diff view, press e and then l or r to
+open that side's file in $VISUAL or $EDITOR; the diff reloads when it exits.
+Press m to hand-merge both sides in $DOVETAIL_MERGE (default vimdiff or meld).
+Press r in the file list to compare again with the same options, keeping
+the actions already chosen.
+Press = in the file list to also list identical files, dimmed, and again to
+hide them.
+On terminals at least 80 columns wide the file list also shows left and
+right sizes; the side with the newer modification time is marked with *.
+Either side may be a .tar, .tar.gz/.tgz or .zip archive; its modified entries
+are extracted to a temporary directory for viewing diffs.
 
 Examples:
   dovetail tui /path/to/source /path/to/target
-  dovetail tui ./src ./backup --exclude-name "*.log"`,
+  dovetail tui ./src ./backup --exclude-name "*.log"
+  dovetail tui ./src ./backup --resume dovetail_actions_20240115_143000.txt
+
+The most recent dovetail_actions_*.txt in the working directory that was
+generated for the same directories is resumed automatically.`,
 	Args: cobra.ExactArgs(2),
 	RunE: runTUI,
 }
@@ -30,7 +54,28 @@ var (
 	tuiExcludeNames      []string
 	tuiExcludePaths      []string
 	tuiExcludeExtensions []string
+	tuiExcludeRegex      []string
+	tuiContentRegex      []string
+	tuiIncludePaths      []string
 	tuiUseGitignore      bool
+	tuiIgnoreCase        bool
+	tuiNoDefaultExcludes bool
+	tuiNoIgnoreFiles     bool
+	tuiHashAlgorithm     string
+	tuiQuickCompare      bool
+	tuiJournalDir        string
+	tuiMinSize           string
+	tuiMaxSize           string
+	tuiTimeTolerance     int
+	tuiNoHashCache       bool
+	tuiPathsFrom         string
+	tuiMaxDepth          int
+	tuiIgnoreLineEndings bool
+	tuiIgnoreWhitespace  bool
+	tuiResumeFile        string
+	tuiContext           int
+	tuiSampledHash       string
+	tuiSampleBlockSize   string
 )
 
 func init() {
@@ -40,7 +85,33 @@ func init() {
 	tuiCmd.Flags().StringSliceVar(&tuiExcludeNames, "exclude-name", []string{}, "exclude files/directories by name or glob pattern")
 	tuiCmd.Flags().StringSliceVar(&tuiExcludePaths, "exclude-path", []string{}, "exclude files/directories by relative path")
 	tuiCmd.Flags().StringSliceVar(&tuiExcludeExtensions, "exclude-ext", []string{}, "exclude files by extension (without dot)")
+	tuiCmd.Flags().StringSliceVar(&tuiExcludeRegex, "exclude-regex", []string{}, "exclude files/directories whose relative path matches a regular expression")
+	tuiCmd.Flags().StringSliceVar(&tuiContentRegex, "exclude-content-regex", []string{}, "exclude files whose first 4 KiB match a regular expression, e.g. a generated-code marker")
+	tuiCmd.Flags().StringSliceVar(&tuiIncludePaths, "include-path", []string{}, "only compare these relative paths and their contents")
 	tuiCmd.Flags().BoolVar(&tuiUseGitignore, "use-gitignore", false, "read and apply .gitignore rules from both directories")
+	tuiCmd.Flags().BoolVar(&tuiIgnoreCase, "ignore-case", false, "match paths that differ only in letter case")
+	tuiCmd.Flags().BoolVar(&tuiNoDefaultExcludes, "no-default-excludes", false, "don't apply the built-in exclusions enabled by exclusions.use_defaults")
+	tuiCmd.Flags().BoolVar(&tuiNoIgnoreFiles, "no-dovetailignore", false, "don't read .dovetailignore files found while scanning")
+
+	// Display options
+	tuiCmd.Flags().BoolVar(&tuiIgnoreWhitespace, "ignore-whitespace", false, "ignore whitespace differences in diffs")
+	tuiCmd.Flags().IntVar(&tuiContext, "context", config.DefaultContextLines, "lines of context around each change in diffs (adjust live with +/-)")
+	tuiCmd.Flags().StringVar(&tuiResumeFile, "resume", "", "pre-populate actions from a previously saved action file")
+	tuiCmd.Flags().StringVar(&tuiJournalDir, "journal-dir", "", "directory for the undo journal of actions applied with a (default $XDG_STATE_HOME/dovetail)")
+
+	// Comparison options
+	tuiCmd.Flags().BoolVar(&tuiIgnoreLineEndings, "ignore-line-endings", false, "treat files that differ only in CRLF vs LF line endings as identical")
+	tuiCmd.Flags().IntVar(&tuiMaxDepth, "max-depth", 0, "compare only paths up to this many levels deep (0 = unlimited)")
+	tuiCmd.Flags().BoolVar(&tuiQuickCompare, "quick", false, "compare files by size and modification time only, without hashing content")
+	tuiCmd.Flags().StringVar(&tuiMinSize, "min-size", "", "exclude files smaller than this size, e.g. 1K")
+	tuiCmd.Flags().StringVar(&tuiMaxSize, "max-size", "", "exclude files larger than this size, e.g. 10M")
+	tuiCmd.Flags().IntVar(&tuiTimeTolerance, "time-tolerance", 0, "treat modification times at most this many seconds apart as equal, e.g. 2 for FAT")
+	tuiCmd.Flags().StringVar(&tuiSampledHash, "sampled-hash", "", "hash files of at least this size (default 64M) from their size and first, middle and last blocks only")
+	tuiCmd.Flags().Lookup("sampled-hash").NoOptDefVal = defaultSampledHashThreshold
+	tuiCmd.Flags().StringVar(&tuiSampleBlockSize, "sample-block-size", "1M", "size of each block read by --sampled-hash")
+	tuiCmd.Flags().BoolVar(&tuiNoHashCache, "no-cache", false, "hash every file instead of reusing hashes cached by earlier runs")
+	tuiCmd.Flags().StringVar(&tuiPathsFrom, "paths-from", "", "compare only the relative paths listed in this file, one per line, without walking the directories")
+	tuiCmd.Flags().StringVar(&tuiHashAlgorithm, "hash-algo", compare.DefaultHashAlgorithm, "hash algorithm for content comparison (sha256, blake3, xxhash)")
 }
 
 func runTUI(cmd *cobra.Command, args []string) error {
@@ -48,79 +119,220 @@ func runTUI(cmd *cobra.Command, args []string) error {
 	rightDir := args[1]
 
 	// Validate directories exist
-	if err := validateDirectory(leftDir); err != nil {
-		return fmt.Errorf("left directory: %w", err)
+	if err := validateSource(leftDir); err != nil {
+		return validationErrorf("left directory: %w", err)
 	}
-	if err := validateDirectory(rightDir); err != nil {
-		return fmt.Errorf("right directory: %w", err)
+	if err := validateSource(rightDir); err != nil {
+		return validationErrorf("right directory: %w", err)
 	}
 
 	// Convert to absolute paths
 	leftDir, err := filepath.Abs(leftDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve left directory path: %w", err)
+		return executionErrorf("failed to resolve left directory path: %w", err)
 	}
 	rightDir, err = filepath.Abs(rightDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve right directory path: %w", err)
+		return executionErrorf("failed to resolve right directory path: %w", err)
+	}
+	journalDir, err := resolveJournalDir(tuiJournalDir, leftDir, rightDir)
+	if err != nil {
+		return err
+	}
+
+	contextOverride, err := contextFlag(cmd, tuiContext)
+	if err != nil {
+		return err
 	}
 
 	// Load configuration
 	loader := config.NewLoader(GetVerboseLevel())
 	cfg, err := loader.Load("")
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return validationErrorf("failed to load configuration: %w", err)
 	}
 
 	// Apply CLI overrides
 	cliConfig := config.CLIConfig{
 		VerboseLevel:      GetVerboseLevel(),
+		NoColor:           GetNoColor(),
 		ExcludeNames:      tuiExcludeNames,
 		ExcludePaths:      tuiExcludePaths,
 		ExcludeExtensions: tuiExcludeExtensions,
+		ExcludeRegex:      tuiExcludeRegex,
+		ContentRegex:      tuiContentRegex,
+		IncludePaths:      tuiIncludePaths,
 		UseGitignore:      tuiUseGitignore,
+		IgnoreCase:        tuiIgnoreCase,
+		NoDefaultExcludes: tuiNoDefaultExcludes,
+		Context:           contextOverride,
+		DiffCommand:       diffCommand,
 	}
 	config.ApplyCLIOverrides(cfg, cliConfig)
+	if err := diff.CheckCommand(cfg.General.DiffCommand); err != nil {
+		return validationErrorf("%w", err)
+	}
+	if err := checkTempDir(cfg); err != nil {
+		return err
+	}
+	palette, err := resolvePalette(cfg)
+	if err != nil {
+		return err
+	}
+	actionHeader, err := cfg.General.ActionHeader()
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
 
 	// Process gitignore if enabled
-	if cfg.Gitignore.Enabled {
-		gitignoreParser := config.NewGitignoreParser(cfg.General.Verbose)
-		gitignoreResult, err := gitignoreParser.ParseGitignoreFiles(leftDir, rightDir, cfg.Gitignore.CheckBothSides)
-		if err != nil {
-			return fmt.Errorf("failed to process .gitignore: %w", err)
-		}
-
-		// Add gitignore patterns to exclusions
-		cfg.Exclusions.Names = append(cfg.Exclusions.Names, gitignoreResult.Names...)
-		cfg.Exclusions.Paths = append(cfg.Exclusions.Paths, gitignoreResult.Paths...)
-		cfg.Exclusions.Extensions = append(cfg.Exclusions.Extensions, gitignoreResult.Extensions...)
+	ignoreRules, err := gitignoreRules(cfg, leftDir, rightDir)
+	if err != nil {
+		return err
 	}
 
 	// Create comparison options from config
-	options := compare.ComparisonOptions{
-		ExcludeNames:      cfg.Exclusions.Names,
-		ExcludePaths:      cfg.Exclusions.Paths,
-		ExcludeExtensions: cfg.Exclusions.Extensions,
-		FollowSymlinks:    cfg.General.FollowSymlinks,
-		IgnorePermissions: cfg.General.IgnorePermissions,
-		MaxFileSize:       cfg.Performance.MaxFileSize,
-		ParallelWorkers:   cfg.Performance.ParallelWorkers,
+	options := comparisonOptions(cfg, ignoreRules)
+	options.HashAlgorithm = resolveHashAlgorithm(tuiHashAlgorithm)
+	options.MetadataOnly = tuiQuickCompare
+	options.TimeToleranceSeconds = tuiTimeTolerance
+	options.MaxDepth = tuiMaxDepth
+	options.IgnoreLineEndings = tuiIgnoreLineEndings
+	options.NoIgnoreFiles = tuiNoIgnoreFiles
+	if options.SampledHashThreshold, options.SampleBlockSize, err = sampledHashSizes(tuiSampledHash, tuiSampleBlockSize); err != nil {
+		return err
+	}
+	if options.MinFileSize, options.MaxCompareSize, err = sizeRange(tuiMinSize, tuiMaxSize); err != nil {
+		return err
+	}
+	if tuiPathsFrom == "-" {
+		return usageErrorf("--paths-from can't read standard input in the TUI, which needs it for the keyboard")
+	}
+	if tuiPathsFrom != "" {
+		if options.ListedPaths, err = readPathList(tuiPathsFrom); err != nil {
+			return validationErrorf("--paths-from: %w", err)
+		}
 	}
 
 	// Create comparison engine
-	engine := compare.NewEngine(options)
+	engine, err := compare.NewEngine(options)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
 	engine.SetVerboseLevel(cfg.General.Verbose)
 
 	// Show loading message
 	fmt.Fprintf(os.Stderr, "Scanning directories...\n")
 
+	// Verbose output already reports progress; otherwise keep one live line
+	// so large trees don't look frozen
+	showedProgress := false
+	if cfg.General.Verbose == 0 {
+		engine.SetProgressFunc(func(done, total int, elapsed time.Duration) {
+			fmt.Fprintf(os.Stderr, "\r\033[KComparing %s", util.FormatProgress(done, total, elapsed))
+			showedProgress = true
+		})
+	}
+
+	hashCache := openHashCache(cfg, tuiNoHashCache || tuiQuickCompare)
+	engine.SetHashCache(hashCache)
+
 	// Perform comparison
 	results, summary, err := engine.Compare(leftDir, rightDir)
+	if showedProgress {
+		fmt.Fprintln(os.Stderr)
+	}
 	if err != nil {
-		return fmt.Errorf("comparison failed: %w", err)
+		return executionErrorf("comparison failed: %w", err)
 	}
+	saveHashCache(hashCache)
+	printMissingPaths(summary.MissingPaths)
 
 	// Launch TUI
+	leftContent, cleanupLeft, err := contentDir(leftDir, results, cfg.General.TempRoot())
+	if err != nil {
+		return err
+	}
+	defer cleanupLeft()
+	rightContent, cleanupRight, err := contentDir(rightDir, results, cfg.General.TempRoot())
+	if err != nil {
+		return err
+	}
+	defer cleanupRight()
+
 	tuiApp := tui.NewApp(results, summary, leftDir, rightDir)
-	return tuiApp.Run()
+	tuiApp.SetContentDirs(leftContent, rightContent)
+	tuiApp.SetComparisonOptions(options)
+	tuiApp.SetIgnoreWhitespace(tuiIgnoreWhitespace)
+	tuiApp.SetContextLines(cfg.General.ContextLines())
+	tuiApp.SetDiffCommand(cfg.General.DiffCommand)
+	tuiApp.SetTheme(palette)
+	tuiApp.SetActionHeader(actionHeader)
+	tuiApp.SetVersion(rootCmd.Version)
+	tuiApp.SetJournalDir(journalDir)
+	tuiApp.SetApplySettings(cfg.General)
+	if err := resumeTUIActions(tuiApp, leftDir, rightDir); err != nil {
+		return err
+	}
+	if err := tuiApp.Run(); err != nil {
+		return executionErrorf("TUI failed: %w", err)
+	}
+	return nil
+}
+
+// resumeTUIActions pre-populates TUI actions from --resume, or from the newest
+// dovetail_actions_*.txt in the working directory generated for the same directories
+func resumeTUIActions(tuiApp *tui.App, leftDir, rightDir string) error {
+	resumeFile := tuiResumeFile
+	explicit := resumeFile != ""
+	if !explicit {
+		resumeFile = findLatestActionFile()
+		if resumeFile == "" {
+			return nil
+		}
+	}
+
+	file, err := os.Open(resumeFile)
+	if err != nil {
+		if explicit {
+			return validationErrorf("failed to open resume file: %w", err)
+		}
+		return nil
+	}
+	defer file.Close()
+
+	actionFileData, err := action.NewParser().ParseActionFile(file)
+	if err != nil {
+		if explicit {
+			return validationErrorf("failed to parse resume file %s: %w", resumeFile, err)
+		}
+		fmt.Fprintf(os.Stderr, "Not resuming from %s: %v\n", resumeFile, err)
+		return nil
+	}
+
+	header := actionFileData.Header
+	if header.LeftDir != leftDir || header.RightDir != rightDir {
+		if !explicit {
+			// Saved for a different pair of directories
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s was generated for %s and %s\n", resumeFile, header.LeftDir, header.RightDir)
+	}
+
+	restored, dropped := tuiApp.ResumeActions(actionFileData.Actions)
+	fmt.Fprintf(os.Stderr, "Resumed %d actions from %s\n", restored, resumeFile)
+	for _, note := range dropped {
+		fmt.Fprintf(os.Stderr, "  Dropped: %s\n", note)
+	}
+	return nil
+}
+
+// findLatestActionFile returns the newest dovetail_actions_*.txt in the working
+// directory, or "" if there is none. Names embed a sortable timestamp.
+func findLatestActionFile() string {
+	matches, err := filepath.Glob("dovetail_actions_*.txt")
+	if err != nil || len(matches) == 0 {
+		return ""
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1]
 }
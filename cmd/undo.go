@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/action"
+)
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo <JOURNAL_FILE>",
+	Short: "Reverse the changes recorded in an apply journal",
+	Long: `Reverse the file operations performed by an 'apply' session using the undo
+journal it wrote. Files created by the session are removed, and overwritten
+or deleted files are restored from the journal's backups. Changes are
+reversed newest first.
+
+Examples:
+  dovetail undo ~/.local/state/dovetail/dovetail_undo_20240115-143000.jsonl
+  dovetail undo ./journals/dovetail_undo_20240115-143000.jsonl --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUndo,
+}
+
+var forceUndo bool
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+
+	undoCmd.Flags().BoolVar(&forceUndo, "force", false, "skip confirmation prompt")
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	journalFile, err := filepath.Abs(args[0])
+	if err != nil {
+		return executionErrorf("failed to resolve journal path: %w", err)
+	}
+
+	if _, err := os.Stat(journalFile); err != nil {
+		if os.IsNotExist(err) {
+			return validationErrorf("journal file does not exist: %s", journalFile)
+		}
+		return validationErrorf("failed to access journal file %s: %w", journalFile, err)
+	}
+
+	journal, err := action.LoadJournal(journalFile)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
+	if journal.UndoneAt != nil {
+		return validationErrorf("journal was already undone at %s", journal.UndoneAt.Format("2006-01-02 15:04:05"))
+	}
+
+	// Safety confirmation unless --force is used
+	if !forceUndo {
+		fmt.Printf("WARNING: This will reverse %d change(s) made by the apply session %s.\n", len(journal.Entries), journal.Session)
+		fmt.Printf("Journal:   %s\n", journalFile)
+		fmt.Printf("Left dir:  %s\n", journal.LeftDir)
+		fmt.Printf("Right dir: %s\n", journal.RightDir)
+		fmt.Printf("\nDo you want to continue? [y/N]: ")
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" && response != "yes" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	results, err := journal.Undo()
+	if err != nil {
+		return executionErrorf("undo failed: %w", err)
+	}
+
+	successCount := 0
+	for _, result := range results {
+		if result.Success {
+			if GetVerboseLevel() >= 1 {
+				fmt.Printf("✓ %s\n", result.Message)
+			}
+			successCount++
+		} else {
+			fmt.Printf("✗ %s\n", result.Message)
+			if result.Error != nil {
+				fmt.Printf("  Error: %s\n", result.Error.Error())
+			}
+		}
+	}
+
+	fmt.Printf("\nUndo Summary:\n")
+	fmt.Printf("=============\n")
+	fmt.Printf("Changes reversed: %d\n", successCount)
+	fmt.Printf("Failed: %d\n", len(results)-successCount)
+
+	if failed := len(results) - successCount; failed > 0 {
+		if successCount > 0 {
+			return partialFailureErrorf("undo completed with %d errors", failed)
+		}
+		return executionErrorf("undo completed with %d errors", failed)
+	}
+
+	fmt.Printf("\nUndo completed successfully! Backups are kept in %s\n", journal.BackupDir)
+	return nil
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch <DIR_LEFT> <DIR_RIGHT>",
+	Short: "Re-run the comparison whenever either directory changes",
+	Long: `Compare two directories, then watch both trees and compare again whenever
+files change. After each run only the differences from the previous run are
+printed: paths that newly differ, paths that became identical, and paths whose
+status or content changed while still differing.
+
+Changes are debounced so a burst of writes (a build, a git checkout) triggers
+a single comparison. Press Ctrl+C to stop.
+
+Examples:
+  dovetail watch ./src ./backup
+  dovetail watch ./src ./backup --exclude-name "*.log" --debounce 2s`,
+	Args: cobra.ExactArgs(2),
+	RunE: runWatch,
+}
+
+var (
+	watchExcludeNames      []string
+	watchExcludePaths      []string
+	watchExcludeExtensions []string
+	watchExcludeRegex      []string
+	watchContentRegex      []string
+	watchIncludePaths      []string
+	watchUseGitignore      bool
+	watchIgnoreCase        bool
+	watchNoDefaultExcludes bool
+	watchHashAlgorithm     string
+	watchDebounce          time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	// Exclusion options
+	watchCmd.Flags().StringSliceVar(&watchExcludeNames, "exclude-name", []string{}, "exclude files/directories by name or glob pattern")
+	watchCmd.Flags().StringSliceVar(&watchExcludePaths, "exclude-path", []string{}, "exclude files/directories by relative path")
+	watchCmd.Flags().StringSliceVar(&watchExcludeExtensions, "exclude-ext", []string{}, "exclude files by extension (without dot)")
+	watchCmd.Flags().StringSliceVar(&watchExcludeRegex, "exclude-regex", []string{}, "exclude files/directories whose relative path matches a regular expression")
+	watchCmd.Flags().StringSliceVar(&watchContentRegex, "exclude-content-regex", []string{}, "exclude files whose first 4 KiB match a regular expression, e.g. a generated-code marker")
+	watchCmd.Flags().StringSliceVar(&watchIncludePaths, "include-path", []string{}, "only compare these relative paths and their contents")
+	watchCmd.Flags().BoolVar(&watchUseGitignore, "use-gitignore", false, "read and apply .gitignore rules from both directories")
+	watchCmd.Flags().BoolVar(&watchIgnoreCase, "ignore-case", false, "match paths that differ only in letter case")
+	watchCmd.Flags().BoolVar(&watchNoDefaultExcludes, "no-default-excludes", false, "don't apply the built-in exclusions enabled by exclusions.use_defaults")
+
+	// Comparison options
+	watchCmd.Flags().StringVar(&watchHashAlgorithm, "hash-algo", compare.DefaultHashAlgorithm, "hash algorithm for content comparison (sha256, blake3, xxhash)")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 500*time.Millisecond, "wait this long after the last change before comparing again")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	leftDir := args[0]
+	rightDir := args[1]
+
+	// Archives can't be watched, so both sides must be directories
+	if err := validateDirectory(leftDir); err != nil {
+		return validationErrorf("left directory: %w", err)
+	}
+	if err := validateDirectory(rightDir); err != nil {
+		return validationErrorf("right directory: %w", err)
+	}
+
+	// Convert to absolute paths
+	leftDir, err := filepath.Abs(leftDir)
+	if err != nil {
+		return executionErrorf("failed to resolve left directory path: %w", err)
+	}
+	rightDir, err = filepath.Abs(rightDir)
+	if err != nil {
+		return executionErrorf("failed to resolve right directory path: %w", err)
+	}
+	if watchDebounce <= 0 {
+		return usageErrorf("--debounce must be positive, got %s", watchDebounce)
+	}
+
+	// Load configuration
+	loader := config.NewLoader(GetVerboseLevel())
+	cfg, err := loader.Load("")
+	if err != nil {
+		return validationErrorf("failed to load configuration: %w", err)
+	}
+
+	cliConfig := config.CLIConfig{
+		VerboseLevel:      GetVerboseLevel(),
+		ExcludeNames:      watchExcludeNames,
+		ExcludePaths:      watchExcludePaths,
+		ExcludeExtensions: watchExcludeExtensions,
+		ExcludeRegex:      watchExcludeRegex,
+		ContentRegex:      watchContentRegex,
+		IncludePaths:      watchIncludePaths,
+		UseGitignore:      watchUseGitignore,
+		IgnoreCase:        watchIgnoreCase,
+		NoDefaultExcludes: watchNoDefaultExcludes,
+	}
+	config.ApplyCLIOverrides(cfg, cliConfig)
+
+	// Process gitignore if enabled
+	ignoreRules, err := gitignoreRules(cfg, leftDir, rightDir)
+	if err != nil {
+		return err
+	}
+
+	options := comparisonOptions(cfg, ignoreRules)
+	options.HashAlgorithm = resolveHashAlgorithm(watchHashAlgorithm)
+
+	engine, err := compare.NewEngine(options)
+	if err != nil {
+		return validationErrorf("%w", err)
+	}
+	engine.SetVerboseLevel(cfg.General.Verbose)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return executionErrorf("failed to start file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	filter := engine.Filter()
+	for _, dir := range []string{leftDir, rightDir} {
+		if err := watchTree(watcher, filter, dir, dir); err != nil {
+			return executionErrorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	// Initial comparison
+	results, summary, err := engine.Compare(leftDir, rightDir)
+	if err != nil {
+		return executionErrorf("comparison failed: %w", err)
+	}
+	fmt.Printf("Watching %s and %s (Ctrl+C to stop)\n", leftDir, rightDir)
+	printWatchDeltas(compare.DiffResults(nil, results))
+	printWatchSummary(summary)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// The timer only runs while changes are pending
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-interrupt:
+			fmt.Println()
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// New directories need watches of their own
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					root := leftDir
+					if isWithin(rightDir, event.Name) {
+						root = rightDir
+					}
+					if err := watchTree(watcher, filter, root, event.Name); err != nil {
+						fmt.Fprintf(os.Stderr, "Warning: failed to watch %s: %v\n", event.Name, err)
+					}
+				}
+			}
+			debounce.Reset(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Warning: watch error: %v\n", err)
+
+		case <-debounce.C:
+			current, summary, err := engine.Compare(leftDir, rightDir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: comparison failed: %v\n", err)
+				continue
+			}
+			deltas := compare.DiffResults(results, current)
+			results = current
+			if len(deltas) == 0 {
+				continue
+			}
+			fmt.Printf("\n[%s] %d path(s) changed\n", time.Now().Format("15:04:05"), len(deltas))
+			printWatchDeltas(deltas)
+			printWatchSummary(summary)
+		}
+	}
+}
+
+// watchTree adds a watch for dir and every directory below it that the
+// filter doesn't exclude. root is the comparison root dir belongs to.
+func watchTree(watcher *fsnotify.Watcher, filter *compare.Filter, root, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil // Unreadable entries and files are skipped
+		}
+		if relPath, err := filepath.Rel(root, path); err == nil && relPath != "." && filter.ShouldExclude(relPath, info) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(dir, path string) bool {
+	relPath, err := filepath.Rel(dir, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// printWatchDeltas prints one line per changed path
+func printWatchDeltas(deltas []compare.ResultDelta) {
+	for _, delta := range deltas {
+		switch delta.Kind {
+		case compare.DeltaNewlyDiffers:
+			fmt.Printf("  + %-13s %s\n", delta.Current.Status, delta.Path)
+		case compare.DeltaNowIdentical:
+			fmt.Printf("  = %-13s %s\n", "IDENTICAL", delta.Path)
+		case compare.DeltaStatusChanged:
+			fmt.Printf("  ~ %-13s %s (was %s)\n", delta.Current.Status, delta.Path, delta.Previous.Status)
+		case compare.DeltaContentChanged:
+			fmt.Printf("  ~ %-13s %s (changed again)\n", delta.Current.Status, delta.Path)
+		}
+	}
+}
+
+// printWatchSummary prints the totals of a comparison on one line
+func printWatchSummary(summary *compare.ComparisonSummary) {
+	fmt.Printf("  Differences: %d modified, %d left only, %d right only\n",
+		summary.ModifiedFiles, summary.OnlyLeftFiles+summary.OnlyLeftDirs, summary.OnlyRightFiles+summary.OnlyRightDirs)
+}
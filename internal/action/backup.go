@@ -0,0 +1,67 @@
+package action
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// backupBeforeChange copies path, if it exists, to the same relative path
+// under side ("left" or "right") in the backup directory. A path changed twice
+// in one run keeps its first backup, so the snapshot shows the tree before the
+// apply; backups left by earlier runs are replaced.
+func (e *Executor) backupBeforeChange(path, side, relPath string) error {
+	if e.backupDir == "" || e.dryRun {
+		return nil
+	}
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("cannot inspect %s for backup: %w", path, err)
+	}
+
+	backup := filepath.Join(e.backupDir, side, relPath)
+	if e.backups.has(backup) {
+		return nil
+	}
+	if err := os.RemoveAll(backup); err != nil {
+		return fmt.Errorf("failed to replace earlier backup %s: %w", backup, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := copyPreserving(path, backup); err != nil {
+		return fmt.Errorf("failed to back up %s to %s: %w", path, backup, err)
+	}
+	e.backups.add(backup)
+	return nil
+}
+
+// backupSet records the backups written during one run. Actions on the same
+// path never run at once, so checking before a backup and adding after it is
+// safe with parallel actions.
+type backupSet struct {
+	mu      sync.Mutex
+	written map[string]bool
+}
+
+// has reports whether path, or a directory containing it, was backed up
+func (b *backupSet) has(path string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if b.written[dir] {
+			return true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return false
+		}
+	}
+}
+
+func (b *backupSet) add(path string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.written[path] = true
+}
@@ -1,19 +1,47 @@
 package action
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"syscall"
 
+	"github.com/harikb/dovetail/internal/config"
 	"github.com/harikb/dovetail/internal/util"
 )
 
 // Executor executes actions from an action file
 type Executor struct {
-	dryRun bool
+	dryRun           bool
+	preserveMetadata bool     // Preserve modification times and ownership on copy
+	newerOnly        bool     // Only overwrite destinations older than the source
+	journal          *Journal // Records changes so they can be undone (nil = disabled)
+	warnings         []string // Non-fatal problems from the action being executed
+	confirm          ConfirmFunc
+	parallelActions  int        // Independent actions run at once (<= 1 = sequential)
+	ioRetries        int        // Extra attempts for copies and deletes that fail transiently
+	backupDir        string     // Snapshot of overwritten and deleted paths, mirrored by side (empty = disabled)
+	backups          *backupSet // Backups written by this executor, which later changes keep
+	verboseLevel     int        // From 1, every finished action is logged with its position
+	progressFunc     util.ProgressFunc
 }
 
+// ConfirmResponse is the answer to a per-action confirmation prompt
+type ConfirmResponse int
+
+const (
+	ConfirmYes  ConfirmResponse = iota // Run this action
+	ConfirmNo                          // Skip this action
+	ConfirmAll                         // Run this and all remaining actions without asking
+	ConfirmQuit                        // Stop before this action
+)
+
+// ConfirmFunc asks whether a destructive action should run. overwrite is
+// true for copies onto an existing destination.
+type ConfirmFunc func(action ActionItem, overwrite bool) ConfirmResponse
+
 // NewExecutor creates a new action executor
 func NewExecutor(dryRun bool) *Executor {
 	return &Executor{
@@ -21,6 +49,88 @@ func NewExecutor(dryRun bool) *Executor {
 	}
 }
 
+// NewConfiguredExecutor creates an executor that makes real changes, set up
+// from the [general] settings that apply wherever actions are applied:
+// preserve_metadata and io_retries. Callers run general.PostApplyHook with
+// RunPostApplyHook once every action succeeded.
+func NewConfiguredExecutor(general config.GeneralConfig) *Executor {
+	executor := NewExecutor(false)
+	executor.SetPreserveMetadata(general.PreserveMetadata)
+	executor.SetIORetries(general.IORetries)
+	return executor
+}
+
+// SetPreserveMetadata controls whether copies preserve modification times and ownership
+func (e *Executor) SetPreserveMetadata(preserve bool) {
+	e.preserveMetadata = preserve
+}
+
+// SetNewerOnly controls whether copies skip destinations that are the same age or newer
+func (e *Executor) SetNewerOnly(newerOnly bool) {
+	e.newerOnly = newerOnly
+}
+
+// SetConfirm makes ExecuteActions ask confirm before every delete, every move
+// and every copy that overwrites an existing destination. Copies creating new files run
+// without asking.
+func (e *Executor) SetConfirm(confirm ConfirmFunc) {
+	e.confirm = confirm
+}
+
+// SetParallelActions runs up to workers independent actions at once. Actions
+// whose paths contain one another still run in action file order. Ignored
+// when a confirmation prompt is set.
+func (e *Executor) SetParallelActions(workers int) {
+	e.parallelActions = workers
+}
+
+// SetIORetries retries copies and deletes up to retries more times, with
+// backoff, when they fail with a transient error such as EAGAIN or a timeout
+func (e *Executor) SetIORetries(retries int) {
+	e.ioRetries = retries
+}
+
+// SetBackupDir copies every path about to be overwritten or deleted into
+// dir/left or dir/right at its relative path first. Ignored in dry-run mode.
+func (e *Executor) SetBackupDir(dir string) {
+	e.backupDir = dir
+	e.backups = &backupSet{written: make(map[string]bool)}
+}
+
+// SetJournal records every change made by ExecuteActions in journal, backing up
+// overwritten and deleted content first. Ignored in dry-run mode.
+func (e *Executor) SetJournal(journal *Journal) {
+	e.journal = journal
+}
+
+// SetVerboseLevel logs each finished action as "[n/total] path: message" on
+// stderr from level 1, so long runs show where they are
+func (e *Executor) SetVerboseLevel(level int) {
+	e.verboseLevel = level
+}
+
+// SetProgressFunc registers a callback that receives how many of the
+// actions to run have finished
+func (e *Executor) SetProgressFunc(fn util.ProgressFunc) {
+	e.progressFunc = fn
+}
+
+// newProgressReporter returns a reporter over the actions that will run
+func (e *Executor) newProgressReporter(actions []ActionItem) *util.ProgressReporter {
+	total := 0
+	for _, action := range actions {
+		if action.Action != ActionIgnore {
+			total++
+		}
+	}
+	progress := util.NewProgressReporter(e.verboseLevel, total)
+	progress.ReportEachItem()
+	if e.progressFunc != nil {
+		progress.SetProgressFunc(e.progressFunc)
+	}
+	return progress
+}
+
 // ExecuteActions executes all actions in an action file
 func (e *Executor) ExecuteActions(
 	actionFile *ActionFile,
@@ -29,7 +139,13 @@ func (e *Executor) ExecuteActions(
 	summary := &ExecutionSummary{
 		TotalActions: len(actionFile.Actions),
 	}
+	progress := e.newProgressReporter(actionFile.Actions)
+	if e.parallelActions > 1 && e.confirm == nil {
+		return e.executeParallel(actionFile, leftDir, rightDir, summary, progress)
+	}
+
 	results := make([]ExecutionResult, 0, len(actionFile.Actions))
+	confirm := e.confirm
 
 	for _, action := range actionFile.Actions {
 		// Skip ignored actions
@@ -37,40 +153,97 @@ func (e *Executor) ExecuteActions(
 			continue
 		}
 
-		result := e.executeAction(action, leftDir, rightDir)
+		// Checked before copying, so the summary can tell overwrites from creations
+		existed := e.fileExists(action, leftDir, rightDir, action.Action)
+
+		if confirm != nil && (existed || isDeleteAction(action.Action) || isMoveAction(action.Action)) {
+			switch confirm(action, existed) {
+			case ConfirmNo:
+				summary.ActionsDeclined++
+				results = append(results, ExecutionResult{
+					Action:  action,
+					Success: true,
+					Skipped: true,
+					Message: fmt.Sprintf("Declined: [%s] %s", action.Action, action.RelativePath),
+				})
+				progress.Report("%s: declined", action.RelativePath)
+				continue
+			case ConfirmAll:
+				confirm = nil
+			case ConfirmQuit:
+				summary.Aborted = true
+				return summary, results, nil
+			}
+		}
+
+		result := e.runAction(action, leftDir, rightDir)
 		results = append(results, result)
+		summary.add(result, existed)
+		progress.Report("%s: %s", action.RelativePath, result.Message)
+	}
 
-		// Update summary
-		if result.Success {
-			summary.SuccessfulActions++
-			summary.BytesCopied += result.BytesCopied
-
-			switch action.Action {
-			case ActionCopyToRight, ActionCopyToLeft:
-				if result.BytesCopied > 0 {
-					// Check if file existed before
-					if e.fileExists(action, leftDir, rightDir, action.Action) {
-						summary.FilesOverwritten++
-					} else {
-						summary.FilesCreated++
-					}
-				}
-			case ActionDeleteLeft, ActionDeleteRight, ActionDeleteBoth:
-				if action.Action == ActionDeleteBoth {
-					summary.FilesDeleted += 2
-				} else {
-					summary.FilesDeleted++
-				}
-			}
+	return summary, results, nil
+}
+
+// runAction executes one action on a copy of the executor, so the warnings it
+// collects belong to that action alone
+func (e *Executor) runAction(action ActionItem, leftDir, rightDir string) ExecutionResult {
+	worker := *e
+	worker.warnings = nil
+	result := worker.executeAction(action, leftDir, rightDir)
+	result.Warnings = worker.warnings
+	return result
+}
+
+// add counts the outcome of an action. existed tells whether the target
+// existed before the action ran.
+func (summary *ExecutionSummary) add(result ExecutionResult, existed bool) {
+	action := result.Action
+	for _, warning := range result.Warnings {
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("%s: %s", action.RelativePath, warning))
+	}
+
+	if !result.Success {
+		summary.FailedActions++
+		if result.Error != nil {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("%s: %s", action.RelativePath, result.Error.Error()))
+		}
+		return
+	}
+
+	summary.SuccessfulActions++
+	summary.BytesCopied += result.BytesCopied
+	if result.Skipped {
+		summary.FilesSkipped++
+		return
+	}
+
+	switch action.Action {
+	case ActionSymlinkToRight, ActionSymlinkToLeft:
+		if existed {
+			summary.FilesOverwritten++
 		} else {
-			summary.FailedActions++
-			if result.Error != nil {
-				summary.Errors = append(summary.Errors, fmt.Sprintf("%s: %s", action.RelativePath, result.Error.Error()))
+			summary.FilesCreated++
+		}
+	case ActionCopyToRight, ActionCopyToLeft:
+		if result.BytesCopied > 0 {
+			if existed {
+				summary.FilesOverwritten++
+			} else {
+				summary.FilesCreated++
 			}
 		}
+	case ActionChmodToRight, ActionChmodToLeft:
+		summary.FilesChmodded++
+	case ActionMoveRight, ActionMoveLeft:
+		summary.FilesMoved++
+	case ActionDeleteLeft, ActionDeleteRight, ActionDeleteBoth:
+		if action.Action == ActionDeleteBoth {
+			summary.FilesDeleted += 2
+		} else {
+			summary.FilesDeleted++
+		}
 	}
-
-	return summary, results, nil
 }
 
 // executeAction executes a single action
@@ -79,20 +252,41 @@ func (e *Executor) executeAction(action ActionItem, leftDir, rightDir string) Ex
 		Action: action,
 	}
 
+	// Checked again here so unvalidated action files can't reach outside the roots
+	for _, path := range []string{action.RelativePath, action.RightPath(), action.DestinationPath()} {
+		if !IsContainedPath(path) {
+			result.Error = fmt.Errorf("path %q escapes the compared directories", path)
+			result.Message = "Failed: Path outside the compared directories"
+			return result
+		}
+	}
+
 	leftPath := filepath.Join(leftDir, action.RelativePath)
-	rightPath := filepath.Join(rightDir, action.RelativePath)
+	rightPath := filepath.Join(rightDir, action.RightPath())
 
 	switch action.Action {
 	case ActionCopyToRight:
-		result = e.executeCopy(leftPath, rightPath, action, "left", "right")
+		result = e.executeCopy(leftPath, filepath.Join(rightDir, action.DestinationPath()), action, "left", "right")
 	case ActionCopyToLeft:
-		result = e.executeCopy(rightPath, leftPath, action, "right", "left")
+		result = e.executeCopy(rightPath, filepath.Join(leftDir, action.DestinationPath()), action, "right", "left")
 	case ActionDeleteLeft:
 		result = e.executeDelete(leftPath, action, "left")
 	case ActionDeleteRight:
 		result = e.executeDelete(rightPath, action, "right")
 	case ActionDeleteBoth:
 		result = e.executeDeleteBoth(leftPath, rightPath, action)
+	case ActionChmodToRight:
+		result = e.executeChmod(leftPath, rightPath, action, "left", "right")
+	case ActionChmodToLeft:
+		result = e.executeChmod(rightPath, leftPath, action, "right", "left")
+	case ActionSymlinkToRight:
+		result = e.executeSymlink(leftPath, rightPath, action, "left", "right")
+	case ActionSymlinkToLeft:
+		result = e.executeSymlink(rightPath, leftPath, action, "right", "left")
+	case ActionMoveRight:
+		result = e.executeMove(filepath.Join(rightDir, action.DestinationPath()), rightPath, action, "right", action.DestinationPath())
+	case ActionMoveLeft:
+		result = e.executeMove(leftPath, filepath.Join(leftDir, action.DestinationPath()), action, "left", action.RelativePath)
 	case ActionIgnore:
 		result.Success = true
 		result.Message = "Ignored"
@@ -111,12 +305,6 @@ func (e *Executor) executeCopy(srcPath, dstPath string, action ActionItem, srcNa
 		Action: action,
 	}
 
-	if e.dryRun {
-		result.Success = true
-		result.Message = fmt.Sprintf("DRY RUN: Would COPY %s -> %s", srcPath, dstPath)
-		return result
-	}
-
 	// Check if source exists
 	srcInfo, err := os.Stat(srcPath)
 	if err != nil {
@@ -125,33 +313,266 @@ func (e *Executor) executeCopy(srcPath, dstPath string, action ActionItem, srcNa
 		return result
 	}
 
+	// Re-stat the destination rather than trusting comparison-time data,
+	// which may be stale by the time the action runs
+	if e.newerOnly && !srcInfo.IsDir() {
+		if dstInfo, err := os.Stat(dstPath); err == nil && !dstInfo.IsDir() && !srcInfo.ModTime().After(dstInfo.ModTime()) {
+			result.Success = true
+			result.Skipped = true
+			result.Message = fmt.Sprintf("Skipped copy from %s to %s: %s copy is not older (%s vs %s)",
+				srcName, dstName, dstName,
+				dstInfo.ModTime().Format("2006-01-02 15:04:05"),
+				srcInfo.ModTime().Format("2006-01-02 15:04:05"))
+			return result
+		}
+	}
+
+	// A dry run reports the bytes a real copy would transfer
+	if e.dryRun {
+		size := srcInfo.Size()
+		if srcInfo.IsDir() {
+			if size, err = treeSize(srcPath); err != nil {
+				result.Error = fmt.Errorf("failed to measure source directory: %w", err)
+				result.Message = fmt.Sprintf("Failed to copy from %s to %s", srcName, dstName)
+				return result
+			}
+		}
+		result.Success = true
+		result.BytesCopied = size
+		result.Message = fmt.Sprintf("DRY RUN: Would COPY %s -> %s (%s)", srcPath, dstPath, util.FormatSize(size))
+		return result
+	}
+
+	if err := e.backupBeforeChange(dstPath, dstName, action.DestinationPath()); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before copying", dstPath)
+		return result
+	}
+	if err := e.journalChange(dstPath, false); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before copying", dstPath)
+		return result
+	}
+
 	// Create destination directory if needed
-	dstDir := filepath.Dir(dstPath)
-	if err := os.MkdirAll(dstDir, 0755); err != nil {
+	if err := e.makeParentDirs(dstPath); err != nil {
 		result.Error = fmt.Errorf("failed to create destination directory: %w", err)
 		result.Message = fmt.Sprintf("Failed to create directory for %s", dstPath)
 		return result
 	}
 
+	// A retried copy starts over, overwriting whatever the failed attempt wrote
+	var retries int
 	if srcInfo.IsDir() {
 		// Copy directory
-		result.Message = fmt.Sprintf("Copied directory from %s to %s", srcName, dstName)
-		err = e.copyDirectory(srcPath, dstPath)
+		retries, err = e.withRetries(func() error {
+			var copyErr error
+			result.BytesCopied, copyErr = e.copyDirectory(srcPath, dstPath)
+			return copyErr
+		})
+		result.Message = fmt.Sprintf("Copied directory from %s to %s (%s%s)", srcName, dstName, util.FormatSize(result.BytesCopied), retryNote(", ", retries))
 	} else {
 		// Copy file
-		var bytesCopied int64
-		bytesCopied, err = e.copyFile(srcPath, dstPath)
-		result.BytesCopied = bytesCopied
-		result.Message = fmt.Sprintf("Copied file from %s to %s (%s)", srcName, dstName, util.FormatSize(bytesCopied))
+		retries, err = e.withRetries(func() error {
+			var copyErr error
+			result.BytesCopied, copyErr = e.copyFile(srcPath, dstPath)
+			return copyErr
+		})
+		result.Message = fmt.Sprintf("Copied file from %s to %s (%s%s)", srcName, dstName, util.FormatSize(result.BytesCopied), retryNote(", ", retries))
+	}
+
+	if err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to copy from %s to %s%s: %s", srcName, dstName, retryNote(" after ", retries), err.Error())
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// executeChmod gives dstPath the permission bits of srcPath without touching
+// its content
+func (e *Executor) executeChmod(srcPath, dstPath string, action ActionItem, srcName, dstName string) ExecutionResult {
+	result := ExecutionResult{
+		Action: action,
+	}
+
+	// Lstat, because chmod on a symlink would change its target instead
+	srcInfo, err := os.Lstat(srcPath)
+	if err == nil && srcInfo.Mode()&os.ModeSymlink != 0 {
+		err = fmt.Errorf("%s is a symlink", srcPath)
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("source cannot be used: %w", err)
+		result.Message = fmt.Sprintf("Failed to copy permissions from %s to %s", srcName, dstName)
+		return result
+	}
+	dstInfo, err := os.Lstat(dstPath)
+	if err == nil && dstInfo.Mode()&os.ModeSymlink != 0 {
+		err = fmt.Errorf("%s is a symlink", dstPath)
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("destination cannot be used: %w", err)
+		result.Message = fmt.Sprintf("Failed to copy permissions from %s to %s", srcName, dstName)
+		return result
+	}
+
+	mode := srcInfo.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	change := fmt.Sprintf("%s -> %s", dstInfo.Mode(), dstInfo.Mode().Type()|mode)
+	if e.dryRun {
+		result.Success = true
+		result.Message = fmt.Sprintf("DRY RUN: Would CHMOD %s (%s)", dstPath, change)
+		return result
+	}
+
+	if err := e.backupBeforeChange(dstPath, dstName, sidePath(action, dstName)); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before changing permissions", dstPath)
+		return result
+	}
+	if err := e.journalChange(dstPath, false); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before changing permissions", dstPath)
+		return result
+	}
+
+	if err := os.Chmod(dstPath, mode); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to copy permissions from %s to %s: %s", srcName, dstName, err.Error())
+		return result
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Copied permissions from %s to %s (%s)", srcName, dstName, change)
+	return result
+}
+
+// executeSymlink recreates the symlink at srcPath at dstPath with the same
+// target, instead of copying the content it points to
+func (e *Executor) executeSymlink(srcPath, dstPath string, action ActionItem, srcName, dstName string) ExecutionResult {
+	result := ExecutionResult{
+		Action: action,
+	}
+
+	// Read the target now; the one seen when comparing may be stale
+	target, err := os.Readlink(srcPath)
+	if err == nil && !IsContainedLinkTarget(action.RelativePath, target) {
+		err = fmt.Errorf("target %q points outside the compared directories", target)
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("source symlink cannot be used: %w", err)
+		result.Message = fmt.Sprintf("Failed to create symlink from %s on %s", srcName, dstName)
+		return result
+	}
+	// Replacing a directory would drop everything in it
+	if dstInfo, err := os.Lstat(dstPath); err == nil && dstInfo.IsDir() {
+		result.Error = fmt.Errorf("%s is a directory", dstPath)
+		result.Message = fmt.Sprintf("Failed to create symlink from %s on %s", srcName, dstName)
+		return result
+	}
+
+	if e.dryRun {
+		result.Success = true
+		result.Message = fmt.Sprintf("DRY RUN: Would SYMLINK %s -> %s", dstPath, target)
+		return result
+	}
+
+	if err := e.backupBeforeChange(dstPath, dstName, sidePath(action, dstName)); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before creating symlink", dstPath)
+		return result
+	}
+	if err := e.journalChange(dstPath, false); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before creating symlink", dstPath)
+		return result
+	}
+
+	if err := e.makeParentDirs(dstPath); err != nil {
+		result.Error = fmt.Errorf("failed to create destination directory: %w", err)
+		result.Message = fmt.Sprintf("Failed to create directory for %s", dstPath)
+		return result
+	}
+	if err := os.Remove(dstPath); err != nil && !os.IsNotExist(err) {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to replace %s: %s", dstPath, err.Error())
+		return result
+	}
+	if err := os.Symlink(target, dstPath); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to create symlink from %s on %s: %s", srcName, dstName, err.Error())
+		return result
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Created symlink from %s on %s (-> %s)", srcName, dstName, target)
+	return result
+}
+
+// executeMove renames srcPath to dstPath within one side, reconciling a
+// renamed entry without copying it. srcRel is srcPath relative to the side.
+func (e *Executor) executeMove(srcPath, dstPath string, action ActionItem, side, srcRel string) ExecutionResult {
+	result := ExecutionResult{
+		Action: action,
+	}
+
+	if _, err := os.Lstat(srcPath); err != nil {
+		result.Error = fmt.Errorf("source cannot be used: %w", err)
+		result.Message = fmt.Sprintf("Failed to move in %s", side)
+		return result
+	}
+	// A move never overwrites; that would need a copy action instead
+	if _, err := os.Lstat(dstPath); err == nil {
+		result.Error = fmt.Errorf("destination %s already exists", dstPath)
+		result.Message = fmt.Sprintf("Failed to move in %s", side)
+		return result
+	}
+
+	if e.dryRun {
+		result.Success = true
+		result.Message = fmt.Sprintf("DRY RUN: Would MOVE %s -> %s", srcPath, dstPath)
+		return result
+	}
+
+	if err := e.backupBeforeChange(srcPath, side, srcRel); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before moving", srcPath)
+		return result
+	}
+	// Journaled as a new destination and a removed source, so undo puts it back
+	if err := e.journalChange(dstPath, false); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to record move of %s", srcPath)
+		return result
+	}
+	if err := e.journalChange(srcPath, true); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before moving", srcPath)
+		return result
+	}
+
+	if err := e.makeParentDirs(dstPath); err != nil {
+		result.Error = fmt.Errorf("failed to create destination directory: %w", err)
+		result.Message = fmt.Sprintf("Failed to create directory for %s", dstPath)
+		return result
 	}
 
+	err := os.Rename(srcPath, dstPath)
+	if errors.Is(err, syscall.EXDEV) {
+		// Rename can't cross filesystems; copy and remove instead
+		if err = copyPreserving(srcPath, dstPath); err == nil {
+			err = os.RemoveAll(srcPath)
+		}
+	}
 	if err != nil {
 		result.Error = err
-		result.Message = fmt.Sprintf("Failed to copy from %s to %s: %s", srcName, dstName, err.Error())
+		result.Message = fmt.Sprintf("Failed to move in %s: %s", side, err.Error())
 		return result
 	}
 
 	result.Success = true
+	result.Message = fmt.Sprintf("Moved in %s: %s -> %s", side, srcPath, dstPath)
 	return result
 }
 
@@ -181,18 +602,32 @@ func (e *Executor) executeDelete(path string, action ActionItem, location string
 		return result
 	}
 
+	if err := e.backupBeforeChange(path, location, sidePath(action, location)); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before deleting", path)
+		return result
+	}
+	if err := e.journalChange(path, true); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to back up %s before deleting", path)
+		return result
+	}
+
 	// Delete the file or directory
-	if err := os.RemoveAll(path); err != nil {
+	retries, err := e.withRetries(func() error {
+		return os.RemoveAll(path)
+	})
+	if err != nil {
 		result.Error = err
-		result.Message = fmt.Sprintf("Failed to delete from %s: %s", location, err.Error())
+		result.Message = fmt.Sprintf("Failed to delete from %s%s: %s", location, retryNote(" after ", retries), err.Error())
 		return result
 	}
 
 	result.Success = true
 	if info.IsDir() {
-		result.Message = fmt.Sprintf("Deleted directory from %s", location)
+		result.Message = fmt.Sprintf("Deleted directory from %s%s", location, retryNote(" after ", retries))
 	} else {
-		result.Message = fmt.Sprintf("Deleted file from %s (%s)", location, util.FormatSize(info.Size()))
+		result.Message = fmt.Sprintf("Deleted file from %s (%s%s)", location, util.FormatSize(info.Size()), retryNote(", ", retries))
 	}
 
 	return result
@@ -213,12 +648,20 @@ func (e *Executor) executeDeleteBoth(leftPath, rightPath string, action ActionIt
 	var errors []string
 
 	// Delete from left
-	if err := os.RemoveAll(leftPath); err != nil && !os.IsNotExist(err) {
+	if err := e.backupBeforeChange(leftPath, "left", action.RelativePath); err != nil {
+		errors = append(errors, fmt.Sprintf("left: %s", err.Error()))
+	} else if err := e.journalChange(leftPath, true); err != nil {
+		errors = append(errors, fmt.Sprintf("left: %s", err.Error()))
+	} else if err := os.RemoveAll(leftPath); err != nil && !os.IsNotExist(err) {
 		errors = append(errors, fmt.Sprintf("left: %s", err.Error()))
 	}
 
 	// Delete from right
-	if err := os.RemoveAll(rightPath); err != nil && !os.IsNotExist(err) {
+	if err := e.backupBeforeChange(rightPath, "right", action.RightPath()); err != nil {
+		errors = append(errors, fmt.Sprintf("right: %s", err.Error()))
+	} else if err := e.journalChange(rightPath, true); err != nil {
+		errors = append(errors, fmt.Sprintf("right: %s", err.Error()))
+	} else if err := os.RemoveAll(rightPath); err != nil && !os.IsNotExist(err) {
 		errors = append(errors, fmt.Sprintf("right: %s", err.Error()))
 	}
 
@@ -233,6 +676,26 @@ func (e *Executor) executeDeleteBoth(leftPath, rightPath string, action ActionIt
 	return result
 }
 
+// makeParentDirs creates the missing parent directories of path, recording
+// them in the undo journal first, if enabled
+func (e *Executor) makeParentDirs(path string) error {
+	dir := filepath.Dir(path)
+	if e.journal != nil {
+		if err := e.journal.recordDirs(dir); err != nil {
+			return err
+		}
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+// journalChange records a pending change to path in the undo journal, if enabled
+func (e *Executor) journalChange(path string, deleting bool) error {
+	if e.journal == nil {
+		return nil
+	}
+	return e.journal.recordChange(path, deleting)
+}
+
 // copyFile copies a single file
 func (e *Executor) copyFile(srcPath, dstPath string) (int64, error) {
 	srcFile, err := os.Open(srcPath)
@@ -245,30 +708,63 @@ func (e *Executor) copyFile(srcPath, dstPath string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	defer dstFile.Close()
 
 	// Copy file contents
 	bytesCopied, err := io.Copy(dstFile, srcFile)
 	if err != nil {
+		dstFile.Close()
+		return bytesCopied, err
+	}
+	// A failed close can lose written data. Closing before setting times
+	// also keeps buffered writes from bumping mtime again.
+	if err := dstFile.Close(); err != nil {
 		return bytesCopied, err
 	}
 
-	// Copy file permissions
-	srcInfo, err := os.Stat(srcPath)
+	// Copy file permissions. The content was copied either way, so failures
+	// are warnings and don't stop the metadata from being preserved.
+	srcInfo, err := srcFile.Stat()
 	if err != nil {
-		return bytesCopied, nil // File copied, but couldn't preserve permissions
+		e.warnings = append(e.warnings, fmt.Sprintf("could not preserve permissions on %s: %v", dstPath, err))
+		return bytesCopied, nil
 	}
 
 	if err := os.Chmod(dstPath, srcInfo.Mode()); err != nil {
-		return bytesCopied, nil // File copied, but couldn't preserve permissions
+		e.warnings = append(e.warnings, fmt.Sprintf("could not preserve permissions on %s: %v", dstPath, err))
+	}
+
+	if e.preserveMetadata {
+		e.copyMetadata(dstPath, srcInfo)
 	}
 
 	return bytesCopied, nil
 }
 
+// copyMetadata applies the source's modification time and ownership to dstPath.
+// Failures are recorded as warnings since the content was copied successfully.
+func (e *Executor) copyMetadata(dstPath string, srcInfo os.FileInfo) {
+	if err := os.Chtimes(dstPath, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
+		e.warnings = append(e.warnings, fmt.Sprintf("could not preserve modification time on %s: %v", dstPath, err))
+	}
+
+	if uid, gid, ok := util.FileOwner(srcInfo); ok {
+		if err := os.Chown(dstPath, uid, gid); err != nil {
+			e.warnings = append(e.warnings, fmt.Sprintf("could not preserve ownership on %s: %v", dstPath, err))
+		}
+	}
+}
+
 // copyDirectory recursively copies a directory
-func (e *Executor) copyDirectory(srcPath, dstPath string) error {
-	return filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
+func (e *Executor) copyDirectory(srcPath, dstPath string) (int64, error) {
+	// Directory times must be set after their contents are copied
+	type dirMetadata struct {
+		path string
+		info os.FileInfo
+	}
+	var dirs []dirMetadata
+	var total int64
+
+	err := filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -282,6 +778,9 @@ func (e *Executor) copyDirectory(srcPath, dstPath string) error {
 
 		if info.IsDir() {
 			// Create directory
+			if e.preserveMetadata {
+				dirs = append(dirs, dirMetadata{path: dstFilePath, info: info})
+			}
 			return os.MkdirAll(dstFilePath, info.Mode())
 		} else {
 			// Create directory for file if needed
@@ -291,10 +790,64 @@ func (e *Executor) copyDirectory(srcPath, dstPath string) error {
 			}
 
 			// Copy file
-			_, err := e.copyFile(path, dstFilePath)
+			bytesCopied, err := e.copyFile(path, dstFilePath)
+			total += bytesCopied
 			return err
 		}
 	})
+	if err != nil {
+		return total, err
+	}
+
+	// Deepest directories first so parents aren't touched afterwards
+	for i := len(dirs) - 1; i >= 0; i-- {
+		e.copyMetadata(dirs[i].path, dirs[i].info)
+	}
+
+	return total, nil
+}
+
+// treeSize returns the total size of the files below dir
+func treeSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// isDeleteAction reports whether actionType removes files
+func isDeleteAction(actionType ActionType) bool {
+	return actionType == ActionDeleteLeft || actionType == ActionDeleteRight || actionType == ActionDeleteBoth
+}
+
+// isChmodAction reports whether actionType only copies permissions
+func isChmodAction(actionType ActionType) bool {
+	return actionType == ActionChmodToRight || actionType == ActionChmodToLeft
+}
+
+// isMoveAction reports whether actionType moves a renamed entry
+func isMoveAction(actionType ActionType) bool {
+	return actionType == ActionMoveRight || actionType == ActionMoveLeft
+}
+
+// sidePath returns the relative path of the action's entry on side ("left" or "right")
+func sidePath(action ActionItem, side string) string {
+	if side == "right" {
+		return action.RightPath()
+	}
+	return action.RelativePath
+}
+
+// isSymlinkAction reports whether actionType recreates a symlink
+func isSymlinkAction(actionType ActionType) bool {
+	return actionType == ActionSymlinkToRight || actionType == ActionSymlinkToLeft
 }
 
 // fileExists checks if a file exists at the target location for the given action
@@ -303,13 +856,17 @@ func (e *Executor) fileExists(action ActionItem, leftDir, rightDir string, actio
 
 	switch actionType {
 	case ActionCopyToRight:
-		targetPath = filepath.Join(rightDir, action.RelativePath)
+		targetPath = filepath.Join(rightDir, action.DestinationPath())
 	case ActionCopyToLeft:
+		targetPath = filepath.Join(leftDir, action.DestinationPath())
+	case ActionChmodToRight, ActionSymlinkToRight:
+		targetPath = filepath.Join(rightDir, action.RightPath())
+	case ActionChmodToLeft, ActionSymlinkToLeft:
 		targetPath = filepath.Join(leftDir, action.RelativePath)
 	default:
 		return false
 	}
 
-	_, err := os.Stat(targetPath)
+	_, err := os.Lstat(targetPath)
 	return err == nil
 }
@@ -3,7 +3,7 @@ package action
 import (
 	"fmt"
 	"io"
-	"sort"
+	"strings"
 	"time"
 
 	"github.com/harikb/dovetail/internal/compare"
@@ -13,6 +13,163 @@ import (
 // Generator creates action files from comparison results
 type Generator struct {
 	version string
+	actions map[string]ActionType // Preset actions by relative path (nil = all ignore)
+	mirror  MirrorSource          // Side the other is made to match (MirrorNone = all ignore)
+	prefer  Preference            // Side differing files default to copying from (PreferNone = all ignore)
+	preface string                // Custom header template written before the standard header
+
+	reproducible bool // Omit the generation time so identical inputs give identical files
+}
+
+// MirrorSource selects the side a mirror action file treats as authoritative
+type MirrorSource int
+
+const (
+	MirrorNone      MirrorSource = iota // Default every action to ignore
+	MirrorFromLeft                      // Make Right match Left
+	MirrorFromRight                     // Make Left match Right
+)
+
+// chmodAction returns the action that copies the source's permissions onto
+// the mirrored side
+func (s MirrorSource) chmodAction() ActionType {
+	switch s {
+	case MirrorFromLeft:
+		return ActionChmodToRight
+	case MirrorFromRight:
+		return ActionChmodToLeft
+	default:
+		return ActionIgnore
+	}
+}
+
+// ParseMirrorSource parses "left" or "right" into a MirrorSource
+func ParseMirrorSource(s string) (MirrorSource, bool) {
+	switch s {
+	case "left":
+		return MirrorFromLeft, true
+	case "right":
+		return MirrorFromRight, true
+	default:
+		return MirrorNone, false
+	}
+}
+
+// mirrorAction returns the action that makes the mirrored side match the source
+func (s MirrorSource) mirrorAction(status compare.FileStatus) ActionType {
+	switch {
+	case s == MirrorFromLeft && (status == compare.StatusModified || status == compare.StatusOnlyLeft):
+		return ActionCopyToRight
+	case s == MirrorFromLeft && status == compare.StatusOnlyRight:
+		return ActionDeleteRight
+	case s == MirrorFromRight && (status == compare.StatusModified || status == compare.StatusOnlyRight):
+		return ActionCopyToLeft
+	case s == MirrorFromRight && status == compare.StatusOnlyLeft:
+		return ActionDeleteLeft
+	case s == MirrorFromLeft && status == compare.StatusRenamed:
+		return ActionMoveRight
+	case s == MirrorFromRight && status == compare.StatusRenamed:
+		return ActionMoveLeft
+	default:
+		return ActionIgnore
+	}
+}
+
+// Preference selects which side's version of a file the generated actions
+// keep, for syncing in both directions without deleting anything
+type Preference int
+
+const (
+	PreferNone  Preference = iota // Default every action to ignore
+	PreferNewer                   // Copy the more recently modified side over the other
+	PreferOlder                   // Copy the less recently modified side over the other
+	PreferLeft                    // Copy Left over Right
+	PreferRight                   // Copy Right over Left
+)
+
+// ParsePreference parses "newer", "older", "left" or "right" into a Preference
+func ParsePreference(s string) (Preference, bool) {
+	switch s {
+	case "newer":
+		return PreferNewer, true
+	case "older":
+		return PreferOlder, true
+	case "left":
+		return PreferLeft, true
+	case "right":
+		return PreferRight, true
+	default:
+		return PreferNone, false
+	}
+}
+
+// String returns the name ParsePreference accepts
+func (p Preference) String() string {
+	switch p {
+	case PreferNewer:
+		return "newer"
+	case PreferOlder:
+		return "older"
+	case PreferLeft:
+		return "left"
+	case PreferRight:
+		return "right"
+	default:
+		return "none"
+	}
+}
+
+// preferredAction returns the action that keeps the preferred version of a
+// result. Entries missing a side are copied there only under newer, as the
+// one copy is the newest version. Renamed entries take the preferred side's
+// path under left and right.
+func (p Preference) preferredAction(result compare.ComparisonResult) ActionType {
+	switch {
+	case result.Status == compare.StatusRenamed && p == PreferLeft:
+		return ActionMoveRight
+	case result.Status == compare.StatusRenamed && p == PreferRight:
+		return ActionMoveLeft
+	case result.Status == compare.StatusOnlyLeft && p == PreferNewer:
+		return ActionCopyToRight
+	case result.Status == compare.StatusOnlyRight && p == PreferNewer:
+		return ActionCopyToLeft
+	case result.Status == compare.StatusModified:
+		return p.modifiedAction(result)
+	default:
+		return ActionIgnore
+	}
+}
+
+// modifiedAction returns the action for an entry present on both sides.
+// Under newer and older, entries modified at the same time are ignored.
+// Entries differing only in permissions get those copied instead.
+func (p Preference) modifiedAction(result compare.ComparisonResult) ActionType {
+	var fromLeft bool
+	switch p {
+	case PreferNewer, PreferOlder:
+		left, right := result.LeftInfo.ModTime, result.RightInfo.ModTime
+		if left.Equal(right) {
+			return ActionIgnore
+		}
+		fromLeft = left.After(right) == (p == PreferNewer)
+	case PreferLeft:
+		fromLeft = true
+	case PreferRight:
+		fromLeft = false
+	default:
+		return ActionIgnore
+	}
+
+	switch {
+	case fromLeft && result.Changes.PermsOnly():
+		return ActionChmodToRight
+	case fromLeft:
+		return ActionCopyToRight
+	case result.Changes.PermsOnly():
+		return ActionChmodToLeft
+	default:
+		return ActionCopyToLeft
+	}
 }
 
 // NewGenerator creates a new action file generator
@@ -22,6 +179,76 @@ func NewGenerator(version string) *Generator {
 	}
 }
 
+// SetActions presets the action written for each relative path; paths not in
+// the map default to ignore
+func (g *Generator) SetActions(actions map[string]ActionType) {
+	g.actions = actions
+}
+
+// SetMirror pre-fills every action so that one side becomes an exact copy of
+// the other: differing files are copied over and extra entries are deleted
+func (g *Generator) SetMirror(source MirrorSource) {
+	g.mirror = source
+}
+
+// SetPreference pre-fills the action of every differing file to copy the
+// preferred side's version over the other. Nothing is deleted.
+func (g *Generator) SetPreference(prefer Preference) {
+	g.prefer = prefer
+}
+
+// SetHeaderTemplate sets a custom preamble written before the standard header.
+// {left}, {right}, {date} and {version} are replaced, and lines not already
+// comments are prefixed with "# ".
+func (g *Generator) SetHeaderTemplate(template string) {
+	g.preface = template
+}
+
+// SetReproducible leaves the generation time out of the header, so that the
+// same comparison always produces a byte-identical action file
+func (g *Generator) SetReproducible(reproducible bool) {
+	g.reproducible = reproducible
+}
+
+// generatedAt returns the timestamp written in the header
+func (g *Generator) generatedAt() string {
+	if g.reproducible {
+		return reproducibleTimestamp
+	}
+	return time.Now().Format("2006-01-02 15:04:05")
+}
+
+// reproducibleTimestamp stands in for the generation time with SetReproducible
+const reproducibleTimestamp = "(omitted, reproducible output)"
+
+// prefaceLines expands the header template for a file being generated
+func (g *Generator) prefaceLines(header ActionFileHeader) []string {
+	template := strings.TrimRight(g.preface, "\n")
+	if template == "" {
+		return nil
+	}
+
+	replacer := strings.NewReplacer(
+		"{left}", header.LeftDir,
+		"{right}", header.RightDir,
+		"{date}", header.GeneratedAt,
+		"{version}", header.Version,
+	)
+	var lines []string
+	for _, line := range strings.Split(replacer.Replace(template), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "#"):
+		case line == "":
+			line = "#"
+		default:
+			line = "# " + line
+		}
+		lines = append(lines, line)
+	}
+	return append(lines, "#")
+}
+
 // GenerateActionFile creates an action file from comparison results
 func (g *Generator) GenerateActionFile(
 	writer io.Writer,
@@ -31,7 +258,7 @@ func (g *Generator) GenerateActionFile(
 	includeIdentical bool,
 ) error {
 	header := ActionFileHeader{
-		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+		GeneratedAt: g.generatedAt(),
 		LeftDir:     leftDir,
 		RightDir:    rightDir,
 		Version:     g.version,
@@ -42,11 +269,11 @@ func (g *Generator) GenerateActionFile(
 		return fmt.Errorf("failed to write header: %w", err)
 	}
 
-	// Convert comparison results to action items and sort them
-	actionItems := g.convertToActionItems(results, includeIdentical)
-	sort.Slice(actionItems, func(i, j int) bool {
-		return actionItems[i].RelativePath < actionItems[j].RelativePath
-	})
+	// Results arrive in worker completion order; sorting them canonically
+	// keeps the file stable across runs
+	sorted := append([]compare.ComparisonResult(nil), results...)
+	compare.SortResults(sorted)
+	actionItems := g.convertToActionItems(sorted, includeIdentical)
 
 	// Write action items
 	for _, item := range actionItems {
@@ -58,9 +285,26 @@ func (g *Generator) GenerateActionFile(
 	return nil
 }
 
+// BuildActionFile returns the actions GenerateActionFile would write for
+// differing results, for running them directly without a file
+func (g *Generator) BuildActionFile(results []compare.ComparisonResult, leftDir, rightDir string) *ActionFile {
+	sorted := append([]compare.ComparisonResult(nil), results...)
+	compare.SortResults(sorted)
+	return &ActionFile{
+		Header: ActionFileHeader{
+			GeneratedAt: g.generatedAt(),
+			LeftDir:     leftDir,
+			RightDir:    rightDir,
+			Version:     g.version,
+		},
+		Actions: g.convertToActionItems(sorted, false),
+	}
+}
+
 // writeHeader writes the action file header with metadata and instructions
 func (g *Generator) writeHeader(writer io.Writer, header ActionFileHeader, summary *compare.ComparisonSummary) error {
-	lines := []string{
+	lines := g.prefaceLines(header)
+	lines = append(lines,
 		fmt.Sprintf("# Action File generated on %s", header.GeneratedAt),
 		fmt.Sprintf("# Generated by dovetail version %s", header.Version),
 		fmt.Sprintf("# Left:  %s", header.LeftDir),
@@ -68,18 +312,31 @@ func (g *Generator) writeHeader(writer io.Writer, header ActionFileHeader, summa
 		"#",
 		"# INSTRUCTIONS:",
 		"# Edit the [ACTION] for each file to specify what you want to do.",
-		"# By default, all actions are set to [i] (ignore) to prevent accidents.",
-		"#",
-		"# Available Actions:",
-		fmt.Sprintf("#   %-3s : %s", ActionIgnore.String(), ActionIgnore.Description()),
-		fmt.Sprintf("#   %-3s : %s", ActionCopyToRight.String(), ActionCopyToRight.Description()),
-		fmt.Sprintf("#   %-3s : %s", ActionCopyToLeft.String(), ActionCopyToLeft.Description()),
-		fmt.Sprintf("#   %-3s : %s", ActionDeleteLeft.String(), ActionDeleteLeft.Description()),
-		fmt.Sprintf("#   %-3s : %s", ActionDeleteRight.String(), ActionDeleteRight.Description()),
-		fmt.Sprintf("#   %-3s : %s", ActionDeleteBoth.String(), ActionDeleteBoth.Description()),
+	)
+	if target, source := g.mirror.sides(); target != "" {
+		lines = append(lines,
+			fmt.Sprintf("# WARNING: DESTRUCTIVE MIRROR. Actions are pre-filled to make %s an exact", target),
+			fmt.Sprintf("# copy of %s: differing files are overwritten and entries only in %s", source, target),
+			"# are DELETED. Preview with 'dovetail dry-run' before applying.",
+			"#",
+		)
+	} else if g.prefer != PreferNone {
+		lines = append(lines,
+			fmt.Sprintf("# Actions are pre-filled to keep the %s version of each differing file", g.prefer),
+			"# (--prefer). Review them, and preview with 'dovetail dry-run' before applying.",
+			"#",
+		)
+	} else {
+		lines = append(lines,
+			"# By default, all actions are set to [i] (ignore) to prevent accidents.",
+			"#",
+		)
+	}
+	lines = append(lines, actionLegendLines()...)
+	lines = append(lines,
 		"#",
 		"# COMPARISON SUMMARY:",
-	}
+	)
 
 	if summary != nil {
 		lines = append(lines,
@@ -88,6 +345,14 @@ func (g *Generator) writeHeader(writer io.Writer, header ActionFileHeader, summa
 			fmt.Sprintf("#   Dirs  - Total: %d, Identical: %d, Left only: %d, Right only: %d",
 				summary.TotalDirs, summary.IdenticalDirs, summary.OnlyLeftDirs, summary.OnlyRightDirs),
 		)
+		if summary.RenamedFiles > 0 {
+			lines = append(lines, fmt.Sprintf("#   Renamed: %d (same content at another path, written as LEFT_PATH -> RIGHT_PATH; move with [%s] or [%s])",
+				summary.RenamedFiles, ActionMoveRight, ActionMoveLeft))
+		}
+
+		if summary.HashAlgorithm != "" {
+			lines = append(lines, fmt.Sprintf("#   Hash algorithm: %s", summary.HashAlgorithm))
+		}
 
 		if len(summary.ErrorsEncountered) > 0 {
 			lines = append(lines, fmt.Sprintf("#   Errors: %d (see details below)", len(summary.ErrorsEncountered)))
@@ -97,9 +362,18 @@ func (g *Generator) writeHeader(writer io.Writer, header ActionFileHeader, summa
 	lines = append(lines,
 		"#",
 		"# FORMAT: [ACTION] : STATUS : RELATIVE_PATH",
+		"#   Copies may write elsewhere: [>] : STATUS : RELATIVE_PATH -> NEW_PATH",
 		"#",
 	)
 
+	if summary != nil && len(summary.SkippedPaths) > 0 {
+		lines = append(lines, "# SKIPPED (could not be read, comparison incomplete):")
+		for _, skip := range summary.SkippedPaths {
+			lines = append(lines, fmt.Sprintf("#   %s: %s (%s)", skip.Side, skip.Path, skip.Reason))
+		}
+		lines = append(lines, "#")
+	}
+
 	// Add error details if any
 	if summary != nil && len(summary.ErrorsEncountered) > 0 {
 		lines = append(lines, "# ERRORS ENCOUNTERED:")
@@ -118,6 +392,156 @@ func (g *Generator) writeHeader(writer io.Writer, header ActionFileHeader, summa
 	return nil
 }
 
+// describeChanges notes differences that sizes alone don't explain
+func describeChanges(result compare.ComparisonResult) string {
+	if result.Status != compare.StatusModified {
+		return ""
+	}
+
+	var note string
+	switch {
+	case result.Changes.Has(compare.ChangeType):
+		note = "type changed"
+	case result.Changes.Has(compare.ChangeLineEndings):
+		note = "line endings only"
+	case result.Changes.PermsOnly():
+		note = fmt.Sprintf("perms only: L:%s R:%s (sync with [%s] or [%s])", result.LeftInfo.Permissions, result.RightInfo.Permissions,
+			ActionChmodToRight, ActionChmodToLeft)
+	case result.Changes.Has(compare.ChangePerms):
+		note = fmt.Sprintf("perms also differ: L:%s R:%s", result.LeftInfo.Permissions, result.RightInfo.Permissions)
+	}
+
+	if result.Changes.Has(compare.ChangeCase) {
+		caseNote := "case differs"
+		if note == "" {
+			return caseNote
+		}
+		return caseNote + "; " + note
+	}
+	return note
+}
+
+// sides returns the names of the side a mirror overwrites and the side it copies
+func (s MirrorSource) sides() (target, source string) {
+	switch s {
+	case MirrorFromLeft:
+		return "Right", "Left"
+	case MirrorFromRight:
+		return "Left", "Right"
+	default:
+		return "", ""
+	}
+}
+
+// actionLegendLines returns the comment lines describing the available actions
+func actionLegendLines() []string {
+	return []string{
+		"# Available Actions:",
+		fmt.Sprintf("#   %-3s : %s", ActionIgnore.String(), ActionIgnore.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionCopyToRight.String(), ActionCopyToRight.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionCopyToLeft.String(), ActionCopyToLeft.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionDeleteLeft.String(), ActionDeleteLeft.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionDeleteRight.String(), ActionDeleteRight.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionDeleteBoth.String(), ActionDeleteBoth.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionChmodToRight.String(), ActionChmodToRight.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionChmodToLeft.String(), ActionChmodToLeft.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionSymlinkToRight.String(), ActionSymlinkToRight.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionSymlinkToLeft.String(), ActionSymlinkToLeft.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionMoveRight.String(), ActionMoveRight.Description()),
+		fmt.Sprintf("#   %-3s : %s", ActionMoveLeft.String(), ActionMoveLeft.Description()),
+	}
+}
+
+// GenerateMergeActionFile creates an action file from a three-way comparison.
+// Changes made on only one side default to propagating that change to the
+// other side; conflicts default to ignore and are marked with a comment.
+func (g *Generator) GenerateMergeActionFile(
+	writer io.Writer,
+	results []compare.ThreeWayResult,
+	baseDir, leftDir, rightDir string,
+) error {
+	var items []ActionItem
+	conflicts := 0
+
+	for _, result := range results {
+		if result.Class == compare.MergeUnchanged {
+			continue
+		}
+
+		item := ActionItem{
+			Action:       ActionIgnore,
+			Status:       result.LeftRightStatus(),
+			RelativePath: result.RelativePath,
+			LeftInfo:     result.LeftInfo,
+			RightInfo:    result.RightInfo,
+		}
+
+		switch result.Class {
+		case compare.MergeChangedLeft:
+			if result.LeftInfo != nil {
+				item.Action = ActionCopyToRight
+				item.Comment = "changed in left"
+			} else {
+				item.Action = ActionDeleteRight
+				item.Comment = "deleted in left"
+			}
+		case compare.MergeChangedRight:
+			if result.RightInfo != nil {
+				item.Action = ActionCopyToLeft
+				item.Comment = "changed in right"
+			} else {
+				item.Action = ActionDeleteLeft
+				item.Comment = "deleted in right"
+			}
+		case compare.MergeConflict:
+			conflicts++
+			item.Comment = "CONFLICT: changed in both left and right, resolve manually"
+		}
+
+		items = append(items, item)
+	}
+
+	generatedAt := g.generatedAt()
+	lines := g.prefaceLines(ActionFileHeader{GeneratedAt: generatedAt, LeftDir: leftDir, RightDir: rightDir, Version: g.version})
+	lines = append(lines,
+		fmt.Sprintf("# Action File generated on %s", generatedAt),
+		fmt.Sprintf("# Generated by dovetail version %s (three-way merge)", g.version),
+		fmt.Sprintf("# Base:  %s", baseDir),
+		fmt.Sprintf("# Left:  %s", leftDir),
+		fmt.Sprintf("# Right: %s", rightDir),
+		"#",
+		"# INSTRUCTIONS:",
+		"# Changes made on only one side default to copying (or deleting) that side's",
+		"# version onto the other. Conflicts default to [i] (ignore) and are marked",
+		"# with a CONFLICT comment; edit their [ACTION] to choose a side.",
+		"#",
+	)
+	lines = append(lines, actionLegendLines()...)
+	lines = append(lines,
+		"#",
+		"# MERGE SUMMARY:",
+		fmt.Sprintf("#   Changes: %d, Conflicts: %d", len(items), conflicts),
+		"#",
+		"# FORMAT: [ACTION] : STATUS : RELATIVE_PATH",
+		"#   Copies may write elsewhere: [>] : STATUS : RELATIVE_PATH -> NEW_PATH",
+		"#",
+	)
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(writer, "%s\n", line); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	for _, item := range items {
+		if err := g.writeActionItem(writer, item); err != nil {
+			return fmt.Errorf("failed to write action item: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // convertToActionItems converts comparison results to action items
 func (g *Generator) convertToActionItems(results []compare.ComparisonResult, includeIdentical bool) []ActionItem {
 	var items []ActionItem
@@ -135,6 +559,24 @@ func (g *Generator) convertToActionItems(results []compare.ComparisonResult, inc
 			LeftInfo:     result.LeftInfo,
 			RightInfo:    result.RightInfo,
 		}
+		if result.Status == compare.StatusRenamed {
+			item.Destination = result.RightPath()
+		} else if result.Changes.Has(compare.ChangeCase) {
+			item.RightCase = result.RightPath()
+		}
+		if g.mirror != MirrorNone {
+			item.Action = g.mirror.mirrorAction(result.Status)
+			if result.Status == compare.StatusModified && result.Changes.PermsOnly() {
+				item.Action = g.mirror.chmodAction()
+			}
+		} else if g.prefer != PreferNone {
+			item.Action = g.prefer.preferredAction(result)
+		}
+		item.Action = SymlinkCopyAction(item.Action, result)
+		if preset, ok := g.actions[result.RelativePath]; ok {
+			item.Action = preset
+		}
+		item.Comment = describeChanges(result)
 
 		items = append(items, item)
 	}
@@ -144,27 +586,55 @@ func (g *Generator) convertToActionItems(results []compare.ComparisonResult, inc
 
 // writeActionItem writes a single action item to the writer
 func (g *Generator) writeActionItem(writer io.Writer, item ActionItem) error {
-	// Format: [ACTION] : STATUS : RELATIVE_PATH
+	// Format: [ACTION] : STATUS : RELATIVE_PATH [-> DESTINATION]
 	line := fmt.Sprintf("[%s] : %-12s : %s",
 		item.Action.String(),
 		item.Status.String(),
-		item.RelativePath,
+		quoteActionPath(item.RelativePath),
 	)
+	if item.Destination != "" {
+		line += destinationSeparator + quoteActionPath(item.Destination)
+	} else if item.RightCase != "" {
+		line += destinationSeparator + quoteActionPath(item.RightCase)
+	}
 
 	// Add size information for files as a comment
-	if item.LeftInfo != nil && !item.LeftInfo.IsDir && item.RightInfo != nil && !item.RightInfo.IsDir {
+	details := ""
+	if isSymlink(item.LeftInfo) || isSymlink(item.RightInfo) {
+		// Symlinks are compared by target, so show the targets instead of sizes
+		details = "Symlink"
+		if item.LeftInfo != nil {
+			details += fmt.Sprintf(" L:-> %s", describeTarget(item.LeftInfo))
+		}
+		if item.RightInfo != nil {
+			details += fmt.Sprintf(" R:-> %s", describeTarget(item.RightInfo))
+		}
+	} else if item.LeftInfo != nil && !item.LeftInfo.IsDir && item.RightInfo != nil && !item.RightInfo.IsDir {
 		// Both files exist
 		if item.Status == compare.StatusModified {
-			line += fmt.Sprintf("  # L:%s R:%s",
+			details = fmt.Sprintf("L:%s R:%s",
 				util.FormatSize(item.LeftInfo.Size),
 				util.FormatSize(item.RightInfo.Size))
+		} else if item.Status == compare.StatusRenamed {
+			details = fmt.Sprintf("Size: %s", util.FormatSize(item.LeftInfo.Size))
 		}
 	} else if item.LeftInfo != nil && !item.LeftInfo.IsDir {
 		// Only left file exists
-		line += fmt.Sprintf("  # Size: %s", util.FormatSize(item.LeftInfo.Size))
+		details = fmt.Sprintf("Size: %s", util.FormatSize(item.LeftInfo.Size))
 	} else if item.RightInfo != nil && !item.RightInfo.IsDir {
 		// Only right file exists
-		line += fmt.Sprintf("  # Size: %s", util.FormatSize(item.RightInfo.Size))
+		details = fmt.Sprintf("Size: %s", util.FormatSize(item.RightInfo.Size))
+	}
+
+	if item.Comment != "" {
+		if details != "" {
+			details = item.Comment + "; " + details
+		} else {
+			details = item.Comment
+		}
+	}
+	if details != "" {
+		line += "  # " + details
 	}
 
 	if _, err := fmt.Fprintf(writer, "%s\n", line); err != nil {
@@ -173,3 +643,32 @@ func (g *Generator) writeActionItem(writer io.Writer, item ActionItem) error {
 
 	return nil
 }
+
+// SymlinkCopyAction turns a copy whose source is an unfollowed symlink into
+// the action that recreates the link, so its target's content isn't copied
+func SymlinkCopyAction(act ActionType, result compare.ComparisonResult) ActionType {
+	switch {
+	case act == ActionCopyToRight && isSymlink(result.LeftInfo):
+		return ActionSymlinkToRight
+	case act == ActionCopyToLeft && isSymlink(result.RightInfo):
+		return ActionSymlinkToLeft
+	default:
+		return act
+	}
+}
+
+// isSymlink reports whether info describes an unfollowed symlink
+func isSymlink(info *compare.FileInfo) bool {
+	return info != nil && info.IsSymlink
+}
+
+// describeTarget returns the symlink target, or a marker for non-link entries
+func describeTarget(info *compare.FileInfo) string {
+	if info.IsSymlink {
+		return info.LinkTarget
+	}
+	if info.IsDir {
+		return "(directory)"
+	}
+	return "(file)"
+}
@@ -0,0 +1,103 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harikb/dovetail/internal/compare"
+)
+
+// writeTree creates files under root from relative paths to contents
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// generate compares leftDir and rightDir and returns the action file written
+// for the results
+func generate(t *testing.T, leftDir, rightDir string) []byte {
+	t.Helper()
+	engine, err := compare.NewEngine(compare.ComparisonOptions{ParallelWorkers: 8})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	results, summary, err := engine.Compare(leftDir, rightDir)
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+
+	generator := NewGenerator("test")
+	generator.SetReproducible(true)
+	var buf bytes.Buffer
+	if err := generator.GenerateActionFile(&buf, results, leftDir, rightDir, summary, true); err != nil {
+		t.Fatalf("GenerateActionFile: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerateActionFileIsByteStable(t *testing.T) {
+	leftDir, rightDir := t.TempDir(), t.TempDir()
+	left, right := map[string]string{}, map[string]string{}
+	for i := 0; i < 50; i++ {
+		dir := fmt.Sprintf("dir%d", i%7)
+		left[fmt.Sprintf("%s/same%d.txt", dir, i)] = "same"
+		right[fmt.Sprintf("%s/same%d.txt", dir, i)] = "same"
+		left[fmt.Sprintf("%s/changed%d.txt", dir, i)] = "left"
+		right[fmt.Sprintf("%s/changed%d.txt", dir, i)] = "right side"
+		left[fmt.Sprintf("%s/left%d.txt", dir, i)] = "only left"
+		right[fmt.Sprintf("%s/right%d.txt", dir, i)] = "only right"
+	}
+	writeTree(t, leftDir, left)
+	writeTree(t, rightDir, right)
+
+	first := generate(t, leftDir, rightDir)
+	for run := 0; run < 5; run++ {
+		if again := generate(t, leftDir, rightDir); !bytes.Equal(first, again) {
+			t.Fatalf("run %d wrote a different action file:\n--- first\n%s\n--- run %d\n%s", run+2, first, run+2, again)
+		}
+	}
+}
+
+func TestGenerateActionFileIgnoresResultOrder(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	info := func(path string, size int64) *compare.FileInfo {
+		return &compare.FileInfo{Path: path, Size: size, ModTime: modTime, Permissions: "-rw-r--r--"}
+	}
+	results := []compare.ComparisonResult{
+		{RelativePath: "a.txt", Status: compare.StatusModified, LeftInfo: info("a.txt", 1), RightInfo: info("a.txt", 2), Changes: compare.ChangeContent},
+		{RelativePath: "b/c.txt", Status: compare.StatusOnlyLeft, LeftInfo: info("b/c.txt", 3)},
+		{RelativePath: "b/d.txt", Status: compare.StatusOnlyRight, RightInfo: info("b/d.txt", 4)},
+		{RelativePath: "e.txt", Status: compare.StatusIdentical, LeftInfo: info("e.txt", 5), RightInfo: info("e.txt", 5)},
+	}
+	reversed := make([]compare.ComparisonResult, len(results))
+	for i, result := range results {
+		reversed[len(results)-1-i] = result
+	}
+
+	write := func(results []compare.ComparisonResult) []byte {
+		generator := NewGenerator("test")
+		generator.SetReproducible(true)
+		var buf bytes.Buffer
+		summary := &compare.ComparisonSummary{HashAlgorithm: "sha256"}
+		if err := generator.GenerateActionFile(&buf, results, "/left", "/right", summary, true); err != nil {
+			t.Fatalf("GenerateActionFile: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	if first, second := write(results), write(reversed); !bytes.Equal(first, second) {
+		t.Fatalf("result order changed the action file:\n--- sorted\n%s\n--- reversed\n%s", first, second)
+	}
+}
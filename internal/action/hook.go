@@ -0,0 +1,46 @@
+package action
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// PostApplyEnv returns the variables a post-apply hook finds in its
+// environment: the compared directories, the action file (empty when actions
+// were applied without one), what summary counted and the undo journal, if
+// one was written
+func PostApplyEnv(leftDir, rightDir, actionFile string, actions int, summary *ExecutionSummary, journal *Journal) []string {
+	env := []string{
+		"DOVETAIL_LEFT_DIR=" + leftDir,
+		"DOVETAIL_RIGHT_DIR=" + rightDir,
+		"DOVETAIL_ACTION_FILE=" + actionFile,
+		fmt.Sprintf("DOVETAIL_ACTIONS=%d", actions),
+		fmt.Sprintf("DOVETAIL_FILES_CREATED=%d", summary.FilesCreated),
+		fmt.Sprintf("DOVETAIL_FILES_OVERWRITTEN=%d", summary.FilesOverwritten),
+		fmt.Sprintf("DOVETAIL_FILES_DELETED=%d", summary.FilesDeleted),
+		fmt.Sprintf("DOVETAIL_BYTES_COPIED=%d", summary.BytesCopied),
+	}
+	if journal != nil && len(journal.Entries) > 0 {
+		env = append(env, "DOVETAIL_UNDO_JOURNAL="+journal.Path())
+	}
+	return env
+}
+
+// RunPostApplyHook runs hook through the shell with env added to the
+// environment and the given standard streams
+func RunPostApplyHook(hook string, env []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", hook)
+	} else {
+		cmd = exec.Command("sh", "-c", hook)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
@@ -0,0 +1,401 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// JournalOperation describes what an applied action did to a path
+type JournalOperation string
+
+const (
+	JournalCreated     JournalOperation = "created"     // Path did not exist before; undo removes it
+	JournalOverwritten JournalOperation = "overwritten" // Previous content was backed up; undo restores it
+	JournalDeleted     JournalOperation = "deleted"     // Removed content was backed up; undo restores it
+	JournalCreatedDir  JournalOperation = "created_dir" // Parent directory created for a change; undo removes it if empty
+)
+
+// JournalEntry records a single change made to the filesystem
+type JournalEntry struct {
+	Path      string           `json:"path"`             // Absolute path that was changed
+	Operation JournalOperation `json:"operation"`        // What happened to the path
+	Backup    string           `json:"backup,omitempty"` // Backup of the previous content, if any
+	IsDir     bool             `json:"is_dir"`           // Whether the previous content was a directory
+}
+
+// Journal records the changes made by one apply session so they can be undone.
+// On disk it is JSON lines: a header with the session's details, then one line
+// per change, appended and synced before the change is made.
+type Journal struct {
+	Session   string         `json:"session"`
+	CreatedAt time.Time      `json:"created_at"`
+	LeftDir   string         `json:"left_dir"`
+	RightDir  string         `json:"right_dir"`
+	BackupDir string         `json:"backup_dir"`
+	Entries   []JournalEntry `json:"entries,omitempty"` // One line each in the file, after the header
+	UndoneAt  *time.Time     `json:"undone_at,omitempty"`
+
+	path string     // Location of the journal file
+	mu   sync.Mutex // Serializes changes recorded by parallel actions
+}
+
+// journalHeader is the first line of a journal file
+type journalHeader struct {
+	Session   string     `json:"session"`
+	CreatedAt time.Time  `json:"created_at"`
+	LeftDir   string     `json:"left_dir"`
+	RightDir  string     `json:"right_dir"`
+	BackupDir string     `json:"backup_dir"`
+	UndoneAt  *time.Time `json:"undone_at,omitempty"`
+}
+
+// journalLine is each line of a journal file after the header
+type journalLine struct {
+	Entry JournalEntry `json:"entry"`
+}
+
+// UndoResult is the outcome of reversing one journal entry
+type UndoResult struct {
+	Entry   JournalEntry
+	Success bool
+	Error   error
+	Message string
+}
+
+// DefaultJournalDir returns where journals are written when no directory is
+// given: $XDG_STATE_HOME/dovetail, or ~/.local/state/dovetail. Unlike the
+// working directory, it can't be inside a tree being synced.
+func DefaultJournalDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "dovetail"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "dovetail"), nil
+}
+
+// NewJournal creates a journal named dovetail_undo_<session>.jsonl in dir, with
+// backups stored in a sibling dovetail_undo_<session> directory. The session is
+// the current time, with a -N suffix when an earlier session already took it.
+func NewJournal(dir, leftDir, rightDir string) (*Journal, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve journal directory: %w", err)
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	now := time.Now()
+	journal := &Journal{
+		CreatedAt: now,
+		LeftDir:   leftDir,
+		RightDir:  rightDir,
+		Entries:   []JournalEntry{},
+	}
+	var file *os.File
+	for n := 1; ; n++ {
+		session := now.Format("20060102-150405")
+		if n > 1 {
+			session += fmt.Sprintf("-%d", n)
+		}
+		journal.Session = session
+		journal.BackupDir = filepath.Join(absDir, "dovetail_undo_"+session)
+		journal.path = filepath.Join(absDir, "dovetail_undo_"+session+".jsonl")
+
+		if _, err := os.Lstat(journal.BackupDir); err == nil {
+			continue
+		}
+		// O_EXCL claims the session, even against a run started the same second
+		file, err = os.OpenFile(journal.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create journal: %w", err)
+		}
+		break
+	}
+	defer file.Close()
+
+	if err := journal.writeHeader(file); err != nil {
+		os.Remove(journal.path)
+		return nil, fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		os.Remove(journal.path)
+		return nil, fmt.Errorf("failed to write journal: %w", err)
+	}
+	return journal, nil
+}
+
+// LoadJournal reads a journal file written by an apply session. Journals
+// written as a single JSON document by earlier versions are read too.
+func LoadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var journal Journal
+	if err := json.Unmarshal(data, &journal); err == nil {
+		journal.path = path
+		return &journal, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if err := json.Unmarshal([]byte(lines[0]), &journal); err != nil {
+		return nil, fmt.Errorf("failed to parse journal %s: %w", path, err)
+	}
+	for i, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var entry journalLine
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			// A run killed while appending leaves a partial last line; the
+			// change it describes was not started
+			if i == len(lines)-2 {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse journal %s line %d: %w", path, i+2, err)
+		}
+		journal.Entries = append(journal.Entries, entry.Entry)
+	}
+	journal.path = path
+	return &journal, nil
+}
+
+// Path returns the location of the journal file
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Save rewrites the whole journal. It is replaced atomically, so an
+// interruption leaves either the old or the new journal.
+func (j *Journal) Save() error {
+	err := util.WriteFileAtomic(j.path, 0644, func(w io.Writer) error {
+		if err := j.writeHeader(w); err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(w)
+		for _, entry := range j.Entries {
+			if err := encoder.Encode(journalLine{Entry: entry}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// writeHeader writes the journal's header line to w
+func (j *Journal) writeHeader(w io.Writer) error {
+	return json.NewEncoder(w).Encode(journalHeader{
+		Session:   j.Session,
+		CreatedAt: j.CreatedAt,
+		LeftDir:   j.LeftDir,
+		RightDir:  j.RightDir,
+		BackupDir: j.BackupDir,
+		UndoneAt:  j.UndoneAt,
+	})
+}
+
+// recordChange backs up path if it exists and appends an entry describing the
+// change about to be made. It must be called before path is modified.
+func (j *Journal) recordChange(path string, deleting bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		if deleting {
+			return nil // Nothing to delete, nothing to undo
+		}
+		return j.append(JournalEntry{Path: path, Operation: JournalCreated})
+	}
+	if err != nil {
+		return fmt.Errorf("cannot inspect %s for backup: %w", path, err)
+	}
+
+	backup := filepath.Join(j.BackupDir, fmt.Sprintf("%04d", len(j.Entries)), filepath.Base(path))
+	if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := copyPreserving(path, backup); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	operation := JournalOverwritten
+	if deleting {
+		operation = JournalDeleted
+	}
+	return j.append(JournalEntry{Path: path, Operation: operation, Backup: backup, IsDir: info.IsDir()})
+}
+
+// recordDirs appends an entry for each directory that doesn't exist yet on
+// the way to dir, outermost first. It must be called before they are created.
+func (j *Journal) recordDirs(dir string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var missing []string
+	for ; ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("cannot inspect %s: %w", dir, err)
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := j.append(JournalEntry{Path: missing[i], Operation: JournalCreatedDir}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// append adds an entry, appending it to the journal file and syncing it
+// before the change is made, so an interrupted apply can still be undone
+func (j *Journal) append(entry JournalEntry) error {
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	defer file.Close()
+	if err := json.NewEncoder(file).Encode(journalLine{Entry: entry}); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	j.Entries = append(j.Entries, entry)
+	return nil
+}
+
+// Undo reverses the journal's entries, newest first. Directories created
+// along the way are removed last, once what was put in them is gone.
+func (j *Journal) Undo() ([]UndoResult, error) {
+	if j.UndoneAt != nil {
+		return nil, fmt.Errorf("journal was already undone at %s", j.UndoneAt.Format("2006-01-02 15:04:05"))
+	}
+
+	var entries, dirs []JournalEntry
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		if j.Entries[i].Operation == JournalCreatedDir {
+			dirs = append(dirs, j.Entries[i])
+		} else {
+			entries = append(entries, j.Entries[i])
+		}
+	}
+
+	results := make([]UndoResult, 0, len(j.Entries))
+	failed := false
+	for _, entry := range append(entries, dirs...) {
+		result := undoEntry(entry)
+		if !result.Success {
+			failed = true
+		}
+		results = append(results, result)
+	}
+
+	if !failed {
+		now := time.Now()
+		j.UndoneAt = &now
+		if err := j.Save(); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// undoEntry reverses a single journal entry
+func undoEntry(entry JournalEntry) UndoResult {
+	result := UndoResult{Entry: entry}
+
+	if entry.Operation == JournalCreatedDir {
+		// Anything still inside wasn't put there by apply
+		if children, err := os.ReadDir(entry.Path); err == nil && len(children) > 0 {
+			result.Success = true
+			result.Message = fmt.Sprintf("Kept %s: not empty", entry.Path)
+			return result
+		}
+		if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+			result.Error = err
+			result.Message = fmt.Sprintf("Failed to remove %s", entry.Path)
+			return result
+		}
+		result.Success = true
+		result.Message = fmt.Sprintf("Removed %s", entry.Path)
+		return result
+	}
+
+	if err := os.RemoveAll(entry.Path); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to remove %s", entry.Path)
+		return result
+	}
+
+	if entry.Operation == JournalCreated {
+		result.Success = true
+		result.Message = fmt.Sprintf("Removed %s", entry.Path)
+		return result
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entry.Path), 0755); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to recreate parent directory of %s", entry.Path)
+		return result
+	}
+	if err := copyPreserving(entry.Backup, entry.Path); err != nil {
+		result.Error = err
+		result.Message = fmt.Sprintf("Failed to restore %s", entry.Path)
+		return result
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Restored %s", entry.Path)
+	return result
+}
+
+// copyPreserving copies a file, directory or symlink keeping modification
+// times and, where possible, ownership
+func copyPreserving(srcPath, dstPath string) error {
+	info, err := os.Lstat(srcPath)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(srcPath)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dstPath)
+	}
+
+	copier := &Executor{preserveMetadata: true}
+	if info.IsDir() {
+		_, err = copier.copyDirectory(srcPath, dstPath)
+	} else {
+		_, err = copier.copyFile(srcPath, dstPath)
+	}
+	return err
+}
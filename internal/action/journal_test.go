@@ -0,0 +1,69 @@
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// listTree returns the relative paths of everything under root
+func listTree(t *testing.T, root string) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		paths = append(paths, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestUndoRemovesCreatedDirectories(t *testing.T) {
+	leftDir, rightDir := t.TempDir(), t.TempDir()
+	writeTree(t, leftDir, map[string]string{"sub/new.txt": "moved", "a/b/c.txt": "copied"})
+	writeTree(t, rightDir, map[string]string{"new.txt": "moved", "keep/x.txt": "kept"})
+
+	actions := "[~>] : RENAMED : sub/new.txt -> new.txt\n[>] : ONLY_IN_LEFT : a/b/c.txt\n"
+	actionFile, err := NewParser().ParseActionFile(strings.NewReader(actions))
+	if err != nil {
+		t.Fatalf("ParseActionFile: %v", err)
+	}
+
+	journal, err := NewJournal(t.TempDir(), leftDir, rightDir)
+	if err != nil {
+		t.Fatalf("NewJournal: %v", err)
+	}
+	executor := NewExecutor(false)
+	executor.SetJournal(journal)
+	summary, _, err := executor.ExecuteActions(actionFile, leftDir, rightDir)
+	if err != nil || summary.FailedActions > 0 {
+		t.Fatalf("ExecuteActions: %v, %d failed", err, summary.FailedActions)
+	}
+
+	// A file added by hand keeps its directory from being removed
+	writeTree(t, rightDir, map[string]string{"a/notes.txt": "mine"})
+
+	results, err := journal.Undo()
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	for _, result := range results {
+		if !result.Success {
+			t.Errorf("undo failed: %s: %v", result.Message, result.Error)
+		}
+	}
+
+	want := []string{"a", "a/notes.txt", "keep", "keep/x.txt", "new.txt"}
+	if got := listTree(t, rightDir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("right after undo = %v, want %v", got, want)
+	}
+}
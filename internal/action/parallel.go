@@ -0,0 +1,121 @@
+package action
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// executeParallel runs the non-ignored actions on a pool of workers. An action
+// waits for every earlier action whose path is the same as, inside, or a parent
+// of its own, so nested copies and deletes keep their action file order.
+// Results are returned, and counted, in action file order.
+func (e *Executor) executeParallel(actionFile *ActionFile, leftDir, rightDir string, summary *ExecutionSummary, progress *util.ProgressReporter) (*ExecutionSummary, []ExecutionResult, error) {
+	var actions []ActionItem
+	for _, action := range actionFile.Actions {
+		if action.Action != ActionIgnore {
+			actions = append(actions, action)
+		}
+	}
+
+	deps := actionDependencies(actions)
+	done := make([]chan struct{}, len(actions))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	results := make([]ExecutionResult, len(actions))
+	existed := make([]bool, len(actions))
+
+	indexes := make(chan int, e.parallelActions)
+	var wg sync.WaitGroup
+	for w := 0; w < e.parallelActions; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				for _, dep := range deps[i] {
+					<-done[dep]
+				}
+				action := actions[i]
+				existed[i] = e.fileExists(action, leftDir, rightDir, action.Action)
+				results[i] = e.runAction(action, leftDir, rightDir)
+				close(done[i])
+				progress.Report("%s: %s", action.RelativePath, results[i].Message)
+			}
+		}()
+	}
+
+	// Actions are handed out in order, so the earliest unfinished action is
+	// always held by a worker whose dependencies are done
+	for i := range actions {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, result := range results {
+		summary.add(result, existed[i])
+	}
+	return summary, results, nil
+}
+
+// actionDependencies lists, for each action, the earlier actions touching an
+// overlapping path
+func actionDependencies(actions []ActionItem) [][]int {
+	exact := make(map[string][]int)  // Actions on exactly this path
+	within := make(map[string][]int) // Actions on paths below this directory
+
+	deps := make([][]int, len(actions))
+	for i, action := range actions {
+		seen := make(map[int]bool)
+		addDeps := func(indexes []int) {
+			for _, index := range indexes {
+				if !seen[index] {
+					seen[index] = true
+					deps[i] = append(deps[i], index)
+				}
+			}
+		}
+
+		paths := actionPaths(action)
+		for _, path := range paths {
+			addDeps(exact[path])
+			addDeps(within[path])
+			for _, parent := range parentPaths(path) {
+				addDeps(exact[parent])
+			}
+		}
+		for _, path := range paths {
+			exact[path] = append(exact[path], i)
+			for _, parent := range parentPaths(path) {
+				within[parent] = append(within[parent], i)
+			}
+		}
+	}
+	return deps
+}
+
+// actionPaths returns the cleaned relative paths an action reads or writes
+func actionPaths(action ActionItem) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, path := range []string{action.RelativePath, action.RightPath(), action.DestinationPath()} {
+		if path = filepath.ToSlash(filepath.Clean(path)); !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// parentPaths returns the parent directories of a slash-separated relative path
+func parentPaths(path string) []string {
+	var parents []string
+	for i := strings.LastIndex(path, "/"); i > 0; i = strings.LastIndex(path, "/") {
+		path = path[:i]
+		parents = append(parents, path)
+	}
+	return parents
+}
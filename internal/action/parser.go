@@ -4,12 +4,71 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/harikb/dovetail/internal/compare"
 )
 
+// destinationSeparator separates a source path from its destination override
+const destinationSeparator = " -> "
+
+// quoteActionPath returns path as written in an action line. Paths the
+// parser would misread, such as one containing destinationSeparator, are
+// written as Go-quoted strings.
+func quoteActionPath(path string) string {
+	if strings.Contains(path, destinationSeparator) || strings.HasPrefix(path, `"`) ||
+		strings.TrimSpace(path) != path {
+		return strconv.Quote(path)
+	}
+	return path
+}
+
+// splitActionPaths splits the path field of an action line into the path and
+// its optional destination. Either may be quoted as written by quoteActionPath.
+func splitActionPaths(field string) (path, dest string, err error) {
+	path, rest, err := readActionPath(field)
+	if err != nil || rest == "" {
+		return path, "", err
+	}
+	arrow := strings.TrimSpace(destinationSeparator)
+	if !strings.HasPrefix(rest, arrow) {
+		return "", "", fmt.Errorf("unexpected text after path %q (expected PATH -> NEWPATH)", path)
+	}
+	dest, rest, err = readActionPath(rest[len(arrow):])
+	if err != nil {
+		return "", "", err
+	}
+	if rest != "" {
+		return "", "", fmt.Errorf("more than one %q in path (quote paths containing it, e.g. \"a -> b\")", arrow)
+	}
+	if path == "" || dest == "" {
+		return "", "", fmt.Errorf("invalid destination override (expected PATH -> NEWPATH)")
+	}
+	return path, dest, nil
+}
+
+// readActionPath reads one possibly quoted path from the start of s and
+// returns it with the text after it
+func readActionPath(s string) (path, rest string, err error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid quoted path %s", s)
+		}
+		path, _ = strconv.Unquote(quoted)
+		return path, strings.TrimSpace(s[len(quoted):]), nil
+	}
+	if before, after, found := strings.Cut(s, destinationSeparator); found {
+		return strings.TrimSpace(before), strings.TrimSpace(destinationSeparator) + after, nil
+	}
+	return s, "", nil
+}
+
 // Parser parses action files
 type Parser struct {
 	actionLineRegex *regexp.Regexp
@@ -17,7 +76,7 @@ type Parser struct {
 
 // NewParser creates a new action file parser
 func NewParser() *Parser {
-	// Regex to match action lines: [ACTION] : STATUS : PATH
+	// Regex to match action lines: [ACTION] : STATUS : PATH [-> DESTINATION]
 	actionLineRegex := regexp.MustCompile(`^\[([^\]]+)\]\s*:\s*([^:]+)\s*:\s*(.+)$`)
 
 	return &Parser{
@@ -27,6 +86,21 @@ func NewParser() *Parser {
 
 // ParseActionFile parses an action file from a reader
 func (p *Parser) ParseActionFile(reader io.Reader) (*ActionFile, error) {
+	actionFile, lineErrors, err := p.ParseActionFileAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(lineErrors) > 0 {
+		return nil, lineErrors[0]
+	}
+	return actionFile, nil
+}
+
+// ParseActionFileAll parses an action file like ParseActionFile, but keeps
+// going past malformed lines and returns all of their errors. The returned
+// error is only set if the reader itself fails.
+func (p *Parser) ParseActionFileAll(reader io.Reader) (*ActionFile, []ActionFileError, error) {
+	var lineErrors []ActionFileError
 	actionFile := &ActionFile{
 		Actions:  make([]ActionItem, 0),
 		Comments: make([]string, 0),
@@ -54,12 +128,13 @@ func (p *Parser) ParseActionFile(reader io.Reader) (*ActionFile, error) {
 		// Parse action line
 		actionItem, err := p.parseActionLine(line, lineNumber)
 		if err != nil {
-			return nil, ActionFileError{
+			lineErrors = append(lineErrors, ActionFileError{
 				Type:    "parse",
 				Line:    lineNumber,
 				Message: err.Error(),
 				Err:     err,
-			}
+			})
+			continue
 		}
 
 		if actionItem != nil {
@@ -68,10 +143,10 @@ func (p *Parser) ParseActionFile(reader io.Reader) (*ActionFile, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading action file: %w", err)
+		return nil, nil, fmt.Errorf("error reading action file: %w", err)
 	}
 
-	return actionFile, nil
+	return actionFile, lineErrors, nil
 }
 
 // parseHeaderLine extracts information from header comment lines
@@ -105,6 +180,12 @@ func (p *Parser) parseActionLine(line string, lineNumber int) (*ActionItem, erro
 	statusStr := strings.TrimSpace(matches[2])
 	pathStr := strings.TrimSpace(matches[3])
 
+	// Copies may name a different destination: "PATH -> NEWPATH"
+	pathStr, destStr, err := splitActionPaths(pathStr)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, line)
+	}
+
 	// Parse action type
 	action, valid := ParseActionType(actionStr)
 	if !valid {
@@ -125,10 +206,34 @@ func (p *Parser) parseActionLine(line string, lineNumber int) (*ActionItem, erro
 		}
 	}
 
+	// Renamed entries always name both paths: LEFT_PATH -> RIGHT_PATH.
+	// So do entries whose paths differ only in case, for any action.
+	rightCase := ""
+	if status != compare.StatusRenamed && destStr != pathStr && strings.EqualFold(destStr, pathStr) {
+		rightCase, destStr = destStr, ""
+	}
+	if status == compare.StatusRenamed {
+		if destStr == "" {
+			return nil, ValidationError{
+				LineNumber: lineNumber,
+				Message:    "a RENAMED entry needs both paths (LEFT_PATH -> RIGHT_PATH)",
+				Action:     actionStr,
+			}
+		}
+	} else if destStr != "" && action != ActionCopyToRight && action != ActionCopyToLeft {
+		return nil, ValidationError{
+			LineNumber: lineNumber,
+			Message:    "a destination override (->) is only allowed on copy actions",
+			Action:     actionStr,
+		}
+	}
+
 	actionItem := &ActionItem{
 		Action:       action,
 		Status:       status,
 		RelativePath: pathStr,
+		Destination:  destStr,
+		RightCase:    rightCase,
 		LineNumber:   lineNumber,
 	}
 
@@ -146,6 +251,8 @@ func (p *Parser) parseStatus(statusStr string) (compare.FileStatus, error) {
 		return compare.StatusOnlyLeft, nil
 	case "ONLY_IN_RIGHT":
 		return compare.StatusOnlyRight, nil
+	case "RENAMED":
+		return compare.StatusRenamed, nil
 	default:
 		return compare.StatusIdentical, fmt.Errorf("unknown status: %s", statusStr)
 	}
@@ -167,11 +274,20 @@ func (p *Parser) ValidateActionFile(actionFile *ActionFile, leftDir, rightDir st
 func (p *Parser) validateAction(action ActionItem, leftDir, rightDir string) []ValidationError {
 	var errors []ValidationError
 
+	// Paths escaping the directories could copy over or delete unrelated files
+	if !IsContainedPath(action.RelativePath) {
+		errors = append(errors, ValidationError{
+			LineNumber: action.LineNumber,
+			Message:    fmt.Sprintf("path %q must be a path inside the compared directories", action.RelativePath),
+			Action:     action.Action.String(),
+		})
+	}
+
 	// Check if action makes sense for the status
 	switch action.Status {
 	case compare.StatusOnlyLeft:
 		// File exists only in left
-		if action.Action == ActionCopyToLeft || action.Action == ActionDeleteRight {
+		if action.Action == ActionCopyToLeft || action.Action == ActionDeleteRight || action.Action == ActionSymlinkToLeft {
 			errors = append(errors, ValidationError{
 				LineNumber: action.LineNumber,
 				Message:    "cannot copy to left or delete from right when file only exists in left",
@@ -181,7 +297,7 @@ func (p *Parser) validateAction(action ActionItem, leftDir, rightDir string) []V
 
 	case compare.StatusOnlyRight:
 		// File exists only in right
-		if action.Action == ActionCopyToRight || action.Action == ActionDeleteLeft {
+		if action.Action == ActionCopyToRight || action.Action == ActionDeleteLeft || action.Action == ActionSymlinkToRight {
 			errors = append(errors, ValidationError{
 				LineNumber: action.LineNumber,
 				Message:    "cannot copy to right or delete from left when file only exists in right",
@@ -189,6 +305,34 @@ func (p *Parser) validateAction(action ActionItem, leftDir, rightDir string) []V
 			})
 		}
 
+	}
+
+	// Renamed entries name two paths, which the copy and delete actions don't handle
+	if action.Status == compare.StatusRenamed && action.Action != ActionIgnore && !isMoveAction(action.Action) {
+		errors = append(errors, ValidationError{
+			LineNumber: action.LineNumber,
+			Message:    "renamed entries can only be ignored or moved",
+			Action:     action.Action.String(),
+		})
+	}
+	if isMoveAction(action.Action) && action.Status != compare.StatusRenamed {
+		errors = append(errors, ValidationError{
+			LineNumber: action.LineNumber,
+			Message:    "only RENAMED entries can be moved",
+			Action:     action.Action.String(),
+		})
+	}
+
+	// Permissions can only be copied between two existing entries
+	if isChmodAction(action.Action) && (action.Status == compare.StatusOnlyLeft || action.Status == compare.StatusOnlyRight) {
+		errors = append(errors, ValidationError{
+			LineNumber: action.LineNumber,
+			Message:    "cannot copy permissions when the file only exists on one side",
+			Action:     action.Action.String(),
+		})
+	}
+
+	switch action.Status {
 	case compare.StatusIdentical:
 		// Files are identical
 		if action.Action == ActionCopyToRight || action.Action == ActionCopyToLeft {
@@ -197,6 +341,21 @@ func (p *Parser) validateAction(action ActionItem, leftDir, rightDir string) []V
 		}
 	}
 
+	if isMoveAction(action.Action) && action.Status == compare.StatusRenamed {
+		errors = append(errors, p.validateMove(action, leftDir, rightDir)...)
+	} else if action.Destination != "" && action.Status != compare.StatusRenamed {
+		errors = append(errors, p.validateDestination(action, leftDir, rightDir)...)
+	} else if action.Destination != "" && !IsContainedPath(action.Destination) {
+		errors = append(errors, ValidationError{
+			LineNumber: action.LineNumber,
+			Message:    fmt.Sprintf("path %q must be a path inside the compared directories", action.Destination),
+			Action:     action.Action.String(),
+		})
+	}
+	if isSymlinkAction(action.Action) && IsContainedPath(action.RelativePath) {
+		errors = append(errors, p.validateSymlink(action, leftDir, rightDir)...)
+	}
+
 	// Additional validations could be added here:
 	// - Check if files still exist
 	// - Check permissions
@@ -205,3 +364,173 @@ func (p *Parser) validateAction(action ActionItem, leftDir, rightDir string) []V
 
 	return errors
 }
+
+// IsContainedPath reports whether a relative path from an action file names an
+// entry strictly inside the directory it is joined to: not absolute, not the
+// directory itself and not escaping it with ".." after cleaning
+func IsContainedPath(relPath string) bool {
+	cleaned := filepath.Clean(filepath.FromSlash(relPath))
+	return !filepath.IsAbs(cleaned) && cleaned != "." && cleaned != ".." &&
+		!strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+}
+
+// IsContainedLinkTarget reports whether a symlink at relPath pointing to
+// target resolves inside the directory relPath is joined to. Absolute
+// targets never do, as they would point elsewhere on another machine.
+func IsContainedLinkTarget(relPath, target string) bool {
+	if target == "" || filepath.IsAbs(target) {
+		return false
+	}
+	linkDir := filepath.Dir(filepath.Clean(filepath.FromSlash(relPath)))
+	resolved := filepath.Join(linkDir, filepath.FromSlash(target))
+	return resolved == "." || IsContainedPath(resolved)
+}
+
+// validateSymlink checks that the source of a symlink action is a symlink
+// whose target is relative and stays inside the compared directories
+func (p *Parser) validateSymlink(action ActionItem, leftDir, rightDir string) []ValidationError {
+	invalid := func(format string, args ...interface{}) []ValidationError {
+		return []ValidationError{{
+			LineNumber: action.LineNumber,
+			Message:    fmt.Sprintf(format, args...),
+			Action:     action.Action.String(),
+		}}
+	}
+
+	srcName, srcDir := "left", leftDir
+	if action.Action == ActionSymlinkToLeft {
+		srcName, srcDir = "right", rightDir
+	}
+	target, err := os.Readlink(filepath.Join(srcDir, sidePath(action, srcName)))
+	if err != nil {
+		return invalid("%s is not a symlink in %s", action.RelativePath, srcName)
+	}
+	if !IsContainedLinkTarget(action.RelativePath, target) {
+		return invalid("symlink target %q must be relative and stay inside the compared directories", target)
+	}
+	return nil
+}
+
+// validateDestination checks that a copy's destination override stays inside
+// the target directory and that its parent directory can be created
+func (p *Parser) validateDestination(action ActionItem, leftDir, rightDir string) []ValidationError {
+	invalid := func(format string, args ...interface{}) []ValidationError {
+		return []ValidationError{{
+			LineNumber: action.LineNumber,
+			Message:    fmt.Sprintf(format, args...),
+			Action:     action.Action.String(),
+		}}
+	}
+
+	if !IsContainedPath(action.Destination) {
+		return invalid("destination %q must be a path inside the target directory", action.Destination)
+	}
+	dest := filepath.Clean(filepath.FromSlash(action.Destination))
+
+	targetDir := rightDir
+	if action.Action == ActionCopyToLeft {
+		targetDir = leftDir
+	}
+
+	if err := checkParentCreatable(filepath.Join(targetDir, dest)); err != nil {
+		return invalid("cannot create parent directory for %q: %v", action.Destination, err)
+	}
+	return nil
+}
+
+// validateMove checks that a renamed entry's source exists on the side being
+// moved and that its new path is free and can be created
+func (p *Parser) validateMove(action ActionItem, leftDir, rightDir string) []ValidationError {
+	invalid := func(format string, args ...interface{}) []ValidationError {
+		return []ValidationError{{
+			LineNumber: action.LineNumber,
+			Message:    fmt.Sprintf(format, args...),
+			Action:     action.Action.String(),
+		}}
+	}
+
+	if !IsContainedPath(action.Destination) {
+		return invalid("path %q must be a path inside the compared directories", action.Destination)
+	}
+	side, dir, from, to := "right", rightDir, action.Destination, action.RelativePath
+	if action.Action == ActionMoveLeft {
+		side, dir, from, to = "left", leftDir, action.RelativePath, action.Destination
+	}
+
+	if !pathExists(filepath.Join(dir, from)) {
+		return invalid("move source %s does not exist in %s", from, side)
+	}
+	if pathExists(filepath.Join(dir, to)) {
+		return invalid("move destination %s already exists in %s", to, side)
+	}
+	if err := checkParentCreatable(filepath.Join(dir, to)); err != nil {
+		return invalid("cannot create parent directory for %q: %v", to, err)
+	}
+	return nil
+}
+
+// checkParentCreatable walks up from path to the nearest existing ancestor,
+// which must be a directory for MkdirAll to create the rest
+func checkParentCreatable(path string) error {
+	parent := filepath.Dir(path)
+	for {
+		info, err := os.Stat(parent)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", parent)
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		next := filepath.Dir(parent)
+		if next == parent {
+			return nil
+		}
+		parent = next
+	}
+}
+
+// CheckPaths compares non-ignored actions against the current contents of
+// the directories. Errors are actions that cannot run as written; warnings
+// are actions that will run but are likely mistakes.
+func (p *Parser) CheckPaths(actionFile *ActionFile, leftDir, rightDir string) (errors, warnings []ValidationError) {
+	for _, action := range actionFile.Actions {
+		if action.Action == ActionIgnore {
+			continue
+		}
+
+		issue := func(format string, args ...interface{}) ValidationError {
+			return ValidationError{
+				LineNumber: action.LineNumber,
+				Message:    fmt.Sprintf(format, args...),
+				Action:     action.Action.String(),
+			}
+		}
+
+		inLeft := pathExists(filepath.Join(leftDir, action.RelativePath))
+		inRight := pathExists(filepath.Join(rightDir, action.RightPath()))
+
+		switch {
+		case !inLeft && !inRight:
+			errors = append(errors, issue("%s no longer exists in either directory", action.RelativePath))
+		case action.Action == ActionCopyToRight && !inLeft:
+			errors = append(errors, issue("copy source %s does not exist in left", action.RelativePath))
+		case action.Action == ActionCopyToLeft && !inRight:
+			errors = append(errors, issue("copy source %s does not exist in right", action.RelativePath))
+		case isChmodAction(action.Action) && (!inLeft || !inRight):
+			errors = append(errors, issue("%s must exist on both sides to copy its permissions", action.RelativePath))
+		case action.Action == ActionDeleteBoth && inLeft && inRight:
+			warnings = append(warnings, issue("%s exists on both sides and will be deleted from both", action.RelativePath))
+		}
+	}
+
+	return errors, warnings
+}
+
+// pathExists reports whether path exists, without following a final symlink
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
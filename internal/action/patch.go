@@ -0,0 +1,90 @@
+package action
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/harikb/dovetail/internal/diff"
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// patchFilePattern matches saved single-file patches, named after the file
+// they apply to and the time they were written, in the same form as action
+// files saved from the TUI: main.go.20240115_143000.patch. The groups capture
+// the base file name and the session ID.
+var patchFilePattern = regexp.MustCompile(`^(.+)\.(\d{8}_\d{6})\.patch$`)
+
+// PatchBaseFile infers the file a saved patch applies to from its name: the
+// patch's directory joined with the name before the timestamp
+func PatchBaseFile(patchPath string) (string, bool) {
+	matches := patchFilePattern.FindStringSubmatch(filepath.Base(patchPath))
+	if matches == nil {
+		return "", false
+	}
+	return filepath.Join(filepath.Dir(patchPath), matches[1]), true
+}
+
+// PatchResult describes a patch applied (or checked) against a file
+type PatchResult struct {
+	Hunks        int // Hunks applied
+	LinesAdded   int
+	LinesRemoved int
+}
+
+// ApplyPatchToFile applies a single-file unified diff to basePath. The patch
+// is checked against the whole file before anything is written, and the file
+// is replaced atomically, keeping its permissions. With dryRun nothing is
+// written.
+func ApplyPatchToFile(basePath string, patch []byte, dryRun bool) (*PatchResult, error) {
+	if files := diff.CountFiles(string(patch)); files > 1 {
+		return nil, fmt.Errorf("patch changes %d files; only single-file patches can be applied", files)
+	}
+	hunks := diff.ParseHunks(string(patch))
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch contains no hunks")
+	}
+
+	info, err := os.Stat(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("base file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("base file %s is not a regular file", basePath)
+	}
+	original, err := os.ReadFile(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base file: %w", err)
+	}
+
+	patched, err := diff.ApplyHunks(string(original), hunks)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PatchResult{Hunks: len(hunks)}
+	for _, hunk := range hunks {
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case diff.LineAdded:
+				result.LinesAdded++
+			case diff.LineRemoved:
+				result.LinesRemoved++
+			}
+		}
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	err = util.WriteFileAtomic(basePath, info.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.WriteString(w, patched)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write patched file: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,184 @@
+package action
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReportFormat selects how an apply report is written
+type ReportFormat string
+
+const (
+	ReportJSON ReportFormat = "json"
+	ReportCSV  ReportFormat = "csv"
+)
+
+// ReportFormatForPath infers the report format from a file name: .csv files
+// get CSV, everything else JSON
+func ReportFormatForPath(path string) ReportFormat {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return ReportCSV
+	}
+	return ReportJSON
+}
+
+// Report outcomes, so failures stand out without inspecting the error column
+const (
+	ReportStatusOK      = "ok"
+	ReportStatusSkipped = "skipped"
+	ReportStatusFailed  = "failed"
+)
+
+// ReportEntry is the machine-readable form of one ExecutionResult
+type ReportEntry struct {
+	Line        int      `json:"line"`                  // Line number in the action file
+	Action      string   `json:"action"`                // Action symbol, e.g. ">"
+	Path        string   `json:"path"`                  // Relative path the action applied to
+	Destination string   `json:"destination,omitempty"` // Relative destination of a copy, if different
+	Status      string   `json:"status"`                // ok, skipped or failed
+	Success     bool     `json:"success"`
+	BytesCopied int64    `json:"bytes_copied"`
+	Error       string   `json:"error,omitempty"`
+	Message     string   `json:"message"`
+	Warnings    []string `json:"warnings,omitempty"` // Non-fatal problems, e.g. metadata not preserved
+}
+
+// ReportTotals mirrors ExecutionSummary
+type ReportTotals struct {
+	TotalActions      int      `json:"total_actions"`
+	SuccessfulActions int      `json:"successful_actions"`
+	FailedActions     int      `json:"failed_actions"`
+	BytesCopied       int64    `json:"bytes_copied"`
+	FilesCreated      int      `json:"files_created"`
+	FilesOverwritten  int      `json:"files_overwritten"`
+	FilesDeleted      int      `json:"files_deleted"`
+	FilesSkipped      int      `json:"files_skipped"`
+	FilesChmodded     int      `json:"files_chmodded"`
+	Errors            []string `json:"errors"`
+	Warnings          []string `json:"warnings"`
+}
+
+// Report is the machine-readable outcome of an apply run
+type Report struct {
+	ActionFile string        `json:"action_file"`
+	LeftDir    string        `json:"left_dir"`
+	RightDir   string        `json:"right_dir"`
+	Results    []ReportEntry `json:"results"`
+	Totals     ReportTotals  `json:"totals"`
+}
+
+// NewReport builds a report from the results of ExecuteActions
+func NewReport(actionFile, leftDir, rightDir string, summary *ExecutionSummary, results []ExecutionResult) *Report {
+	report := &Report{
+		ActionFile: actionFile,
+		LeftDir:    leftDir,
+		RightDir:   rightDir,
+		Results:    []ReportEntry{},
+	}
+
+	for _, result := range results {
+		entry := ReportEntry{
+			Line:        result.Action.LineNumber,
+			Action:      result.Action.Action.String(),
+			Path:        result.Action.RelativePath,
+			Destination: result.Action.Destination,
+			Success:     result.Success,
+			BytesCopied: result.BytesCopied,
+			Message:     result.Message,
+			Warnings:    result.Warnings,
+		}
+		switch {
+		case !result.Success:
+			entry.Status = ReportStatusFailed
+		case result.Skipped:
+			entry.Status = ReportStatusSkipped
+		default:
+			entry.Status = ReportStatusOK
+		}
+		if result.Error != nil {
+			entry.Error = result.Error.Error()
+		}
+		report.Results = append(report.Results, entry)
+	}
+
+	if summary != nil {
+		report.Totals = ReportTotals{
+			TotalActions:      summary.TotalActions,
+			SuccessfulActions: summary.SuccessfulActions,
+			FailedActions:     summary.FailedActions,
+			BytesCopied:       summary.BytesCopied,
+			FilesCreated:      summary.FilesCreated,
+			FilesOverwritten:  summary.FilesOverwritten,
+			FilesDeleted:      summary.FilesDeleted,
+			FilesSkipped:      summary.FilesSkipped,
+			FilesChmodded:     summary.FilesChmodded,
+			Errors:            summary.Errors,
+			Warnings:          summary.Warnings,
+		}
+	}
+	if report.Totals.Errors == nil {
+		report.Totals.Errors = []string{}
+	}
+	if report.Totals.Warnings == nil {
+		report.Totals.Warnings = []string{}
+	}
+
+	return report
+}
+
+// Write writes the report in the given format
+func (r *Report) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportCSV:
+		return r.writeCSV(w)
+	case ReportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false) // Keep action symbols like ">" readable
+		return encoder.Encode(r)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+// reportCSVHeader is the fixed column order of CSV reports
+var reportCSVHeader = []string{"line", "action", "path", "destination", "status", "bytes_copied", "error", "message"}
+
+// writeCSV writes one row per result in action file order, followed by the
+// totals as a # comment line (csv.Reader skips it when Comment is '#')
+func (r *Report) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(reportCSVHeader); err != nil {
+		return err
+	}
+	for _, entry := range r.Results {
+		row := []string{
+			strconv.Itoa(entry.Line),
+			entry.Action,
+			entry.Path,
+			entry.Destination,
+			entry.Status,
+			strconv.FormatInt(entry.BytesCopied, 10),
+			entry.Error,
+			entry.Message,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	totals := r.Totals
+	_, err := fmt.Fprintf(w, "# total=%d successful=%d failed=%d created=%d overwritten=%d deleted=%d skipped=%d chmodded=%d bytes_copied=%d\n",
+		totals.TotalActions, totals.SuccessfulActions, totals.FailedActions, totals.FilesCreated,
+		totals.FilesOverwritten, totals.FilesDeleted, totals.FilesSkipped, totals.FilesChmodded, totals.BytesCopied)
+	return err
+}
@@ -0,0 +1,68 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// retryBackoff is the wait before the first retry; it doubles on each one
+const retryBackoff = 100 * time.Millisecond
+
+// transientErrnos are errors that network filesystems return when a retry
+// may well succeed
+var transientErrnos = []syscall.Errno{
+	syscall.EAGAIN,
+	syscall.EINTR,
+	syscall.ETIMEDOUT,
+	syscall.ESTALE,
+}
+
+// isTransientError reports whether err looks temporary. Errors like ENOENT
+// and EACCES won't go away on their own, so they are never retried.
+func isTransientError(err error) bool {
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+		return false
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetries runs op, running it again up to e.ioRetries times while it fails
+// with a transient error. It returns the number of retries made.
+func (e *Executor) withRetries(op func() error) (int, error) {
+	err := op()
+	retries := 0
+	for backoff := retryBackoff; err != nil && retries < e.ioRetries && isTransientError(err); backoff *= 2 {
+		time.Sleep(backoff)
+		retries++
+		err = op()
+	}
+	return retries, err
+}
+
+// retryNote describes the retries for a result message, or returns "" when
+// there were none
+func retryNote(prefix string, retries int) string {
+	switch retries {
+	case 0:
+		return ""
+	case 1:
+		return prefix + "1 retry"
+	default:
+		return fmt.Sprintf("%s%d retries", prefix, retries)
+	}
+}
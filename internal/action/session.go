@@ -0,0 +1,73 @@
+package action
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// actionFilePattern matches action files saved from the TUI, named after the
+// session that saved them: dovetail_actions_20240115_143000.txt
+var actionFilePattern = regexp.MustCompile(`^dovetail_actions_(\d{8}_\d{6})\.txt$`)
+
+// sessionIDPattern matches a session ID: the time a session started
+var sessionIDPattern = regexp.MustCompile(`^\d{8}_\d{6}$`)
+
+// SessionFile is an artifact left behind by a dovetail session
+type SessionFile struct {
+	Session string // Session ID, YYYYMMDD_HHMMSS
+	Path    string
+	IsPatch bool // A saved patch rather than an action file
+}
+
+// IsSessionID reports whether id is a session ID, YYYYMMDD_HHMMSS
+func IsSessionID(id string) bool {
+	return sessionIDPattern.MatchString(id)
+}
+
+// sessionOf returns the session ID embedded in an action or patch file name
+func sessionOf(name string) (session string, isPatch bool, ok bool) {
+	if matches := actionFilePattern.FindStringSubmatch(name); matches != nil {
+		return matches[1], false, true
+	}
+	if matches := patchFilePattern.FindStringSubmatch(name); matches != nil {
+		return matches[2], true, true
+	}
+	return "", false, false
+}
+
+// FindSessionFiles returns the action files and saved patches under root,
+// sorted by session and then path. Hidden directories are not searched.
+func FindSessionFiles(root string) ([]SessionFile, error) {
+	var files []SessionFile
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if session, isPatch, ok := sessionOf(entry.Name()); ok {
+			files = append(files, SessionFile{Session: session, Path: path, IsPatch: isPatch})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Session != files[j].Session {
+			return files[i].Session < files[j].Session
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
@@ -10,12 +10,18 @@ import (
 type ActionType int
 
 const (
-	ActionIgnore      ActionType = iota // [i] - Do nothing
-	ActionCopyToRight                   // [>] - Copy from left to right
-	ActionCopyToLeft                    // [<] - Copy from right to left
-	ActionDeleteLeft                    // [x-] - Delete from left
-	ActionDeleteRight                   // [-x] - Delete from right
-	ActionDeleteBoth                    // [xx] - Delete from both
+	ActionIgnore         ActionType = iota // [i] - Do nothing
+	ActionCopyToRight                      // [>] - Copy from left to right
+	ActionCopyToLeft                       // [<] - Copy from right to left
+	ActionDeleteLeft                       // [x-] - Delete from left
+	ActionDeleteRight                      // [-x] - Delete from right
+	ActionDeleteBoth                       // [xx] - Delete from both
+	ActionChmodToRight                     // [m>] - Copy permissions from left to right
+	ActionChmodToLeft                      // [<m] - Copy permissions from right to left
+	ActionSymlinkToRight                   // [l>] - Recreate a left symlink on the right
+	ActionSymlinkToLeft                    // [<l] - Recreate a right symlink on the left
+	ActionMoveRight                        // [~>] - Move a renamed file on the right to its left path
+	ActionMoveLeft                         // [<~] - Move a renamed file on the left to its right path
 )
 
 func (a ActionType) String() string {
@@ -32,6 +38,18 @@ func (a ActionType) String() string {
 		return "-x"
 	case ActionDeleteBoth:
 		return "xx"
+	case ActionChmodToRight:
+		return "m>"
+	case ActionChmodToLeft:
+		return "<m"
+	case ActionSymlinkToRight:
+		return "l>"
+	case ActionSymlinkToLeft:
+		return "<l"
+	case ActionMoveRight:
+		return "~>"
+	case ActionMoveLeft:
+		return "<~"
 	default:
 		return "?"
 	}
@@ -51,11 +69,39 @@ func (a ActionType) Description() string {
 		return "Delete file from Right"
 	case ActionDeleteBoth:
 		return "Delete file from both Left and Right"
+	case ActionChmodToRight:
+		return "Copy permissions from Left to Right (content untouched)"
+	case ActionChmodToLeft:
+		return "Copy permissions from Right to Left (content untouched)"
+	case ActionSymlinkToRight:
+		return "Create a symlink on Right with Left's target (link, not content)"
+	case ActionSymlinkToLeft:
+		return "Create a symlink on Left with Right's target (link, not content)"
+	case ActionMoveRight:
+		return "Move renamed file on Right to Left's path (no copy)"
+	case ActionMoveLeft:
+		return "Move renamed file on Left to Right's path (no copy)"
 	default:
 		return "Unknown action"
 	}
 }
 
+// ActionTypes lists every action type in legend order
+var ActionTypes = []ActionType{
+	ActionIgnore, ActionCopyToRight, ActionCopyToLeft, ActionDeleteLeft,
+	ActionDeleteRight, ActionDeleteBoth, ActionChmodToRight, ActionChmodToLeft,
+	ActionSymlinkToRight, ActionSymlinkToLeft, ActionMoveRight, ActionMoveLeft,
+}
+
+// CountByType tallies actions by their type
+func CountByType(actions []ActionItem) map[ActionType]int {
+	counts := make(map[ActionType]int)
+	for _, item := range actions {
+		counts[item.Action]++
+	}
+	return counts
+}
+
 // ParseActionType parses an action string into an ActionType
 func ParseActionType(s string) (ActionType, bool) {
 	switch s {
@@ -71,6 +117,18 @@ func ParseActionType(s string) (ActionType, bool) {
 		return ActionDeleteRight, true
 	case "xx":
 		return ActionDeleteBoth, true
+	case "m>":
+		return ActionChmodToRight, true
+	case "<m":
+		return ActionChmodToLeft, true
+	case "l>":
+		return ActionSymlinkToRight, true
+	case "<l":
+		return ActionSymlinkToLeft, true
+	case "~>":
+		return ActionMoveRight, true
+	case "<~":
+		return ActionMoveLeft, true
 	default:
 		return ActionIgnore, false
 	}
@@ -81,9 +139,33 @@ type ActionItem struct {
 	Action       ActionType         // The action to perform
 	Status       compare.FileStatus // The comparison status that led to this action
 	RelativePath string             // Path relative to the root directories
+	Destination  string             // Relative destination path for copies, or the right path of a renamed entry
+	RightCase    string             // Right spelling of RelativePath, when paths were matched case-insensitively
 	LeftInfo     *compare.FileInfo  // File info from left directory (may be nil)
 	RightInfo    *compare.FileInfo  // File info from right directory (may be nil)
 	LineNumber   int                // Line number in the action file (for error reporting)
+	Comment      string             // Optional note written as an inline comment
+}
+
+// RightPath returns the relative path of the entry on the right, which
+// differs from RelativePath only in case when paths were matched
+// case-insensitively
+func (item ActionItem) RightPath() string {
+	if item.RightCase != "" {
+		return item.RightCase
+	}
+	return item.RelativePath
+}
+
+// DestinationPath returns the relative path a copy action writes to
+func (item ActionItem) DestinationPath() string {
+	if item.Destination != "" {
+		return item.Destination
+	}
+	if item.Action == ActionCopyToRight {
+		return item.RightPath()
+	}
+	return item.RelativePath
 }
 
 // ActionFile represents a complete action file
@@ -108,6 +190,8 @@ type ExecutionResult struct {
 	Error       error      // Error if action failed
 	BytesCopied int64      // Number of bytes copied (for copy operations)
 	Message     string     // Human-readable message about what happened
+	Warnings    []string   // Non-fatal problems (e.g. metadata that couldn't be preserved)
+	Skipped     bool       // Action was a deliberate no-op (e.g. destination not older)
 }
 
 // ExecutionSummary contains statistics about action execution
@@ -119,7 +203,13 @@ type ExecutionSummary struct {
 	FilesCreated      int
 	FilesDeleted      int
 	FilesOverwritten  int
+	FilesSkipped      int
+	FilesChmodded     int  // Files whose permissions were changed without copying
+	FilesMoved        int  // Renamed files moved to the other side's path
+	ActionsDeclined   int  // Actions the user chose not to run when asked
+	Aborted           bool // The user stopped execution before all actions ran
 	Errors            []string
+	Warnings          []string
 }
 
 // ValidationError represents an error in action file validation
@@ -133,6 +223,12 @@ func (ve ValidationError) Error() string {
 	return fmt.Sprintf("line %d: %s (action: %s)", ve.LineNumber, ve.Message, ve.Action)
 }
 
+// ErrorAt formats the error as path:line: message, the form compilers use,
+// so editors can jump straight to the offending line
+func (ve ValidationError) ErrorAt(path string) string {
+	return fmt.Sprintf("%s:%d: %s (action: %s)", path, ve.LineNumber, ve.Message, ve.Action)
+}
+
 // ActionFileError represents an error related to action file processing
 type ActionFileError struct {
 	Type    string // "parse", "validate", "execute"
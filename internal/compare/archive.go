@@ -0,0 +1,362 @@
+package compare
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// ArchiveSource is a .tar, .tar.gz/.tgz or .zip file whose entries are
+// compared as if it had been unpacked
+type ArchiveSource string
+
+// archiveSuffixes are the file names recognized as archives
+var archiveSuffixes = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// IsArchive reports whether path names a regular file with a supported archive suffix
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			info, err := os.Stat(path)
+			return err == nil && info.Mode().IsRegular()
+		}
+	}
+	return false
+}
+
+// Path returns the archive file
+func (s ArchiveSource) Path() string {
+	return string(s)
+}
+
+// archiveEntry is one entry read from an archive
+type archiveEntry struct {
+	name       string      // Normalized slash-separated relative path
+	info       os.FileInfo // Header information
+	linkTarget string      // Symlink target, if the entry is a symlink
+	noMode     bool        // The archive doesn't record Unix permissions for the entry
+	open       func() (io.ReadCloser, error)
+}
+
+func (s ArchiveSource) collect(e *Engine, side string) (*fileScan, error) {
+	files := make(map[string]*FileInfo)
+	var scanErrors []string
+	excludedDirs := make(map[string]bool)
+	var listed map[string]bool
+	if len(e.options.ListedPaths) > 0 {
+		listed = make(map[string]bool)
+		for _, relPath := range cleanPaths(e.options.ListedPaths) {
+			listed[relPath] = true
+		}
+	}
+
+	err := forEachArchiveEntry(string(s), func(entry archiveEntry) error {
+		relPath := filepath.FromSlash(entry.name)
+
+		// Listed paths are taken as they are, without depth limits or filters
+		if listed != nil {
+			if !listed[relPath] {
+				return nil
+			}
+		} else {
+			// Below the depth limit only the directories leading down to it count
+			if e.options.MaxDepth > 0 && pathDepth(relPath) > e.options.MaxDepth {
+				parts := strings.Split(filepath.ToSlash(relPath), "/")
+				e.addArchiveParents(files, excludedDirs, filepath.FromSlash(strings.Join(parts[:e.options.MaxDepth+1], "/")), side)
+				return nil
+			}
+
+			// Parent directories are often implied rather than stored
+			if excluded := e.addArchiveParents(files, excludedDirs, relPath, side); excluded {
+				return nil
+			}
+
+			if e.filter.ShouldExclude(relPath, entry.info) {
+				util.VerbosePrintf(e.verboseLevel, 3, "Excluding (%s): %s", side, relPath)
+				if entry.info.IsDir() {
+					excludedDirs[relPath] = true
+					delete(files, relPath)
+				}
+				return nil
+			}
+		}
+
+		fileInfo := &FileInfo{
+			Path:        relPath,
+			Size:        entry.info.Size(),
+			ModTime:     entry.info.ModTime(),
+			IsDir:       entry.info.IsDir(),
+			IsSymlink:   entry.linkTarget != "",
+			LinkTarget:  entry.linkTarget,
+			Permissions: entry.info.Mode().String(),
+		}
+		if entry.noMode {
+			fileInfo.Permissions = ""
+		}
+
+		if !fileInfo.IsDir && !fileInfo.IsSymlink && e.options.MetadataOnly {
+			fileInfo.Hash = metadataHash(fileInfo.Size, fileInfo.ModTime)
+		} else if !fileInfo.IsDir && !fileInfo.IsSymlink {
+			util.VerbosePrintf(e.verboseLevel, 3, "Calculating hash (%s): %s", side, relPath)
+			hash, err := e.hashArchiveEntry(entry)
+			if err != nil {
+				util.VerbosePrintf(e.verboseLevel, 2, "Hash calculation failed (%s): %s - %v", side, relPath, err)
+				fileInfo.Hash = "ERROR_CALCULATING_HASH"
+			} else {
+				fileInfo.Hash = hash
+			}
+		}
+
+		files[relPath] = fileInfo
+		return nil
+	}, func(problem string) {
+		scanErrors = append(scanErrors, fmt.Sprintf("%s (%s)", problem, side))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileScan{side: side, files: files, errors: scanErrors}, nil
+}
+
+// addArchiveParents records implied parent directories of relPath and
+// reports whether relPath is inside an excluded directory
+func (e *Engine) addArchiveParents(files map[string]*FileInfo, excludedDirs map[string]bool, relPath, side string) bool {
+	var parents []string
+	for dir := filepath.Dir(relPath); dir != "."; dir = filepath.Dir(dir) {
+		parents = append(parents, dir)
+	}
+
+	// Outermost first, so an excluded ancestor hides everything below it
+	for i := len(parents) - 1; i >= 0; i-- {
+		dir := parents[i]
+		if excludedDirs[dir] {
+			return true
+		}
+		if _, ok := files[dir]; ok {
+			continue
+		}
+		info := impliedDirInfo{name: filepath.Base(dir)}
+		if e.filter.ShouldExclude(dir, info) {
+			util.VerbosePrintf(e.verboseLevel, 3, "Excluding (%s): %s", side, dir)
+			excludedDirs[dir] = true
+			return true
+		}
+		files[dir] = &FileInfo{Path: dir, IsDir: true, Permissions: info.Mode().String()}
+	}
+	return false
+}
+
+// hashArchiveEntry hashes an entry's content like calculateHash does for files
+func (e *Engine) hashArchiveEntry(entry archiveEntry) (string, error) {
+	reader, err := entry.open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	return e.hashContent(reader, entry.info.Size(), entry.info.ModTime())
+}
+
+// forEachArchiveEntry calls fn for every entry of a supported archive.
+// Entries that can't be represented are reported through problem and skipped.
+func forEachArchiveEntry(archivePath string, fn func(archiveEntry) error, problem func(string)) error {
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		return forEachZipEntry(archivePath, fn, problem)
+	}
+	return forEachTarEntry(archivePath, fn, problem)
+}
+
+// forEachTarEntry reads a plain or gzip-compressed tar archive
+func forEachTarEntry(archivePath string, fn func(archiveEntry) error, problem func(string)) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var stream io.Reader = file
+	lower := strings.ToLower(archivePath)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", archivePath, err)
+		}
+		defer gz.Close()
+		stream = gz
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", archivePath, err)
+		}
+
+		name, ok := normalizeArchiveName(header.Name)
+		if !ok {
+			problem(fmt.Sprintf("skipped unsafe archive entry %q", header.Name))
+			continue
+		}
+		if name == "" {
+			continue // The archive root itself
+		}
+
+		entry := archiveEntry{name: name, info: header.FileInfo()}
+		switch header.Typeflag {
+		case tar.TypeDir:
+		case tar.TypeReg, tar.TypeRegA:
+			// The tar stream can only be read once, at its current position
+			entry.open = func() (io.ReadCloser, error) { return io.NopCloser(reader), nil }
+		case tar.TypeSymlink:
+			entry.linkTarget = header.Linkname
+		default:
+			problem(fmt.Sprintf("skipped unsupported archive entry %s (type %q)", name, header.Typeflag))
+			continue
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// forEachZipEntry reads a zip archive
+func forEachZipEntry(archivePath string, fn func(archiveEntry) error, problem func(string)) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", archivePath, err)
+	}
+	defer reader.Close()
+
+	for _, zipFile := range reader.File {
+		name, ok := normalizeArchiveName(zipFile.Name)
+		if !ok {
+			problem(fmt.Sprintf("skipped unsafe archive entry %q", zipFile.Name))
+			continue
+		}
+		if name == "" {
+			continue
+		}
+
+		zipFile := zipFile
+		entry := archiveEntry{
+			name:   name,
+			info:   zipFile.FileInfo(),
+			noMode: zipFile.CreatorVersion>>8 != zipCreatorUnix,
+		}
+		mode := zipFile.Mode()
+		switch {
+		case mode.IsDir():
+		case mode&os.ModeSymlink != 0:
+			// Zip stores the symlink target as the entry content
+			target, err := readZipEntry(zipFile)
+			if err != nil {
+				problem(fmt.Sprintf("failed to read symlink %s: %v", name, err))
+				continue
+			}
+			entry.linkTarget = target
+		case mode.IsRegular():
+			entry.open = func() (io.ReadCloser, error) { return zipFile.Open() }
+		default:
+			problem(fmt.Sprintf("skipped unsupported archive entry %s (mode %s)", name, mode))
+			continue
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipCreatorUnix is the "version made by" host for archives that store Unix modes
+const zipCreatorUnix = 3
+
+// readZipEntry returns the content of a small zip entry as a string
+func readZipEntry(zipFile *zip.File) (string, error) {
+	reader, err := zipFile.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	return string(data), err
+}
+
+// normalizeArchiveName converts an entry name to a clean relative path.
+// Names that are absolute or escape the archive root are rejected.
+func normalizeArchiveName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") {
+		return "", false
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return "", true
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// ExtractArchive unpacks the regular files of archivePath for which want
+// returns true into destDir, so they can be shown with the usual diff tools
+func ExtractArchive(archivePath, destDir string, want func(relPath string) bool) error {
+	return forEachArchiveEntry(archivePath, func(entry archiveEntry) error {
+		relPath := filepath.FromSlash(entry.name)
+		if entry.open == nil || !want(relPath) {
+			return nil
+		}
+
+		dstPath := filepath.Join(destDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return err
+		}
+
+		reader, err := entry.open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, entry.info.Mode().Perm()|0200)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, reader); err != nil {
+			dst.Close()
+			return err
+		}
+		if err := dst.Close(); err != nil {
+			return err
+		}
+		return os.Chtimes(dstPath, entry.info.ModTime(), entry.info.ModTime())
+	}, func(string) {})
+}
+
+// impliedDirInfo describes a directory that exists in an archive only as
+// the parent of other entries
+type impliedDirInfo struct {
+	name string
+}
+
+func (i impliedDirInfo) Name() string       { return i.name }
+func (i impliedDirInfo) Size() int64        { return 0 }
+func (i impliedDirInfo) Mode() os.FileMode  { return os.ModeDir | 0755 }
+func (i impliedDirInfo) ModTime() time.Time { return time.Time{} }
+func (i impliedDirInfo) IsDir() bool        { return true }
+func (i impliedDirInfo) Sys() interface{}   { return nil }
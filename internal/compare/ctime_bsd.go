@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd
+
+package compare
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileChangeTime returns a file's inode change time in Unix nanoseconds, if
+// available on this platform
+func fileChangeTime(info os.FileInfo) (int64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return stat.Ctimespec.Nano(), true
+}
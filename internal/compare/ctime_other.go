@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !netbsd
+
+package compare
+
+import "os"
+
+// fileChangeTime returns a file's inode change time in Unix nanoseconds, if
+// available on this platform
+func fileChangeTime(info os.FileInfo) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package compare
+
+import "sort"
+
+// DeltaKind describes how a path's comparison result changed between two runs
+type DeltaKind int
+
+const (
+	DeltaNewlyDiffers   DeltaKind = iota // Was identical or absent, now differs
+	DeltaNowIdentical                    // Differed, now identical or gone from both sides
+	DeltaStatusChanged                   // Still differs, but with a different status
+	DeltaContentChanged                  // Still differs with the same status, but an entry changed again
+)
+
+func (k DeltaKind) String() string {
+	switch k {
+	case DeltaNewlyDiffers:
+		return "differs"
+	case DeltaNowIdentical:
+		return "identical"
+	case DeltaStatusChanged:
+		return "status"
+	case DeltaContentChanged:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// ResultDelta is one path whose result differs between two comparisons
+type ResultDelta struct {
+	Kind     DeltaKind
+	Path     string
+	Previous *ComparisonResult // nil if the path wasn't in the previous run
+	Current  *ComparisonResult // nil if the path is gone from both sides
+}
+
+// DiffResults reports the paths whose results changed from previous to
+// current, sorted by path. Paths identical in both runs are not reported.
+func DiffResults(previous, current []ComparisonResult) []ResultDelta {
+	before := make(map[string]*ComparisonResult, len(previous))
+	for i := range previous {
+		before[previous[i].RelativePath] = &previous[i]
+	}
+
+	var deltas []ResultDelta
+	seen := make(map[string]bool, len(current))
+	for i := range current {
+		cur := &current[i]
+		seen[cur.RelativePath] = true
+		prev := before[cur.RelativePath]
+
+		prevDiffers := prev != nil && prev.Status != StatusIdentical
+		curDiffers := cur.Status != StatusIdentical
+		switch {
+		case !prevDiffers && curDiffers:
+			deltas = append(deltas, ResultDelta{DeltaNewlyDiffers, cur.RelativePath, prev, cur})
+		case prevDiffers && !curDiffers:
+			deltas = append(deltas, ResultDelta{DeltaNowIdentical, cur.RelativePath, prev, cur})
+		case prevDiffers && prev.Status != cur.Status:
+			deltas = append(deltas, ResultDelta{DeltaStatusChanged, cur.RelativePath, prev, cur})
+		case prevDiffers && (entryChanged(prev.LeftInfo, cur.LeftInfo) || entryChanged(prev.RightInfo, cur.RightInfo)):
+			deltas = append(deltas, ResultDelta{DeltaContentChanged, cur.RelativePath, prev, cur})
+		}
+	}
+
+	for i := range previous {
+		prev := &previous[i]
+		if !seen[prev.RelativePath] && prev.Status != StatusIdentical {
+			deltas = append(deltas, ResultDelta{DeltaNowIdentical, prev.RelativePath, prev, nil})
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		return deltas[i].Path < deltas[j].Path
+	})
+	return deltas
+}
+
+// entryChanged reports whether an entry's content, size or link target changed
+func entryChanged(before, after *FileInfo) bool {
+	if before == nil || after == nil {
+		return before != after
+	}
+	return before.Hash != after.Hash || before.Size != after.Size || before.LinkTarget != after.LinkTarget
+}
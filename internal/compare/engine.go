@@ -1,29 +1,54 @@
 package compare
 
 import (
-	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/harikb/dovetail/internal/util"
 )
 
-// NewEngine creates a new comparison engine with the given options
-func NewEngine(options ComparisonOptions) *Engine {
+// NewEngine creates a new comparison engine with the given options. It
+// returns an error if the options are invalid.
+func NewEngine(options ComparisonOptions) (*Engine, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Set default values
-	if options.ParallelWorkers == 0 {
+	if options.ParallelWorkers <= 0 {
 		options.ParallelWorkers = runtime.NumCPU()
 	}
+	options.HashAlgorithm = normalizeHashAlgorithm(options.HashAlgorithm)
+
+	filter, err := NewFilter(options)
+	if err != nil {
+		return nil, err
+	}
+	normalizers, err := compileNormalizeRules(options.NormalizeRules)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Engine{
 		options:      options,
-		filter:       NewFilter(options),
+		filter:       filter,
+		normalizers:  normalizers,
 		verboseLevel: 0, // Default to no verbosity
-	}
+	}, nil
+}
+
+// Filter returns the filter the engine applies while walking the trees
+func (e *Engine) Filter() *Filter {
+	return e.filter
 }
 
 // SetVerboseLevel sets the verbosity level for progress reporting
@@ -31,109 +56,406 @@ func (e *Engine) SetVerboseLevel(level int) {
 	e.verboseLevel = level
 }
 
+// SetProgressFunc registers a callback that receives comparison progress
+// regardless of the verbosity level
+func (e *Engine) SetProgressFunc(fn util.ProgressFunc) {
+	e.progressFunc = fn
+}
+
+// SetHashCache reuses hashes from cache for files whose size, mtime, inode
+// change time and inode are unchanged, and records newly computed ones. The
+// caller saves the cache.
+func (e *Engine) SetHashCache(cache *HashCache) {
+	e.hashCache = cache
+}
+
 // Compare performs a recursive comparison of two directories
 func (e *Engine) Compare(leftDir, rightDir string) ([]ComparisonResult, *ComparisonSummary, error) {
+	results := []ComparisonResult{}
+	summary, err := e.CompareStream(leftDir, rightDir, func(result ComparisonResult) {
+		results = append(results, result)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return results, summary, nil
+}
+
+// CompareStream compares two directories like Compare, but passes each result
+// to fn as soon as a worker produces it instead of collecting them. Results
+// arrive in no particular order. fn is called from a single goroutine, so it
+// needs no locking of its own. Either side may be a supported archive file.
+func (e *Engine) CompareStream(leftDir, rightDir string, fn func(ComparisonResult)) (*ComparisonSummary, error) {
+	return e.CompareSources(NewSource(leftDir), NewSource(rightDir), fn)
+}
+
+// CompareSources streams the comparison of two sources, as CompareStream does
+func (e *Engine) CompareSources(left, right Source, fn func(ComparisonResult)) (*ComparisonSummary, error) {
 	util.VerbosePrintf(e.verboseLevel, 1, "Starting directory comparison...")
+	leftDir, rightDir := left.Path(), right.Path()
+	e.hashedBytes.Store(0)
+	e.cacheHits.Store(0)
+	e.ignoreRoots, e.ignoreLoaded, e.ignoreFiles = nil, make(map[string]bool), nil
+	for _, source := range []Source{left, right} {
+		if _, ok := source.(ArchiveSource); !ok {
+			e.ignoreRoots = append(e.ignoreRoots, source.Path())
+		}
+	}
+	started := time.Now()
 
-	// Collect all files from both directories
+	// Collect all files from both sides
 	util.VerbosePrintf(e.verboseLevel, 1, "Scanning left directory: %s", leftDir)
-	leftFiles, err := e.collectFiles(leftDir, "left")
+	leftScan, err := left.collect(e, "left")
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to scan left directory: %w", err)
+		return nil, fmt.Errorf("failed to scan left directory: %w", err)
 	}
+	leftFiles := leftScan.files
 	util.VerbosePrintf(e.verboseLevel, 1, "Found %d items in left directory", len(leftFiles))
+	leftScanTime := time.Since(started)
 
 	util.VerbosePrintf(e.verboseLevel, 1, "Scanning right directory: %s", rightDir)
-	rightFiles, err := e.collectFiles(rightDir, "right")
+	rightScan, err := right.collect(e, "right")
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to scan right directory: %w", err)
+		return nil, fmt.Errorf("failed to scan right directory: %w", err)
 	}
+	rightFiles := rightScan.files
 	util.VerbosePrintf(e.verboseLevel, 1, "Found %d items in right directory", len(rightFiles))
+	rightScanTime := time.Since(started) - leftScanTime
+
+	// Entries below a directory one side couldn't list can't be compared;
+	// without this they would all look like they exist on one side only
+	skipped := append(leftScan.skipped, rightScan.skipped...)
+	dropUnlisted(leftFiles, rightScan.skipped)
+	dropUnlisted(rightFiles, leftScan.skipped)
+
+	// A file excluded by content on one side is excluded on the other too,
+	// rather than reported as existing there only
+	for _, relPath := range append(leftScan.excluded, rightScan.excluded...) {
+		delete(leftFiles, relPath)
+		delete(rightFiles, relPath)
+	}
 
-	// Create a set of all unique paths
-	allPaths := make(map[string]bool)
-	for path := range leftFiles {
-		allPaths[path] = true
+	// Pair up the entries of both sides
+	allPaths := e.pairPaths(leftFiles, rightFiles)
+	if e.options.DetectRenames {
+		allPaths = pairRenames(allPaths, leftFiles, rightFiles)
 	}
-	for path := range rightFiles {
-		allPaths[path] = true
+	missingPaths := e.missingListedPaths(leftFiles, rightFiles)
+	var linkDifferences []HardlinkDifference
+	if e.options.DetectHardlinks {
+		linkDifferences = hardlinkDifferences(leftFiles, rightFiles)
 	}
 
 	util.VerbosePrintf(e.verboseLevel, 1, "Comparing %d unique paths using %d workers...", len(allPaths), e.options.ParallelWorkers)
 
-	// Compare files in parallel
-	results := make([]ComparisonResult, 0, len(allPaths))
-	summary := &ComparisonSummary{}
-	resultsChan := make(chan ComparisonResult, len(allPaths))
-	errorsChan := make(chan error, len(allPaths))
+	summary := &ComparisonSummary{
+		HashAlgorithm:       e.options.HashAlgorithm,
+		MissingPaths:        missingPaths,
+		HardlinkDifferences: linkDifferences,
+	}
+	summary.ErrorsEncountered = append(summary.ErrorsEncountered, leftScan.errors...)
+	summary.ErrorsEncountered = append(summary.ErrorsEncountered, rightScan.errors...)
+	sort.Slice(skipped, func(i, j int) bool {
+		if skipped[i].Side != skipped[j].Side {
+			return skipped[i].Side == "left"
+		}
+		return skipped[i].Path < skipped[j].Path
+	})
+	summary.SkippedPaths = skipped
 
 	// Create progress reporter
 	progressReporter := util.NewProgressReporter(e.verboseLevel, len(allPaths))
+	if e.progressFunc != nil {
+		progressReporter.SetProgressFunc(e.progressFunc)
+	}
 
-	// Create worker pool
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, e.options.ParallelWorkers)
+	// A fixed pool of workers with small channels keeps memory flat no
+	// matter how many paths there are
+	type outcome struct {
+		result ComparisonResult
+		err    error
+	}
+	pathsChan := make(chan pathPair, e.options.ParallelWorkers)
+	outcomesChan := make(chan outcome, e.options.ParallelWorkers)
 
-	for path := range allPaths {
+	var wg sync.WaitGroup
+	for i := 0; i < e.options.ParallelWorkers; i++ {
 		wg.Add(1)
-		go func(p string) {
+		go func() {
 			defer wg.Done()
-			semaphore <- struct{}{}        // Acquire
-			defer func() { <-semaphore }() // Release
+			for pair := range pathsChan {
+				// Report progress
+				progressReporter.Report("Comparing: %s", pair.path)
+
+				if pair.renamed {
+					outcomesChan <- outcome{result: e.renamedResult(pair, leftFiles[pair.left], rightFiles[pair.right])}
+					continue
+				}
+				result, err := e.compareFile(pair.path, leftFiles[pair.left], rightFiles[pair.right], leftDir, rightDir)
+				if err != nil {
+					outcomesChan <- outcome{err: fmt.Errorf("error comparing %s: %w", pair.path, err)}
+					continue
+				}
+				outcomesChan <- outcome{result: result}
+			}
+		}()
+	}
 
-			leftInfo := leftFiles[p]
-			rightInfo := rightFiles[p]
+	go func() {
+		for _, pair := range allPaths {
+			pathsChan <- pair
+		}
+		close(pathsChan)
+	}()
 
-			// Report progress
-			progressReporter.Report("Comparing: %s", p)
+	// Close the outcome channel when all workers are done
+	go func() {
+		wg.Wait()
+		close(outcomesChan)
+	}()
 
-			result, err := e.compareFile(p, leftInfo, rightInfo, leftDir, rightDir)
-			if err != nil {
-				errorsChan <- fmt.Errorf("error comparing %s: %w", p, err)
-				return
+	// Results are consumed on this goroutine only, so the summary needs no lock
+	var compareErrors []string
+	for o := range outcomesChan {
+		if o.err != nil {
+			compareErrors = append(compareErrors, o.err.Error())
+			continue
+		}
+		e.updateSummary(summary, o.result)
+		fn(o.result)
+	}
+	// Sorted so reports don't depend on which worker finished first
+	sort.Strings(compareErrors)
+	summary.ErrorsEncountered = append(summary.ErrorsEncountered, compareErrors...)
+
+	progressReporter.Finish()
+	util.VerbosePrintf(e.verboseLevel, 1, "Comparison complete!")
+	total := time.Since(started)
+	util.VerbosePrintf(e.verboseLevel, 2, "Timing: scan left %s, scan right %s, compare %s, total %s",
+		leftScanTime.Round(time.Millisecond), rightScanTime.Round(time.Millisecond),
+		(total - leftScanTime - rightScanTime).Round(time.Millisecond), total.Round(time.Millisecond))
+	if e.hashCache != nil {
+		util.VerbosePrintf(e.verboseLevel, 2, "Hashed %s, %d hashes reused from the cache",
+			util.FormatSize(e.hashedBytes.Load()), e.cacheHits.Load())
+	} else {
+		util.VerbosePrintf(e.verboseLevel, 2, "Hashed %s", util.FormatSize(e.hashedBytes.Load()))
+	}
+
+	sort.Slice(summary.DetectedPatchFiles, func(i, j int) bool {
+		a, b := summary.DetectedPatchFiles[i], summary.DetectedPatchFiles[j]
+		if a.Side != b.Side {
+			return a.Side == "left"
+		}
+		return a.Path < b.Path
+	})
+
+	return summary, nil
+}
+
+// dropUnlisted removes the entries below directories whose contents the
+// other side could not list
+func dropUnlisted(files map[string]*FileInfo, skipped []SkippedPath) {
+	for _, skip := range skipped {
+		if !skip.IsDir {
+			continue
+		}
+		prefix := skip.Path + string(filepath.Separator)
+		for relPath := range files {
+			if strings.HasPrefix(relPath, prefix) {
+				delete(files, relPath)
 			}
+		}
+	}
+}
 
-			resultsChan <- result
-		}(path)
+// skipReason describes why an entry could not be read
+func skipReason(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return "permission denied"
+	case errors.Is(err, fs.ErrNotExist):
+		return "vanished during the scan"
+	default:
+		return err.Error()
 	}
+}
 
-	// Close channels when all workers are done
-	go func() {
-		wg.Wait()
-		close(resultsChan)
-		close(errorsChan)
-	}()
+// missingListedPaths returns the sorted ListedPaths found on neither side
+func (e *Engine) missingListedPaths(leftFiles, rightFiles map[string]*FileInfo) []string {
+	var missing []string
+	for _, relPath := range cleanPaths(e.options.ListedPaths) {
+		if leftFiles[relPath] == nil && rightFiles[relPath] == nil {
+			missing = append(missing, relPath)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
 
-	// Collect results and errors
-	for result := range resultsChan {
-		results = append(results, result)
-		e.updateSummary(summary, result)
+// pathPair names the left and right entries compared as one path
+type pathPair struct {
+	path    string // Reported RelativePath (the left casing when both exist)
+	left    string // Key into the left files
+	right   string // Key into the right files
+	renamed bool   // Paired by content under different paths (DetectRenames)
+}
+
+// pairPaths lists every path to compare. With CaseInsensitivePaths, a left and
+// right entry whose paths differ only in case are paired, unless either side
+// holds several entries that fold to the same name; those stay separate.
+func (e *Engine) pairPaths(leftFiles, rightFiles map[string]*FileInfo) []pathPair {
+	var pairs []pathPair
+	if !e.options.CaseInsensitivePaths {
+		for path := range leftFiles {
+			pairs = append(pairs, pathPair{path, path, path, false})
+		}
+		for path := range rightFiles {
+			if _, ok := leftFiles[path]; !ok {
+				pairs = append(pairs, pathPair{path, path, path, false})
+			}
+		}
+		return pairs
+	}
+
+	leftFolded := foldPaths(leftFiles)
+	rightFolded := foldPaths(rightFiles)
+	for folded, leftPaths := range leftFolded {
+		rightPaths := rightFolded[folded]
+		if len(leftPaths) == 1 && len(rightPaths) == 1 {
+			pairs = append(pairs, pathPair{leftPaths[0], leftPaths[0], rightPaths[0], false})
+			continue
+		}
+		for _, path := range leftPaths {
+			pairs = append(pairs, pathPair{path, path, path, false})
+		}
+		for _, path := range rightPaths {
+			if _, ok := leftFiles[path]; !ok {
+				pairs = append(pairs, pathPair{path, path, path, false})
+			}
+		}
 	}
+	for folded, rightPaths := range rightFolded {
+		if _, ok := leftFolded[folded]; ok {
+			continue
+		}
+		for _, path := range rightPaths {
+			pairs = append(pairs, pathPair{path, path, path, false})
+		}
+	}
+	return pairs
+}
 
-	for err := range errorsChan {
-		summary.ErrorsEncountered = append(summary.ErrorsEncountered, err.Error())
+// foldPaths groups paths by their case-folded form
+func foldPaths(files map[string]*FileInfo) map[string][]string {
+	folded := make(map[string][]string, len(files))
+	for path := range files {
+		key := strings.ToLower(path)
+		folded[key] = append(folded[key], path)
 	}
+	return folded
+}
 
-	progressReporter.Finish()
-	util.VerbosePrintf(e.verboseLevel, 1, "Comparison complete!")
+// collectFiles recursively collects all files from a directory.
+// Problems that prevent an entry from being compared (such as broken
+// symlinks when following links) are returned as scan errors.
+func (e *Engine) collectFiles(dir string, side string) (*fileScan, error) {
+	scan := &fileScan{
+		side:    side,
+		files:   make(map[string]*FileInfo),
+		walking: make(map[string]bool),
+	}
 
-	return results, summary, nil
+	// Track the root so symlinks pointing back into the tree don't loop forever
+	if realDir, err := filepath.EvalSymlinks(dir); err == nil {
+		scan.walking[realDir] = true
+	}
+	e.loadIgnoreFiles(scan, "")
+
+	err := e.walkTree(scan, dir, "")
+
+	if e.verboseLevel >= 2 {
+		util.VerbosePrintf(e.verboseLevel, 2, "Completed scan of %s: %d files found", side, scan.fileCount)
+	}
+
+	return scan, err
+}
+
+// collectPaths stats each of the relative paths under dir instead of
+// walking it. Paths missing from dir are left out.
+func (e *Engine) collectPaths(dir string, paths []string, side string) *fileScan {
+	scan := &fileScan{side: side, files: make(map[string]*FileInfo)}
+	for _, relPath := range cleanPaths(paths) {
+		if !isContained(relPath) {
+			scan.errors = append(scan.errors, fmt.Sprintf("invalid path (%s) %s: must be inside the compared directory", side, relPath))
+			continue
+		}
+		fileInfo, err := e.statEntry(dir, relPath, side)
+		if err != nil {
+			scan.skipped = append(scan.skipped, SkippedPath{Side: side, Path: relPath, Reason: skipReason(err)})
+			continue
+		}
+		if fileInfo != nil {
+			scan.files[relPath] = fileInfo
+		}
+	}
+	return scan
 }
 
-// collectFiles recursively collects all files from a directory
-func (e *Engine) collectFiles(dir string, side string) (map[string]*FileInfo, error) {
-	files := make(map[string]*FileInfo)
-	fileCount := 0
+// cleanPaths returns paths cleaned and without duplicates
+func cleanPaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	var cleaned []string
+	for _, path := range paths {
+		path = filepath.Clean(filepath.FromSlash(path))
+		if !seen[path] {
+			seen[path] = true
+			cleaned = append(cleaned, path)
+		}
+	}
+	return cleaned
+}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+// fileScan holds the state of a single directory scan
+type fileScan struct {
+	side      string
+	files     map[string]*FileInfo
+	errors    []string
+	skipped   []SkippedPath
+	excluded  []string        // Files excluded by content, dropped from both sides
+	walking   map[string]bool // Real paths of directory trees currently being walked (for symlink cycles)
+	fileCount int
+}
+
+// walkTree walks root, recording entries under relPrefix in the scan's file map
+func (e *Engine) walkTree(scan *fileScan, root, relPrefix string) error {
+	side := scan.side
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			// Skip files we can't access rather than failing completely
+			// Skip entries we can't access rather than failing completely,
+			// but record them so the comparison is known to be incomplete
 			util.VerbosePrintf(e.verboseLevel, 2, "Skipping inaccessible path (%s): %s", side, path)
+			relPath, relErr := filepath.Rel(root, path)
+			if relErr != nil {
+				return nil
+			}
+			isDir := info != nil && info.IsDir()
+			scan.skipped = append(scan.skipped, SkippedPath{
+				Side:   side,
+				Path:   filepath.Join(relPrefix, relPath),
+				IsDir:  isDir,
+				Reason: skipReason(err),
+			})
+			// An unlistable directory is still an entry on this side
+			if isDir && relPath != "." {
+				relPath = filepath.Join(relPrefix, relPath)
+				scan.files[relPath] = e.newFileInfo(path, relPath, side, info, false, "")
+			}
 			return nil
 		}
 
 		// Calculate relative path
-		relPath, err := filepath.Rel(dir, path)
+		relPath, err := filepath.Rel(root, path)
 		if err != nil {
 			return err
 		}
@@ -142,6 +464,28 @@ func (e *Engine) collectFiles(dir string, side string) (map[string]*FileInfo, er
 		if relPath == "." {
 			return nil
 		}
+		relPath = filepath.Join(relPrefix, relPath)
+
+		// Resolve symbolic links
+		linkTarget := ""
+		isSymlink := info.Mode()&os.ModeSymlink != 0
+		if isSymlink {
+			linkTarget, err = os.Readlink(path)
+			if err != nil {
+				scan.errors = append(scan.errors, fmt.Sprintf("failed to read symlink (%s) %s: %v", side, relPath, err))
+				return nil
+			}
+
+			if e.options.FollowSymlinks {
+				targetInfo, err := os.Stat(path)
+				if err != nil {
+					scan.errors = append(scan.errors, fmt.Sprintf("broken symlink (%s) %s -> %s: %v", side, relPath, linkTarget, err))
+					return nil
+				}
+				info = targetInfo
+				isSymlink = false
+			}
+		}
 
 		// Report current directory being scanned
 		if info.IsDir() {
@@ -149,55 +493,86 @@ func (e *Engine) collectFiles(dir string, side string) (map[string]*FileInfo, er
 		}
 
 		// Apply filters
-		if e.filter.ShouldExclude(relPath, info) {
+		if e.filter.ShouldExclude(relPath, info) || e.ignoredByFile(relPath) {
 			util.VerbosePrintf(e.verboseLevel, 3, "Excluding (%s): %s", side, relPath)
-			if info.IsDir() {
+			// For a followed symlink, Walk sees a file: SkipDir would skip its siblings
+			if info.IsDir() && linkTarget == "" {
 				return filepath.SkipDir
 			}
 			return nil
 		}
+		if !isSymlink && e.filter.ExcludesContent(path, info) {
+			util.VerbosePrintf(e.verboseLevel, 3, "Excluding by content (%s): %s", side, relPath)
+			scan.excluded = append(scan.excluded, relPath)
+			return nil
+		}
+
+		if info.IsDir() {
+			e.loadIgnoreFiles(scan, relPath)
+		}
 
 		// Report file being processed
 		if !info.IsDir() {
-			fileCount++
+			scan.fileCount++
 			if e.verboseLevel >= 3 {
 				util.VerbosePrintf(e.verboseLevel, 3, "Found file (%s): %s", side, relPath)
-			} else if e.verboseLevel >= 2 && fileCount%100 == 0 {
-				util.VerbosePrintf(e.verboseLevel, 2, "Scanned %d files in %s...", fileCount, side)
+			} else if e.verboseLevel >= 2 && scan.fileCount%100 == 0 {
+				util.VerbosePrintf(e.verboseLevel, 2, "Scanned %d files in %s...", scan.fileCount, side)
 			}
 		}
 
-		// Create FileInfo
-		fileInfo := &FileInfo{
-			Path:        relPath,
-			Size:        info.Size(),
-			ModTime:     info.ModTime(),
-			IsDir:       info.IsDir(),
-			Permissions: info.Mode().String(),
+		scan.files[relPath] = e.newFileInfo(path, relPath, side, info, isSymlink, linkTarget)
+		if scan.files[relPath].Hash == "ERROR_CALCULATING_HASH" {
+			scan.skipped = append(scan.skipped, SkippedPath{Side: side, Path: relPath, Reason: "content could not be read; compared as modified"})
 		}
 
-		// Calculate hash for files (not directories)
-		if !info.IsDir() {
-			util.VerbosePrintf(e.verboseLevel, 3, "Calculating hash (%s): %s", side, relPath)
-			hash, err := e.calculateHash(path)
+		// Directories at the depth limit are compared as entries only
+		if info.IsDir() && e.atMaxDepth(relPath) {
+			if linkTarget != "" {
+				return nil // Walk doesn't descend into symlinks anyway
+			}
+			return filepath.SkipDir
+		}
+
+		// filepath.Walk doesn't descend into symlinked directories, so walk the target ourselves
+		if linkTarget != "" && info.IsDir() {
+			realPath, err := filepath.EvalSymlinks(path)
 			if err != nil {
-				// Log error but don't fail - we'll mark as different
-				util.VerbosePrintf(e.verboseLevel, 2, "Hash calculation failed (%s): %s - %v", side, relPath, err)
-				fileInfo.Hash = "ERROR_CALCULATING_HASH"
-			} else {
-				fileInfo.Hash = hash
+				scan.errors = append(scan.errors, fmt.Sprintf("failed to resolve symlink (%s) %s: %v", side, relPath, err))
+				return nil
+			}
+			if scan.walking[realPath] || isAncestorDir(realPath, path) {
+				scan.errors = append(scan.errors, fmt.Sprintf("symlink cycle (%s) %s -> %s", side, relPath, linkTarget))
+				return nil
 			}
+			scan.walking[realPath] = true
+			err = e.walkTree(scan, realPath, relPath)
+			delete(scan.walking, realPath)
+			return err
 		}
 
-		files[relPath] = fileInfo
 		return nil
 	})
+}
 
-	if e.verboseLevel >= 2 {
-		util.VerbosePrintf(e.verboseLevel, 2, "Completed scan of %s: %d files found", side, fileCount)
-	}
+// atMaxDepth reports whether relPath is as deep as MaxDepth allows, so
+// nothing below it is compared
+func (e *Engine) atMaxDepth(relPath string) bool {
+	return e.options.MaxDepth > 0 && pathDepth(relPath) >= e.options.MaxDepth
+}
 
-	return files, err
+// pathDepth returns the number of segments in a relative path
+func pathDepth(relPath string) int {
+	return strings.Count(filepath.ToSlash(filepath.Clean(relPath)), "/") + 1
+}
+
+// isAncestorDir reports whether dir is the real parent directory of path or one of its ancestors
+func isAncestorDir(dir, path string) bool {
+	realParent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	return realParent == dir || strings.HasPrefix(realParent, dir+string(filepath.Separator))
 }
 
 // compareFile compares a single file between left and right directories
@@ -207,6 +582,11 @@ func (e *Engine) compareFile(relPath string, leftInfo, rightInfo *FileInfo, left
 		LeftInfo:     leftInfo,
 		RightInfo:    rightInfo,
 	}
+	if e.options.MetadataOnly {
+		result.Method = ComparisonMetadata
+	} else if (leftInfo != nil && IsSampledHash(leftInfo.Hash)) || (rightInfo != nil && IsSampledHash(rightInfo.Hash)) {
+		result.Method = ComparisonSampled
+	}
 
 	// Determine status
 	if leftInfo == nil && rightInfo == nil {
@@ -220,23 +600,168 @@ func (e *Engine) compareFile(relPath string, leftInfo, rightInfo *FileInfo, left
 		if leftInfo.IsDir && rightInfo.IsDir {
 			// Both are directories - they're identical as directories
 			result.Status = StatusIdentical
-		} else if leftInfo.IsDir != rightInfo.IsDir {
-			// One is directory, one is file - they're different
-			result.Status = StatusModified
+		} else if leftInfo.IsDir != rightInfo.IsDir || leftInfo.IsSymlink != rightInfo.IsSymlink {
+			// One is directory or symlink, the other is not - they're different
+			result.Changes |= ChangeType
+		} else if leftInfo.IsSymlink {
+			// Unfollowed symlinks are compared by their target path
+			if leftInfo.LinkTarget != rightInfo.LinkTarget {
+				result.Changes |= ChangeContent
+			}
 		} else {
 			// Both are files - compare content
-			if leftInfo.Hash == rightInfo.Hash && leftInfo.Hash != "ERROR_CALCULATING_HASH" {
-				result.Status = StatusIdentical
-			} else {
-				result.Status = StatusModified
+			if e.options.MetadataOnly {
+				if leftInfo.Size != rightInfo.Size || !e.sameModTime(leftInfo.ModTime, rightInfo.ModTime) {
+					result.Changes |= ChangeContent
+				}
+			} else if leftInfo.Hash != rightInfo.Hash || leftInfo.Hash == "ERROR_CALCULATING_HASH" {
+				leftPath, rightPath := filepath.Join(leftDir, leftInfo.Path), filepath.Join(rightDir, rightInfo.Path)
+				if e.lineEndingsOnly(leftPath, rightPath, leftInfo, rightInfo) {
+					if !e.options.IgnoreLineEndings {
+						result.Changes |= ChangeContent | ChangeLineEndings
+					}
+				} else if !e.normalizedEqual(leftPath, rightPath, leftInfo, rightInfo) {
+					result.Changes |= ChangeContent
+				}
+			}
+			// Permissions are empty when unknown (e.g. zip entries without Unix modes)
+			if !e.options.IgnorePermissions && leftInfo.Permissions != "" && rightInfo.Permissions != "" &&
+				leftInfo.Permissions != rightInfo.Permissions {
+				result.Changes |= ChangePerms
 			}
 		}
+
+		// Only set when paths were matched case-insensitively
+		if leftInfo.Path != rightInfo.Path {
+			result.Changes |= ChangeCase
+		}
+
+		if result.Changes != 0 {
+			result.Status = StatusModified
+		} else {
+			result.Status = StatusIdentical
+		}
+
+		// Times alone don't make entries differ, but are worth reporting
+		if !leftInfo.IsDir && !rightInfo.IsDir && !e.sameModTime(leftInfo.ModTime, rightInfo.ModTime) {
+			result.Changes |= ChangeTime
+		}
 	}
 
 	return result, nil
 }
 
-// calculateHash calculates SHA-256 hash of a file
+// sameModTime reports whether two modification times are equal within
+// TimeToleranceSeconds
+func (e *Engine) sameModTime(left, right time.Time) bool {
+	diff := left.Sub(right)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= time.Duration(e.options.TimeToleranceSeconds)*time.Second
+}
+
+// newFileInfo records an entry found at path, hashing it if it is a file
+// (not a directory or an unfollowed symlink)
+func (e *Engine) newFileInfo(path, relPath, side string, info os.FileInfo, isSymlink bool, linkTarget string) *FileInfo {
+	fileInfo := &FileInfo{
+		Path:        relPath,
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		IsDir:       info.IsDir(),
+		IsSymlink:   isSymlink,
+		LinkTarget:  linkTarget,
+		Permissions: info.Mode().String(),
+	}
+	if e.options.DetectHardlinks && info.Mode().IsRegular() {
+		fileInfo.Inode = inodeKey(fileInode(info))
+	}
+
+	if !info.IsDir() && !isSymlink && e.options.MetadataOnly {
+		fileInfo.Hash = metadataHash(fileInfo.Size, fileInfo.ModTime)
+	} else if !info.IsDir() && !isSymlink {
+		util.VerbosePrintf(e.verboseLevel, 3, "Calculating hash (%s): %s", side, relPath)
+		hash, err := e.calculateHash(path)
+		if err != nil {
+			// Log error but don't fail - we'll mark as different
+			util.VerbosePrintf(e.verboseLevel, 2, "Hash calculation failed (%s): %s - %v", side, relPath, err)
+			fileInfo.Hash = "ERROR_CALCULATING_HASH"
+		} else {
+			fileInfo.Hash = hash
+		}
+	}
+	return fileInfo
+}
+
+// ComparePath compares one entry of two directories as Compare would, e.g.
+// to refresh a single result. leftPath and rightPath only differ when paths
+// were paired case-insensitively. A directory is compared as an entry only,
+// without its contents. Paths missing from both sides are an error.
+func (e *Engine) ComparePath(leftDir, rightDir, leftPath, rightPath string) (ComparisonResult, error) {
+	leftInfo, err := e.statEntry(leftDir, leftPath, "left")
+	if err != nil {
+		return ComparisonResult{}, err
+	}
+	rightInfo, err := e.statEntry(rightDir, rightPath, "right")
+	if err != nil {
+		return ComparisonResult{}, err
+	}
+	relPath := leftPath
+	if leftInfo == nil {
+		relPath = rightPath
+	}
+	return e.compareFile(relPath, leftInfo, rightInfo, leftDir, rightDir)
+}
+
+// statEntry builds the FileInfo of relPath under root, or returns nil if it
+// doesn't exist
+func (e *Engine) statEntry(root, relPath, side string) (*FileInfo, error) {
+	path := filepath.Join(root, relPath)
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	linkTarget := ""
+	isSymlink := info.Mode()&os.ModeSymlink != 0
+	if isSymlink {
+		if linkTarget, err = os.Readlink(path); err != nil {
+			return nil, fmt.Errorf("failed to read symlink (%s) %s: %w", side, relPath, err)
+		}
+		if e.options.FollowSymlinks {
+			if info, err = os.Stat(path); err != nil {
+				return nil, fmt.Errorf("broken symlink (%s) %s -> %s: %w", side, relPath, linkTarget, err)
+			}
+			isSymlink = false
+		}
+	}
+	return e.newFileInfo(path, relPath, side, info, isSymlink, linkTarget), nil
+}
+
+// addParentDirs adds the ancestor directories of every collected entry to
+// the scan, so a directory holding a listed file exists on this side too
+func (e *Engine) addParentDirs(scan *fileScan, root string) {
+	paths := make([]string, 0, len(scan.files))
+	for relPath := range scan.files {
+		paths = append(paths, relPath)
+	}
+	for _, relPath := range paths {
+		for dir := filepath.Dir(relPath); dir != "."; dir = filepath.Dir(dir) {
+			if _, ok := scan.files[dir]; ok {
+				continue
+			}
+			fileInfo, err := e.statEntry(root, dir, scan.side)
+			if err != nil || fileInfo == nil || !fileInfo.IsDir {
+				break
+			}
+			scan.files[dir] = fileInfo
+		}
+	}
+}
+
+// calculateHash calculates the content hash of a file using the configured algorithm
 func (e *Engine) calculateHash(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -244,22 +769,61 @@ func (e *Engine) calculateHash(filePath string) (string, error) {
 	}
 	defer file.Close()
 
-	// Check file size limit
-	if e.options.MaxFileSize > 0 {
-		if info, err := file.Stat(); err == nil && info.Size() > e.options.MaxFileSize {
-			// For very large files, just use size + modtime as "hash"
-			return fmt.Sprintf("LARGE_FILE_%d_%d", info.Size(), info.ModTime().Unix()), nil
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// Files over the size limit get a placeholder that isn't worth caching,
+	// and sampled hashes are cheap and must not stand in for full ones
+	cacheable := e.hashCache != nil && (e.options.MaxFileSize <= 0 || info.Size() <= e.options.MaxFileSize) &&
+		!e.sampled(info.Size())
+	var stamp fileStamp
+	if cacheable {
+		stamp, cacheable = newFileStamp(info)
+	}
+	if cacheable {
+		if hash, ok := e.hashCache.lookup(e.options.HashAlgorithm, filePath, stamp); ok {
+			e.cacheHits.Add(1)
+			return hash, nil
 		}
 	}
 
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	hash, err := e.hashContent(file, info.Size(), info.ModTime())
+	if err == nil && cacheable {
+		e.hashCache.store(e.options.HashAlgorithm, filePath, stamp, hash)
+	}
+	return hash, err
+}
+
+// hashContent hashes content of the given size and modification time
+func (e *Engine) hashContent(content io.Reader, size int64, modTime time.Time) (string, error) {
+	if e.sampled(size) {
+		return e.sampledHash(content, size)
+	}
+
+	// Check file size limit
+	if e.options.MaxFileSize > 0 && size > e.options.MaxFileSize {
+		// For very large files, just use size + modtime as "hash"
+		return fmt.Sprintf("LARGE_FILE_%d_%d", size, modTime.Unix()), nil
+	}
+
+	hash := newHasher(e.options.HashAlgorithm)
+	n, err := io.Copy(hash, content)
+	e.hashedBytes.Add(n)
+	if err != nil {
 		return "", err
 	}
 
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
 
+// metadataHash stands in for a content hash when files are compared by
+// metadata only, so entries still carry a value that changes with the file
+func metadataHash(size int64, modTime time.Time) string {
+	return fmt.Sprintf("METADATA_%d_%d", size, modTime.UnixNano())
+}
+
 // updateSummary updates the comparison summary with a result
 func (e *Engine) updateSummary(summary *ComparisonSummary, result ComparisonResult) {
 	if result.LeftInfo != nil && result.LeftInfo.IsDir {
@@ -278,15 +842,28 @@ func (e *Engine) updateSummary(summary *ComparisonSummary, result ComparisonResu
 	} else {
 		// It's a file
 		summary.TotalFiles++
+		if result.LeftInfo != nil && IsPatchFile(result.LeftInfo.Path) {
+			summary.DetectedPatchFiles = append(summary.DetectedPatchFiles, PatchFile{Side: "left", Path: result.LeftInfo.Path})
+		}
+		if result.RightInfo != nil && IsPatchFile(result.RightInfo.Path) {
+			summary.DetectedPatchFiles = append(summary.DetectedPatchFiles, PatchFile{Side: "right", Path: result.RightInfo.Path})
+		}
 		switch result.Status {
 		case StatusIdentical:
 			summary.IdenticalFiles++
 		case StatusModified:
 			summary.ModifiedFiles++
+			if result.LeftInfo != nil && result.RightInfo != nil {
+				summary.ModifiedBytesDelta += result.LeftInfo.Size - result.RightInfo.Size
+			}
 		case StatusOnlyLeft:
 			summary.OnlyLeftFiles++
+			summary.OnlyLeftBytes += result.LeftInfo.Size
 		case StatusOnlyRight:
 			summary.OnlyRightFiles++
+			summary.OnlyRightBytes += result.RightInfo.Size
+		case StatusRenamed:
+			summary.RenamedFiles++
 		}
 	}
 }
@@ -0,0 +1,103 @@
+package compare
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// exportHeader is the fixed column order of WriteResultsTable
+var exportHeader = []string{
+	"path", "status", "left_size", "right_size", "size_comparison",
+	"time_comparison", "left_hash", "right_hash", "comparison_method",
+}
+
+func (m ComparisonMethod) String() string {
+	switch m {
+	case ComparisonMetadata:
+		return "metadata"
+	case ComparisonSampled:
+		return "sampled"
+	default:
+		return "hash"
+	}
+}
+
+// WriteResultsTable writes one row per result, sorted by path, as CSV or, with
+// a tab delimiter, TSV. Identical entries are written only if includeIdentical
+// is set. Columns describing a side are empty when that side is missing.
+func WriteResultsTable(w io.Writer, results []ComparisonResult, delimiter rune, includeIdentical bool) error {
+	sorted := make([]ComparisonResult, 0, len(results))
+	for _, result := range results {
+		if includeIdentical || result.Status != StatusIdentical {
+			sorted = append(sorted, result)
+		}
+	}
+	SortResults(sorted)
+
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	if err := writer.Write(exportHeader); err != nil {
+		return err
+	}
+	for _, result := range sorted {
+		leftSize, leftHash := exportSide(result.LeftInfo)
+		rightSize, rightHash := exportSide(result.RightInfo)
+		sizeComparison, timeComparison := compareSides(result)
+		row := []string{
+			result.RelativePath,
+			result.Status.String(),
+			leftSize,
+			rightSize,
+			sizeComparison,
+			timeComparison,
+			leftHash,
+			rightHash,
+			result.Method.String(),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportSide returns the size and hash columns for one side; directories
+// have neither
+func exportSide(info *FileInfo) (size, hash string) {
+	if info == nil || info.IsDir {
+		return "", ""
+	}
+	return strconv.FormatInt(info.Size, 10), info.Hash
+}
+
+// compareSides describes which side is larger and which is newer, when both
+// sides are files
+func compareSides(result ComparisonResult) (size, time string) {
+	left, right := result.LeftInfo, result.RightInfo
+	if left == nil || right == nil || left.IsDir || right.IsDir {
+		return "", ""
+	}
+
+	switch {
+	case left.Size > right.Size:
+		size = "left_larger"
+	case left.Size < right.Size:
+		size = "right_larger"
+	default:
+		size = "same"
+	}
+	// ChangeTime is unset for times within TimeToleranceSeconds
+	switch {
+	case !result.Changes.Has(ChangeTime):
+		time = "same"
+	case left.ModTime.After(right.ModTime):
+		time = "left_newer"
+	case right.ModTime.After(left.ModTime):
+		time = "right_newer"
+	default:
+		time = "same"
+	}
+	return size, time
+}
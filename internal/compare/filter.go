@@ -1,9 +1,15 @@
 package compare
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+
+	"github.com/harikb/dovetail/internal/config"
 )
 
 // Filter handles file and directory filtering during comparison
@@ -11,19 +17,173 @@ type Filter struct {
 	excludeNames      []string
 	excludePaths      []string
 	excludeExtensions []string
+	excludeRegex      []*regexp.Regexp
+	gitignoreRules    []ignoreRule
+	contentRegex      []*regexp.Regexp
+	includePaths      []string // Normalized, without trailing slashes
+	minSize           int64    // Files smaller than this are excluded
+	maxSize           int64    // Files larger than this are excluded (0 = no limit)
 }
 
-// NewFilter creates a new filter with the given options
-func NewFilter(options ComparisonOptions) *Filter {
-	return &Filter{
+// NewFilter creates a new filter with the given options. It returns an error
+// if one of the regular expressions is invalid.
+func NewFilter(options ComparisonOptions) (*Filter, error) {
+	filter := &Filter{
 		excludeNames:      options.ExcludeNames,
 		excludePaths:      options.ExcludePaths,
 		excludeExtensions: options.ExcludeExtensions,
+		minSize:           options.MinFileSize,
+		maxSize:           options.MaxCompareSize,
+	}
+	var err error
+	if filter.excludeRegex, err = compilePatterns("exclude regex", options.ExcludeRegex); err != nil {
+		return nil, err
+	}
+	if filter.gitignoreRules, err = compileRules(options.GitignoreRules); err != nil {
+		return nil, err
+	}
+	if filter.contentRegex, err = compilePatterns("exclude content regex", options.ExcludeContentRegex); err != nil {
+		return nil, err
+	}
+	for _, includePath := range options.IncludePaths {
+		normalized := strings.Trim(filepath.ToSlash(filepath.Clean(includePath)), "/")
+		if normalized == "" || normalized == "." {
+			// Including the root includes everything
+			filter.includePaths = nil
+			break
+		}
+		filter.includePaths = append(filter.includePaths, normalized)
+	}
+	return filter, nil
+}
+
+// compilePatterns compiles regular expressions; kind names them in the error
+// for an invalid one
+func compilePatterns(kind string, patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", kind, pattern, err)
+		}
+		compiled = append(compiled, re)
 	}
+	return compiled, nil
+}
+
+// ignoreRule is a compiled config.IgnoreRule
+type ignoreRule struct {
+	re     *regexp.Regexp
+	negate bool
 }
 
-// ShouldExclude determines if a file or directory should be excluded from comparison
+// compileRules compiles ignore rules in order
+func compileRules(rules []config.IgnoreRule) ([]ignoreRule, error) {
+	var compiled []ignoreRule
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid .gitignore rule %q: %w", rule.Regex, err)
+		}
+		compiled = append(compiled, ignoreRule{re: re, negate: rule.Negate})
+	}
+	return compiled, nil
+}
+
+// matchRules evaluates ignore rules in order against relPath. The last
+// matching rule decides, as in git; matched is false when none matches.
+func matchRules(rules []ignoreRule, relPath string) (matched, excluded bool) {
+	normalizedPath := filepath.ToSlash(relPath)
+	for _, rule := range rules {
+		if rule.re.MatchString(normalizedPath) {
+			matched, excluded = true, !rule.negate
+		}
+	}
+	return matched, excluded
+}
+
+// ShouldExclude determines if a file or directory should be excluded from comparison.
+// Paths outside the include list or the size range are always excluded.
+// Otherwise a path is excluded by the exclusion options or by the gitignore
+// rules, where a later negation overrides an earlier gitignore rule only.
 func (f *Filter) ShouldExclude(relPath string, info os.FileInfo) bool {
+	if !f.isIncluded(relPath, info) || !f.inSizeRange(info) {
+		return true
+	}
+	if f.matchesExclusion(relPath, info) {
+		return true
+	}
+	_, excluded := matchRules(f.gitignoreRules, relPath)
+	return excluded
+}
+
+// contentPeekSize is how much of a file ExcludeContentRegex patterns see
+const contentPeekSize = 4096
+
+// ExcludesContent reports whether the start of the regular file at path
+// matches an ExcludeContentRegex pattern. Only the first contentPeekSize bytes
+// are read, and files that look binary never match.
+func (f *Filter) ExcludesContent(path string, info os.FileInfo) bool {
+	if len(f.contentRegex) == 0 || !info.Mode().IsRegular() || info.Size() == 0 {
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false // Unreadable files are reported when they are hashed
+	}
+	defer file.Close()
+
+	peek := make([]byte, contentPeekSize)
+	n, err := io.ReadFull(file, peek)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	peek = peek[:n]
+	if bytes.IndexByte(peek, 0) >= 0 {
+		return false
+	}
+
+	for _, re := range f.contentRegex {
+		if re.Match(peek) {
+			return true
+		}
+	}
+	return false
+}
+
+// inSizeRange checks a regular file's size against MinFileSize and
+// MaxCompareSize. Directories, symlinks and other entries are always in range.
+func (f *Filter) inSizeRange(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return true
+	}
+	size := info.Size()
+	return size >= f.minSize && (f.maxSize <= 0 || size <= f.maxSize)
+}
+
+// isIncluded checks a path against the include list. With no include list
+// everything is included. Directories leading to an included path are
+// included so the walk can reach it.
+func (f *Filter) isIncluded(relPath string, info os.FileInfo) bool {
+	if len(f.includePaths) == 0 {
+		return true
+	}
+
+	normalizedPath := filepath.ToSlash(relPath)
+	for _, includePath := range f.includePaths {
+		if normalizedPath == includePath || strings.HasPrefix(normalizedPath, includePath+"/") {
+			return true
+		}
+		if info.IsDir() && strings.HasPrefix(includePath, normalizedPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesExclusion checks a path against all exclusion rules
+func (f *Filter) matchesExclusion(relPath string, info os.FileInfo) bool {
 	// Check by name/glob patterns
 	if f.matchesExcludeName(filepath.Base(relPath)) {
 		return true
@@ -34,6 +194,11 @@ func (f *Filter) ShouldExclude(relPath string, info os.FileInfo) bool {
 		return true
 	}
 
+	// Check by regular expression against the relative path
+	if matchesAnyRegex(f.excludeRegex, relPath) {
+		return true
+	}
+
 	// Check by extension (only for files)
 	if !info.IsDir() && f.matchesExcludeExtension(relPath) {
 		return true
@@ -104,6 +269,17 @@ func (f *Filter) matchesExcludePath(relPath string) bool {
 	return false
 }
 
+// matchesAnyRegex checks if a relative path matches any of the regular expressions
+func matchesAnyRegex(patterns []*regexp.Regexp, relPath string) bool {
+	normalizedPath := filepath.ToSlash(relPath)
+	for _, re := range patterns {
+		if re.MatchString(normalizedPath) {
+			return true
+		}
+	}
+	return false
+}
+
 // matchesExcludeExtension checks if a file extension matches any exclude extensions
 func (f *Filter) matchesExcludeExtension(relPath string) bool {
 	if len(f.excludeExtensions) == 0 {
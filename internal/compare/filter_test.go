@@ -0,0 +1,207 @@
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harikb/dovetail/internal/config"
+)
+
+// nodeGitignore is an excerpt of GitHub's Node.gitignore template
+const nodeGitignore = `# Logs
+logs
+*.log
+npm-debug.log*
+yarn-debug.log*
+yarn-error.log*
+
+# Diagnostic reports (https://nodejs.org/api/report.html)
+report.[0-9]*.[0-9]*.[0-9]*.[0-9]*.json
+
+# Runtime data
+pids
+*.pid
+*.seed
+*.pid.lock
+
+# Coverage directory used by tools like istanbul
+coverage
+*.lcov
+
+# Dependency directories
+node_modules/
+jspm_packages/
+
+# dotenv environment variable files
+.env
+.env.local
+
+# Next.js build output
+.next
+out
+
+# yarn v2
+.yarn/cache
+.yarn/unplugged
+.yarn/build-state.yml
+.pnp.*
+`
+
+// goGitignore is GitHub's Go.gitignore template
+const goGitignore = `# Binaries for programs and plugins
+*.exe
+*.exe~
+*.dll
+*.so
+*.dylib
+
+# Test binary, built with ` + "`go test -c`" + `
+*.test
+
+# Output of the go coverage tool, specifically when used with LiteIDE
+*.out
+
+# Dependency directories (remove the comment below to include it)
+# vendor/
+
+# Go workspace file
+go.work
+go.work.sum
+
+# env file
+.env
+`
+
+// fakeInfo is the os.FileInfo of a small regular file or a directory
+type fakeInfo struct {
+	name string
+	dir  bool
+}
+
+func (i fakeInfo) Name() string       { return i.name }
+func (i fakeInfo) Size() int64        { return 1 }
+func (i fakeInfo) ModTime() time.Time { return time.Time{} }
+func (i fakeInfo) IsDir() bool        { return i.dir }
+func (i fakeInfo) Sys() interface{}   { return nil }
+func (i fakeInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// gitignoreFilter writes content as the .gitignore of a new directory and
+// returns a filter using its rules on top of options
+func gitignoreFilter(t *testing.T, content string, options ComparisonOptions) *Filter {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := config.NewGitignoreParser(0).ParseGitignoreFiles(dir, dir, false)
+	if err != nil {
+		t.Fatalf("ParseGitignoreFiles: %v", err)
+	}
+	options.GitignoreRules = result.Rules
+	filter, err := NewFilter(options)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	return filter
+}
+
+func TestShouldExcludeGitignore(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		path    string
+		dir     bool
+		want    bool
+	}{
+		{"node log", nodeGitignore, "server.log", false, true},
+		{"node nested log", nodeGitignore, "src/api/debug.log", false, true},
+		{"node npm debug log", nodeGitignore, "npm-debug.log.1234", false, true},
+		{"node report", nodeGitignore, "report.20240101.101010.1234.0.json", false, true},
+		{"node other json", nodeGitignore, "package.json", false, false},
+		{"node modules", nodeGitignore, "node_modules", true, true},
+		{"node nested modules", nodeGitignore, "packages/app/node_modules", true, true},
+		{"node coverage", nodeGitignore, "coverage", true, true},
+		{"node env", nodeGitignore, ".env", false, true},
+		{"node env example", nodeGitignore, ".env.example", false, false},
+		{"node yarn cache", nodeGitignore, ".yarn/cache", true, true},
+		{"node yarn releases", nodeGitignore, ".yarn/releases", true, false},
+		{"node yarn cache is root relative", nodeGitignore, "sub/.yarn/cache", true, false},
+		{"node pnp", nodeGitignore, ".pnp.cjs", false, true},
+		{"node source", nodeGitignore, "src/index.js", false, false},
+		{"go binary", goGitignore, "cmd/tool/tool.exe", false, true},
+		{"go test binary", goGitignore, "pkg.test", false, true},
+		{"go coverage", goGitignore, "cover.out", false, true},
+		{"go workspace", goGitignore, "go.work.sum", false, true},
+		{"go module", goGitignore, "go.mod", false, false},
+		{"go vendor is commented out", goGitignore, "vendor", true, false},
+		{"negation after exclusion", nodeGitignore + "!keep.log\n", "keep.log", false, false},
+		{"negation only re-includes its path", nodeGitignore + "!keep.log\n", "other.log", false, true},
+		{"exclusion after negation", "!keep.log\n*.log\n", "keep.log", false, true},
+		{"last of several rules wins", goGitignore + "!*.out\ncover*.out\n", "cover.out", false, true},
+		{"re-included by a later rule", goGitignore + "!*.out\ncover*.out\n", "trace.out", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := gitignoreFilter(t, tt.content, ComparisonOptions{})
+			info := fakeInfo{name: filepath.Base(tt.path), dir: tt.dir}
+			if got := filter.ShouldExclude(tt.path, info); got != tt.want {
+				t.Errorf("ShouldExclude(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldExcludeGitignoreNegationKeepsExplicitExclusions(t *testing.T) {
+	content := nodeGitignore + "!keep.log\n!dist/\n"
+	tests := []struct {
+		name    string
+		options ComparisonOptions
+		path    string
+		dir     bool
+		want    bool
+	}{
+		{"exclude name", ComparisonOptions{ExcludeNames: []string{"keep.log"}}, "keep.log", false, true},
+		{"exclude glob", ComparisonOptions{ExcludeNames: []string{"*.log"}}, "keep.log", false, true},
+		{"exclude path", ComparisonOptions{ExcludePaths: []string{"dist/"}}, "dist", true, true},
+		{"exclude extension", ComparisonOptions{ExcludeExtensions: []string{"log"}}, "keep.log", false, true},
+		{"exclude regex", ComparisonOptions{ExcludeRegex: []string{`^keep\.log$`}}, "keep.log", false, true},
+		{"no explicit exclusion", ComparisonOptions{}, "keep.log", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := gitignoreFilter(t, content, tt.options)
+			info := fakeInfo{name: filepath.Base(tt.path), dir: tt.dir}
+			if got := filter.ShouldExclude(tt.path, info); got != tt.want {
+				t.Errorf("ShouldExclude(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilterRejectsInvalidRegex(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ComparisonOptions
+	}{
+		{"exclude regex", ComparisonOptions{ExcludeRegex: []string{`^ok$`, `(`}}},
+		{"exclude content regex", ComparisonOptions{ExcludeContentRegex: []string{`[`}}},
+		{"gitignore rule", ComparisonOptions{GitignoreRules: []config.IgnoreRule{{Regex: `(`}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFilter(tt.options); err == nil {
+				t.Errorf("NewFilter succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package compare
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// HardlinkDifference is a group of paths that share an inode on one side but
+// are not linked together the same way on the other
+type HardlinkDifference struct {
+	Side  string   // "left" or "right": the side where the paths share an inode
+	Paths []string // The paths sharing the inode, sorted
+}
+
+// hardlinkGroups groups the files of one side that share an inode, mapping
+// each linked path to its sorted group. Files without other links are left out.
+func hardlinkGroups(files map[string]*FileInfo) map[string][]string {
+	byInode := make(map[string][]string)
+	for relPath, fileInfo := range files {
+		if fileInfo.Inode != "" {
+			byInode[fileInfo.Inode] = append(byInode[fileInfo.Inode], relPath)
+		}
+	}
+
+	groups := make(map[string][]string)
+	for _, paths := range byInode {
+		if len(paths) < 2 {
+			continue // Its other links are outside the compared tree
+		}
+		sort.Strings(paths)
+		for _, relPath := range paths {
+			groups[relPath] = paths
+		}
+	}
+	return groups
+}
+
+// hardlinkDifferences reports the link groups of each side that the other
+// side doesn't have with exactly the same paths, sorted by side and first path
+func hardlinkDifferences(leftFiles, rightFiles map[string]*FileInfo) []HardlinkDifference {
+	leftGroups, rightGroups := hardlinkGroups(leftFiles), hardlinkGroups(rightFiles)
+
+	var differences []HardlinkDifference
+	for _, side := range []struct {
+		name          string
+		groups, other map[string][]string
+	}{{"left", leftGroups, rightGroups}, {"right", rightGroups, leftGroups}} {
+		seen := make(map[string]bool)
+		for relPath, paths := range side.groups {
+			if seen[paths[0]] {
+				continue
+			}
+			seen[paths[0]] = true
+			if !slices.Equal(paths, side.other[relPath]) {
+				differences = append(differences, HardlinkDifference{Side: side.name, Paths: paths})
+			}
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		a, b := differences[i], differences[j]
+		if a.Side != b.Side {
+			return a.Side == "left"
+		}
+		return a.Paths[0] < b.Paths[0]
+	})
+	return differences
+}
+
+// inodeKey identifies a file's inode for hardlink detection, or returns ""
+// when it has no other links or the platform doesn't expose inodes
+func inodeKey(dev, ino, links uint64, ok bool) string {
+	if !ok || links < 2 {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", dev, ino)
+}
@@ -0,0 +1,55 @@
+package compare
+
+import (
+	"crypto/sha256"
+	"hash"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/blake3"
+)
+
+// Supported hash algorithms for file content comparison
+const (
+	HashSHA256 = "sha256"
+	HashBLAKE3 = "blake3"
+	HashXXHash = "xxhash"
+)
+
+// DefaultHashAlgorithm is used when no algorithm is specified or an unknown one is requested
+const DefaultHashAlgorithm = HashSHA256
+
+// SupportedHashAlgorithms lists the accepted values for ComparisonOptions.HashAlgorithm
+var SupportedHashAlgorithms = []string{HashSHA256, HashBLAKE3, HashXXHash}
+
+// IsSupportedHashAlgorithm reports whether the given algorithm name is supported
+func IsSupportedHashAlgorithm(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, algo := range SupportedHashAlgorithms {
+		if name == algo {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHashAlgorithm returns a supported algorithm name, falling back to the default
+func normalizeHashAlgorithm(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if IsSupportedHashAlgorithm(name) {
+		return name
+	}
+	return DefaultHashAlgorithm
+}
+
+// newHasher creates a hash.Hash for the given (normalized) algorithm name
+func newHasher(name string) hash.Hash {
+	switch name {
+	case HashBLAKE3:
+		return blake3.New()
+	case HashXXHash:
+		return xxhash.New()
+	default:
+		return sha256.New()
+	}
+}
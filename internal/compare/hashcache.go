@@ -0,0 +1,156 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// hashCacheFile is the name of the cache file inside the cache directory
+const hashCacheFile = "hashes.json"
+
+// hashCacheMaxAge is how long an entry is kept without being used, so paths
+// that are gone or no longer compared don't accumulate forever
+const hashCacheMaxAge = 30 * 24 * time.Hour
+
+// fileStamp identifies one version of a file. The mtime can be set back after
+// a write (touch -d, tar, rsync), so the inode change time, which can't, and
+// the inode, which changes when a file is replaced, are part of it too.
+type fileStamp struct {
+	Size       int64  `json:"size"`
+	ModTime    int64  `json:"mtime"` // Unix nanoseconds
+	ChangeTime int64  `json:"ctime"` // Unix nanoseconds
+	Inode      uint64 `json:"inode"`
+}
+
+// newFileStamp returns the stamp of a file, or false on platforms without
+// inode change times, where a cached hash could not be trusted
+func newFileStamp(info os.FileInfo) (fileStamp, bool) {
+	changeTime, ok := fileChangeTime(info)
+	if !ok {
+		return fileStamp{}, false
+	}
+	_, ino, _, _ := fileInode(info)
+	return fileStamp{Size: info.Size(), ModTime: info.ModTime().UnixNano(), ChangeTime: changeTime, Inode: ino}, true
+}
+
+// hashCacheEntry is a file's hash as of a given stamp
+type hashCacheEntry struct {
+	fileStamp
+	Hash string `json:"hash"`
+	Used int64  `json:"used"` // Unix seconds when last looked up or stored
+}
+
+// HashCache remembers content hashes across runs, keyed by hash algorithm and
+// path. An entry is only used while the file's stamp is unchanged, and is
+// dropped after hashCacheMaxAge without use.
+type HashCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]hashCacheEntry
+	dirty   bool // Entries changed since loading
+}
+
+// DefaultHashCacheDir returns the directory the hash cache lives in when
+// performance.hash_cache_dir isn't configured
+func DefaultHashCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dovetail"), nil
+}
+
+// LoadHashCache reads the hash cache in dir. A missing or unreadable cache
+// starts out empty; it is only an optimization.
+func LoadHashCache(dir string) *HashCache {
+	cache := &HashCache{
+		path:    filepath.Join(dir, hashCacheFile),
+		entries: make(map[string]hashCacheEntry),
+	}
+	if data, err := os.ReadFile(cache.path); err == nil {
+		if err := json.Unmarshal(data, &cache.entries); err != nil {
+			cache.entries = make(map[string]hashCacheEntry)
+		}
+	}
+
+	cutoff := time.Now().Add(-hashCacheMaxAge).Unix()
+	for key, entry := range cache.entries {
+		if entry.Used < cutoff {
+			delete(cache.entries, key)
+			cache.dirty = true
+		}
+	}
+	return cache
+}
+
+// Len returns the number of cached hashes
+func (c *HashCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// lookup returns the cached hash of path if its stamp still matches
+func (c *HashCache) lookup(algorithm, path string, stamp fileStamp) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := hashCacheKey(algorithm, path)
+	entry, ok := c.entries[key]
+	if !ok || entry.fileStamp != stamp {
+		return "", false
+	}
+	// Refreshed at most daily, so runs that only read the cache rarely rewrite it
+	if time.Since(time.Unix(entry.Used, 0)) > 24*time.Hour {
+		entry.Used = time.Now().Unix()
+		c.entries[key] = entry
+		c.dirty = true
+	}
+	return entry.Hash, true
+}
+
+// store records the hash of path at the given stamp
+func (c *HashCache) store(algorithm, path string, stamp fileStamp, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[hashCacheKey(algorithm, path)] = hashCacheEntry{fileStamp: stamp, Hash: hash, Used: time.Now().Unix()}
+	c.dirty = true
+}
+
+// Save writes the cache back to disk if it changed. The file is replaced
+// atomically so a concurrent run never reads a partial cache.
+func (c *HashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode hash cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create hash cache directory: %w", err)
+	}
+	err = util.WriteFileAtomic(c.path, 0644, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write hash cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// hashCacheKey keeps hashes from different algorithms apart
+func hashCacheKey(algorithm, path string) string {
+	return algorithm + ":" + path
+}
@@ -0,0 +1,96 @@
+package compare
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// ignoreFile holds the patterns of one .dovetailignore, which apply to the
+// subtree of the directory holding it
+type ignoreFile struct {
+	dir   string // Slash-separated relative directory ("" for the root)
+	rules []ignoreRule
+}
+
+// decides reports whether the file has a say about relPath and, if so,
+// whether it excludes it. The last matching pattern in the file decides.
+func (f ignoreFile) decides(relPath string) (applies, excluded bool) {
+	if f.dir != "" {
+		if !strings.HasPrefix(relPath, f.dir+"/") {
+			return false, false
+		}
+		relPath = relPath[len(f.dir)+1:]
+	}
+	return matchRules(f.rules, relPath)
+}
+
+// ignoredByFile reports whether the .dovetailignore files loaded so far
+// exclude relPath. Files deeper in the tree override those above them.
+func (e *Engine) ignoredByFile(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	excluded := false
+	for _, file := range e.ignoreFiles {
+		if applies, ex := file.decides(relPath); applies {
+			excluded = ex
+		}
+	}
+	return excluded
+}
+
+// loadIgnoreFiles reads the .dovetailignore in relDir under each compared
+// directory, so that walking one side already honors the ignore files of the
+// other and a file ignored on one side isn't reported as only on the other.
+// Files are kept ordered by depth, so parents come before subdirectories.
+func (e *Engine) loadIgnoreFiles(scan *fileScan, relDir string) {
+	if e.options.NoIgnoreFiles {
+		return
+	}
+	for _, root := range e.ignoreRoots {
+		path := filepath.Join(root, relDir, config.IgnoreFileName)
+		if e.ignoreLoaded[path] {
+			continue
+		}
+		e.ignoreLoaded[path] = true
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		rules, err := config.NewGitignoreParser(e.verboseLevel).ParseIgnoreFile(path)
+		if err != nil {
+			scan.errors = append(scan.errors, fmt.Sprintf("failed to read ignore file %s: %v", path, err))
+			continue
+		}
+		util.VerbosePrintf(e.verboseLevel, 2, "Applying %s: %s", config.IgnoreFileName, path)
+
+		compiled, err := compileRules(rules)
+		if err != nil {
+			scan.errors = append(scan.errors, fmt.Sprintf("failed to read ignore file %s: %v", path, err))
+			continue
+		}
+		dir := filepath.ToSlash(relDir)
+		if dir == "." {
+			dir = ""
+		}
+		e.ignoreFiles = append(e.ignoreFiles, ignoreFile{
+			dir:   dir,
+			rules: compiled,
+		})
+	}
+	sort.SliceStable(e.ignoreFiles, func(i, j int) bool {
+		return ignoreDepth(e.ignoreFiles[i].dir) < ignoreDepth(e.ignoreFiles[j].dir)
+	})
+}
+
+// ignoreDepth returns how many directories deep an ignore file's directory is
+func ignoreDepth(dir string) int {
+	if dir == "" {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
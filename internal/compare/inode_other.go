@@ -0,0 +1,11 @@
+//go:build !unix
+
+package compare
+
+import "os"
+
+// fileInode returns the device and inode of a file and its hardlink count,
+// if available on this platform
+func fileInode(info os.FileInfo) (dev, ino, links uint64, ok bool) {
+	return 0, 0, 0, false
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package compare
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the device and inode of a file and its hardlink count,
+// if available on this platform
+func fileInode(info os.FileInfo) (dev, ino, links uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	return uint64(stat.Dev), uint64(stat.Ino), uint64(stat.Nlink), true
+}
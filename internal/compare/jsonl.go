@@ -0,0 +1,144 @@
+package compare
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// jsonSide describes one side of a result in JSON lines output
+type jsonSide struct {
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mtime"`
+	IsDir       bool      `json:"is_dir,omitempty"`
+	IsSymlink   bool      `json:"is_symlink,omitempty"`
+	LinkTarget  string    `json:"link_target,omitempty"`
+	Hash        string    `json:"hash,omitempty"`
+	Permissions string    `json:"permissions,omitempty"`
+}
+
+// jsonResult is the line written for each compared path
+type jsonResult struct {
+	Type    string    `json:"type"` // Always "result"
+	Path    string    `json:"path"`
+	NewPath string    `json:"new_path,omitempty"` // Right path of a renamed entry
+	Status  string    `json:"status"`
+	Changes []string  `json:"changes,omitempty"`
+	Method  string    `json:"method"`
+	Left    *jsonSide `json:"left,omitempty"`
+	Right   *jsonSide `json:"right,omitempty"`
+}
+
+// jsonSummary is the last line, written once the comparison is complete
+type jsonSummary struct {
+	Type               string   `json:"type"` // Always "summary"
+	TotalFiles         int      `json:"total_files"`
+	IdenticalFiles     int      `json:"identical_files"`
+	ModifiedFiles      int      `json:"modified_files"`
+	OnlyLeftFiles      int      `json:"only_left_files"`
+	OnlyRightFiles     int      `json:"only_right_files"`
+	TotalDirs          int      `json:"total_dirs"`
+	IdenticalDirs      int      `json:"identical_dirs"`
+	OnlyLeftDirs       int      `json:"only_left_dirs"`
+	OnlyRightDirs      int      `json:"only_right_dirs"`
+	OnlyLeftBytes      int64    `json:"only_left_bytes"`
+	OnlyRightBytes     int64    `json:"only_right_bytes"`
+	ModifiedBytesDelta int64    `json:"modified_bytes_delta"`
+	HashAlgorithm      string   `json:"hash_algorithm"`
+	Errors             []string `json:"errors,omitempty"`
+	MissingPaths       []string `json:"missing_paths,omitempty"`
+	SkippedPaths       int      `json:"skipped_paths"`
+}
+
+// JSONLinesWriter writes comparison results as JSON lines, one object per
+// result as it arrives and a summary object last, so any number of results
+// can be written without holding them in memory
+type JSONLinesWriter struct {
+	buffer           *bufio.Writer
+	encoder          *json.Encoder
+	includeIdentical bool
+	err              error // First write error; later writes are skipped
+}
+
+// NewJSONLinesWriter returns a writer to w. Identical entries are written
+// only if includeIdentical is set.
+func NewJSONLinesWriter(w io.Writer, includeIdentical bool) *JSONLinesWriter {
+	buffer := bufio.NewWriter(w)
+	return &JSONLinesWriter{
+		buffer:           buffer,
+		encoder:          json.NewEncoder(buffer),
+		includeIdentical: includeIdentical,
+	}
+}
+
+// WriteResult writes one result line. It fits CompareStream's callback, so
+// errors are kept and returned by WriteSummary.
+func (w *JSONLinesWriter) WriteResult(result ComparisonResult) {
+	if w.err != nil || (!w.includeIdentical && result.Status == StatusIdentical) {
+		return
+	}
+	line := jsonResult{
+		Type:   "result",
+		Path:   result.RelativePath,
+		Status: result.Status.String(),
+		Method: result.Method.String(),
+		Left:   newJSONSide(result.LeftInfo),
+		Right:  newJSONSide(result.RightInfo),
+	}
+	if result.Status == StatusRenamed {
+		line.NewPath = result.RightPath()
+	}
+	if result.Changes != 0 {
+		line.Changes = strings.Split(result.Changes.String(), ",")
+	}
+	w.err = w.encoder.Encode(line)
+}
+
+// WriteSummary writes the summary line and flushes the output. It returns
+// the first error from any write.
+func (w *JSONLinesWriter) WriteSummary(summary *ComparisonSummary) error {
+	if w.err != nil {
+		return w.err
+	}
+	line := jsonSummary{
+		Type:               "summary",
+		TotalFiles:         summary.TotalFiles,
+		IdenticalFiles:     summary.IdenticalFiles,
+		ModifiedFiles:      summary.ModifiedFiles,
+		OnlyLeftFiles:      summary.OnlyLeftFiles,
+		OnlyRightFiles:     summary.OnlyRightFiles,
+		TotalDirs:          summary.TotalDirs,
+		IdenticalDirs:      summary.IdenticalDirs,
+		OnlyLeftDirs:       summary.OnlyLeftDirs,
+		OnlyRightDirs:      summary.OnlyRightDirs,
+		OnlyLeftBytes:      summary.OnlyLeftBytes,
+		OnlyRightBytes:     summary.OnlyRightBytes,
+		ModifiedBytesDelta: summary.ModifiedBytesDelta,
+		HashAlgorithm:      summary.HashAlgorithm,
+		Errors:             summary.ErrorsEncountered,
+		MissingPaths:       summary.MissingPaths,
+		SkippedPaths:       len(summary.SkippedPaths),
+	}
+	if err := w.encoder.Encode(line); err != nil {
+		return err
+	}
+	return w.buffer.Flush()
+}
+
+// newJSONSide returns the JSON description of one side, nil if it is missing
+func newJSONSide(info *FileInfo) *jsonSide {
+	if info == nil {
+		return nil
+	}
+	return &jsonSide{
+		Size:        info.Size,
+		ModTime:     info.ModTime,
+		IsDir:       info.IsDir,
+		IsSymlink:   info.IsSymlink,
+		LinkTarget:  info.LinkTarget,
+		Hash:        info.Hash,
+		Permissions: info.Permissions,
+	}
+}
@@ -0,0 +1,60 @@
+package compare
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// lineEndingsOnly reports whether two files with different hashes hold the
+// same text once CRLF and lone CR line endings are read as LF. Files over
+// MaxFileSize, files that can't be opened (such as archive entries) and
+// binary content are never reported.
+func (e *Engine) lineEndingsOnly(leftPath, rightPath string, leftInfo, rightInfo *FileInfo) bool {
+	if leftInfo.Hash == "ERROR_CALCULATING_HASH" || rightInfo.Hash == "ERROR_CALCULATING_HASH" {
+		return false
+	}
+	if e.options.MaxFileSize > 0 && (leftInfo.Size > e.options.MaxFileSize || rightInfo.Size > e.options.MaxFileSize) {
+		return false
+	}
+
+	left, err := os.Open(leftPath)
+	if err != nil {
+		return false
+	}
+	defer left.Close()
+	right, err := os.Open(rightPath)
+	if err != nil {
+		return false
+	}
+	defer right.Close()
+
+	return equalIgnoringLineEndings(bufio.NewReader(left), bufio.NewReader(right))
+}
+
+// equalIgnoringLineEndings compares two text streams byte by byte with line
+// endings normalized. A NUL byte marks binary content and ends the comparison.
+func equalIgnoringLineEndings(left, right *bufio.Reader) bool {
+	for {
+		a, errA := nextNormalizedByte(left)
+		b, errB := nextNormalizedByte(right)
+		if errA != nil || errB != nil {
+			return errA == io.EOF && errB == io.EOF
+		}
+		if a != b || a == 0 {
+			return false
+		}
+	}
+}
+
+// nextNormalizedByte reads one byte, returning CRLF and lone CR as LF
+func nextNormalizedByte(r *bufio.Reader) (byte, error) {
+	c, err := r.ReadByte()
+	if err != nil || c != '\r' {
+		return c, err
+	}
+	if next, err := r.Peek(1); err == nil && next[0] == '\n' {
+		r.ReadByte()
+	}
+	return '\n', nil
+}
@@ -0,0 +1,125 @@
+package compare
+
+import "sort"
+
+// MergeClass classifies a path in a three-way comparison against a common base
+type MergeClass int
+
+const (
+	MergeUnchanged    MergeClass = iota // Left and right match (unchanged, or the same change on both sides)
+	MergeChangedLeft                    // Changed in left only
+	MergeChangedRight                   // Changed in right only
+	MergeConflict                       // Changed in both left and right, differently
+)
+
+func (c MergeClass) String() string {
+	switch c {
+	case MergeUnchanged:
+		return "UNCHANGED"
+	case MergeChangedLeft:
+		return "CHANGED_LEFT"
+	case MergeChangedRight:
+		return "CHANGED_RIGHT"
+	case MergeConflict:
+		return "CONFLICT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ThreeWayResult is the result of comparing one path across base, left and right
+type ThreeWayResult struct {
+	RelativePath string
+	Class        MergeClass
+	BaseInfo     *FileInfo // nil if absent in base
+	LeftInfo     *FileInfo // nil if absent in left
+	RightInfo    *FileInfo // nil if absent in right
+}
+
+// LeftRightStatus returns the two-way status between left and right for this path
+func (r ThreeWayResult) LeftRightStatus() FileStatus {
+	switch {
+	case r.LeftInfo == nil:
+		return StatusOnlyRight
+	case r.RightInfo == nil:
+		return StatusOnlyLeft
+	case sameEntry(r.LeftInfo, r.RightInfo):
+		return StatusIdentical
+	default:
+		return StatusModified
+	}
+}
+
+// ClassifyThreeWay combines base-vs-left and base-vs-right comparison results
+// into a three-way classification, sorted by path
+func ClassifyThreeWay(baseLeft, baseRight []ComparisonResult) []ThreeWayResult {
+	byPath := make(map[string]*ThreeWayResult)
+	leftChanged := make(map[string]bool)
+	rightChanged := make(map[string]bool)
+
+	entry := func(path string) *ThreeWayResult {
+		if r, ok := byPath[path]; ok {
+			return r
+		}
+		r := &ThreeWayResult{RelativePath: path}
+		byPath[path] = r
+		return r
+	}
+
+	for _, result := range baseLeft {
+		r := entry(result.RelativePath)
+		r.BaseInfo = result.LeftInfo
+		r.LeftInfo = result.RightInfo
+		leftChanged[result.RelativePath] = result.Status != StatusIdentical
+	}
+	for _, result := range baseRight {
+		r := entry(result.RelativePath)
+		r.BaseInfo = result.LeftInfo
+		r.RightInfo = result.RightInfo
+		rightChanged[result.RelativePath] = result.Status != StatusIdentical
+	}
+
+	results := make([]ThreeWayResult, 0, len(byPath))
+	for path, r := range byPath {
+		switch {
+		case leftChanged[path] && rightChanged[path]:
+			if bothAbsent(r.LeftInfo, r.RightInfo) || sameEntry(r.LeftInfo, r.RightInfo) {
+				r.Class = MergeUnchanged
+			} else {
+				r.Class = MergeConflict
+			}
+		case leftChanged[path]:
+			r.Class = MergeChangedLeft
+		case rightChanged[path]:
+			r.Class = MergeChangedRight
+		default:
+			r.Class = MergeUnchanged
+		}
+		results = append(results, *r)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].RelativePath < results[j].RelativePath
+	})
+
+	return results
+}
+
+// bothAbsent reports whether a path was removed on both sides
+func bothAbsent(a, b *FileInfo) bool {
+	return a == nil && b == nil
+}
+
+// sameEntry reports whether two entries have the same type and content
+func sameEntry(a, b *FileInfo) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if a.IsDir || b.IsDir {
+		return a.IsDir && b.IsDir
+	}
+	if a.IsSymlink || b.IsSymlink {
+		return a.IsSymlink && b.IsSymlink && a.LinkTarget == b.LinkTarget
+	}
+	return a.Hash == b.Hash && a.Hash != "ERROR_CALCULATING_HASH"
+}
@@ -0,0 +1,69 @@
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// NormalizeRule is a regular expression substitution applied to the text of
+// two files before deciding that they differ, so values that legitimately
+// vary between environments, like hostnames or ports, don't count
+type NormalizeRule struct {
+	Pattern     string // Regular expression in Go syntax
+	Replacement string // Replacement text; $1 or ${name} refer to submatches
+}
+
+// normalizer is a compiled NormalizeRule
+type normalizer struct {
+	pattern     *regexp.Regexp
+	replacement []byte
+}
+
+// compileNormalizeRules compiles rules in order
+func compileNormalizeRules(rules []NormalizeRule) ([]normalizer, error) {
+	var normalizers []normalizer
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid normalize pattern %q: %w", rule.Pattern, err)
+		}
+		normalizers = append(normalizers, normalizer{pattern: re, replacement: []byte(rule.Replacement)})
+	}
+	return normalizers, nil
+}
+
+// normalizedEqual reports whether two files with different hashes hold the
+// same text once every NormalizeRules substitution has been applied to each.
+// Like lineEndingsOnly, it never reports files over MaxFileSize, files that
+// can't be opened (such as archive entries) or binary content.
+func (e *Engine) normalizedEqual(leftPath, rightPath string, leftInfo, rightInfo *FileInfo) bool {
+	if len(e.normalizers) == 0 {
+		return false
+	}
+	if leftInfo.Hash == "ERROR_CALCULATING_HASH" || rightInfo.Hash == "ERROR_CALCULATING_HASH" {
+		return false
+	}
+	if e.options.MaxFileSize > 0 && (leftInfo.Size > e.options.MaxFileSize || rightInfo.Size > e.options.MaxFileSize) {
+		return false
+	}
+
+	left, err := os.ReadFile(leftPath)
+	if err != nil || bytes.IndexByte(left, 0) >= 0 {
+		return false
+	}
+	right, err := os.ReadFile(rightPath)
+	if err != nil || bytes.IndexByte(right, 0) >= 0 {
+		return false
+	}
+	return bytes.Equal(e.normalize(left), e.normalize(right))
+}
+
+// normalize applies every normalizer to content, in order
+func (e *Engine) normalize(content []byte) []byte {
+	for _, n := range e.normalizers {
+		content = n.pattern.ReplaceAll(content, n.replacement)
+	}
+	return content
+}
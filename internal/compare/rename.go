@@ -0,0 +1,90 @@
+package compare
+
+import "sort"
+
+// pairRenames merges a file found only on the left with a file found only on
+// the right into one renamed pair when their content hashes match, for
+// DetectRenames. Content shared by several one-sided files is ambiguous and
+// left unpaired, as are empty files, which all share the same hash.
+func pairRenames(pairs []pathPair, leftFiles, rightFiles map[string]*FileInfo) []pathPair {
+	type candidates struct {
+		left, right []int // Indexes into pairs
+	}
+	byContent := make(map[string]*candidates)
+	candidateFor := func(info *FileInfo) *candidates {
+		if info.IsDir || info.IsSymlink || info.Size == 0 || info.Hash == "" || info.Hash == "ERROR_CALCULATING_HASH" {
+			return nil
+		}
+		key := info.Hash
+		if byContent[key] == nil {
+			byContent[key] = &candidates{}
+		}
+		return byContent[key]
+	}
+
+	for i, pair := range pairs {
+		leftInfo, rightInfo := leftFiles[pair.left], rightFiles[pair.right]
+		switch {
+		case leftInfo != nil && rightInfo == nil:
+			if c := candidateFor(leftInfo); c != nil {
+				c.left = append(c.left, i)
+			}
+		case leftInfo == nil && rightInfo != nil:
+			if c := candidateFor(rightInfo); c != nil {
+				c.right = append(c.right, i)
+			}
+		}
+	}
+
+	merged := make(map[int]bool)
+	var renamed []pathPair
+	for _, c := range byContent {
+		if len(c.left) != 1 || len(c.right) != 1 {
+			continue
+		}
+		left, right := pairs[c.left[0]], pairs[c.right[0]]
+		if leftFiles[left.left].Size != rightFiles[right.right].Size {
+			continue
+		}
+		merged[c.left[0]], merged[c.right[0]] = true, true
+		renamed = append(renamed, pathPair{path: left.left, left: left.left, right: right.right, renamed: true})
+	}
+	if len(renamed) == 0 {
+		return pairs
+	}
+
+	kept := make([]pathPair, 0, len(pairs)-len(renamed))
+	for i, pair := range pairs {
+		if !merged[i] {
+			kept = append(kept, pair)
+		}
+	}
+	// Map order would otherwise decide the order results are produced in
+	sort.Slice(renamed, func(i, j int) bool {
+		return renamed[i].path < renamed[j].path
+	})
+	return append(kept, renamed...)
+}
+
+// renamedResult describes a renamed pair, whose content is already known to
+// be identical from the hashes that paired it
+func (e *Engine) renamedResult(pair pathPair, leftInfo, rightInfo *FileInfo) ComparisonResult {
+	result := ComparisonResult{
+		RelativePath: pair.path,
+		Status:       StatusRenamed,
+		LeftInfo:     leftInfo,
+		RightInfo:    rightInfo,
+	}
+	if e.options.MetadataOnly {
+		result.Method = ComparisonMetadata
+	} else if IsSampledHash(leftInfo.Hash) {
+		result.Method = ComparisonSampled
+	}
+	if !e.options.IgnorePermissions && leftInfo.Permissions != rightInfo.Permissions {
+		result.Changes |= ChangePerms
+	}
+	if !e.sameModTime(leftInfo.ModTime, rightInfo.ModTime) {
+		result.Changes |= ChangeTime
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package compare
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// DirectoryRollup counts the differing entries directly inside one directory
+type DirectoryRollup struct {
+	Path      string // Relative directory path, "." for the root
+	Modified  int
+	OnlyLeft  int
+	OnlyRight int
+}
+
+// Total returns the number of differing children
+func (r DirectoryRollup) Total() int {
+	return r.Modified + r.OnlyLeft + r.OnlyRight
+}
+
+// RollupByDirectory groups differing results by their parent directory.
+// Directories without differing children are omitted; the rest are sorted by
+// total changes, most first, then by path.
+func RollupByDirectory(results []ComparisonResult) []DirectoryRollup {
+	byDir := make(map[string]*DirectoryRollup)
+	for _, result := range results {
+		if result.Status == StatusIdentical {
+			continue
+		}
+		dir := filepath.Dir(result.RelativePath)
+		rollup := byDir[dir]
+		if rollup == nil {
+			rollup = &DirectoryRollup{Path: dir}
+			byDir[dir] = rollup
+		}
+		switch result.Status {
+		case StatusModified:
+			rollup.Modified++
+		case StatusOnlyLeft:
+			rollup.OnlyLeft++
+		case StatusOnlyRight:
+			rollup.OnlyRight++
+		}
+	}
+
+	rollups := make([]DirectoryRollup, 0, len(byDir))
+	for _, rollup := range byDir {
+		rollups = append(rollups, *rollup)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		if rollups[i].Total() != rollups[j].Total() {
+			return rollups[i].Total() > rollups[j].Total()
+		}
+		return rollups[i].Path < rollups[j].Path
+	})
+	return rollups
+}
@@ -0,0 +1,75 @@
+package compare
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultSampleBlockSize is the size of each region read by sampled hashing
+// when SampleBlockSize is not set
+const DefaultSampleBlockSize = 1 << 20
+
+// sampledHashPrefix marks hashes computed from samples of a file, so they are
+// never mistaken for (or cached as) hashes of the whole content
+const sampledHashPrefix = "SAMPLED_"
+
+// IsSampledHash reports whether a hash was computed from samples of a file
+func IsSampledHash(hash string) bool {
+	return strings.HasPrefix(hash, sampledHashPrefix)
+}
+
+// sampleBlockSize returns the configured sample block size
+func (e *Engine) sampleBlockSize() int64 {
+	if e.options.SampleBlockSize > 0 {
+		return e.options.SampleBlockSize
+	}
+	return DefaultSampleBlockSize
+}
+
+// sampled reports whether a file of the given size gets a sampled hash.
+// Files too small to hold three distinct blocks are always hashed in full.
+func (e *Engine) sampled(size int64) bool {
+	threshold := e.options.SampledHashThreshold
+	return threshold > 0 && size >= threshold && size > 3*e.sampleBlockSize()
+}
+
+// sampledHash hashes the size of the content and its first, middle and last
+// blocks. Content that supports random access is read only at those blocks;
+// anything else, like an archive entry, is read through and the gaps skipped.
+func (e *Engine) sampledHash(content io.Reader, size int64) (string, error) {
+	block := e.sampleBlockSize()
+	offsets := []int64{0, (size - block) / 2, size - block}
+
+	hash := newHasher(e.options.HashAlgorithm)
+	var sizeBytes [8]byte
+	binary.BigEndian.PutUint64(sizeBytes[:], uint64(size))
+	hash.Write(sizeBytes[:])
+
+	readerAt, random := content.(io.ReaderAt)
+	var pos int64
+	for _, offset := range offsets {
+		var section io.Reader
+		if random {
+			section = io.NewSectionReader(readerAt, offset, block)
+		} else {
+			skipped, err := io.CopyN(io.Discard, content, offset-pos)
+			e.hashedBytes.Add(skipped)
+			if err != nil {
+				return "", err
+			}
+			section = io.LimitReader(content, block)
+			pos = offset + block
+		}
+		n, err := io.Copy(hash, section)
+		e.hashedBytes.Add(n)
+		if err != nil {
+			return "", err
+		}
+		if n != block {
+			return "", fmt.Errorf("file shrank while sampling: read %d of %d bytes at offset %d", n, block, offset)
+		}
+	}
+	return fmt.Sprintf("%s%x", sampledHashPrefix, hash.Sum(nil)), nil
+}
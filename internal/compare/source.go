@@ -0,0 +1,56 @@
+package compare
+
+// Source provides the entries for one side of a comparison
+type Source interface {
+	// Path returns the directory or archive the entries come from
+	Path() string
+
+	// collect returns the entries keyed by relative path, plus problems that
+	// prevented individual entries from being compared
+	collect(e *Engine, side string) (*fileScan, error)
+}
+
+// NewSource returns an ArchiveSource for supported archive files and a
+// DirSource for anything else
+func NewSource(path string) Source {
+	if IsArchive(path) {
+		return ArchiveSource(path)
+	}
+	return DirSource(path)
+}
+
+// DirSource is a directory tree on disk
+type DirSource string
+
+// Path returns the directory
+func (s DirSource) Path() string {
+	return string(s)
+}
+
+func (s DirSource) collect(e *Engine, side string) (*fileScan, error) {
+	if len(e.options.ListedPaths) > 0 {
+		return e.collectPaths(string(s), e.options.ListedPaths, side), nil
+	}
+	return e.collectFiles(string(s), side)
+}
+
+// ListSource is a fixed set of relative paths under a directory, looked up
+// directly instead of walking the tree. Filters and the depth limit don't
+// apply, and listed directories are compared as entries only. The parent
+// directories of listed paths are included so they aren't reported as
+// missing when the other side is walked.
+type ListSource struct {
+	Dir   string
+	Paths []string
+}
+
+// Path returns the directory the paths are relative to
+func (s ListSource) Path() string {
+	return s.Dir
+}
+
+func (s ListSource) collect(e *Engine, side string) (*fileScan, error) {
+	scan := e.collectPaths(s.Dir, s.Paths, side)
+	e.addParentDirs(scan, s.Dir)
+	return scan, nil
+}
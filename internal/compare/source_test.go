@@ -0,0 +1,44 @@
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListSourceIncludesParentDirs(t *testing.T) {
+	left, right := t.TempDir(), t.TempDir()
+	writeTree(t, left, "b.txt", "d/e/a.txt")
+	writeTree(t, right, "b.txt", "d/e/a.txt")
+
+	engine, err := NewEngine(ComparisonOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]FileStatus{}
+	_, err = engine.CompareSources(ListSource{Dir: left, Paths: []string{"b.txt", "d/e/a.txt"}}, DirSource(right), func(r ComparisonResult) {
+		statuses[filepath.ToSlash(r.RelativePath)] = r.Status
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"b.txt", "d", "d/e", "d/e/a.txt"} {
+		if got, ok := statuses[path]; !ok || got != StatusIdentical {
+			t.Errorf("%s: status = %v (reported %v), want %v", path, got, ok, StatusIdentical)
+		}
+	}
+}
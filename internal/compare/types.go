@@ -1,7 +1,15 @@
 package compare
 
 import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/util"
 )
 
 // FileStatus represents the comparison status of a file/directory
@@ -12,6 +20,7 @@ const (
 	StatusModified
 	StatusOnlyLeft
 	StatusOnlyRight
+	StatusRenamed // Same content at a different path on each side (DetectRenames)
 )
 
 func (s FileStatus) String() string {
@@ -24,27 +33,106 @@ func (s FileStatus) String() string {
 		return "ONLY_IN_LEFT"
 	case StatusOnlyRight:
 		return "ONLY_IN_RIGHT"
+	case StatusRenamed:
+		return "RENAMED"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// ChangeFlags describes how an entry present on both sides differs
+type ChangeFlags uint8
+
+const (
+	ChangeContent     ChangeFlags = 1 << iota // File content or symlink target differs
+	ChangePerms                               // Permission bits differ (unless IgnorePermissions)
+	ChangeType                                // Entry kind differs (file, directory or symlink)
+	ChangeTime                                // Modification time differs (informational only)
+	ChangeCase                                // Names differ only in letter case (CaseInsensitivePaths)
+	ChangeLineEndings                         // Content differs only in CRLF vs LF line endings (with ChangeContent)
+)
+
+// Has reports whether all of the given flags are set
+func (c ChangeFlags) Has(flags ChangeFlags) bool {
+	return c&flags == flags
+}
+
+// PermsOnly reports whether the permissions are the only meaningful change
+func (c ChangeFlags) PermsOnly() bool {
+	return c&^ChangeTime == ChangePerms
+}
+
+func (c ChangeFlags) String() string {
+	var names []string
+	for _, flag := range []struct {
+		flag ChangeFlags
+		name string
+	}{{ChangeContent, "content"}, {ChangePerms, "perms"}, {ChangeType, "type"}, {ChangeTime, "time"}, {ChangeCase, "case"}, {ChangeLineEndings, "eol"}} {
+		if c.Has(flag.flag) {
+			names = append(names, flag.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
 // FileInfo contains information about a file for comparison
 type FileInfo struct {
 	Path        string    // Relative path from root
 	Size        int64     // File size in bytes
 	ModTime     time.Time // Modification time
 	IsDir       bool      // Whether this is a directory
-	Hash        string    // SHA-256 hash for files (empty for directories)
+	IsSymlink   bool      // Whether this is an unfollowed symlink (compared by LinkTarget)
+	LinkTarget  string    // Symlink target path (empty if not a symlink)
+	Hash        string    // Content hash for files (empty for directories)
 	Permissions string    // File permissions (for display/debugging)
+	Inode       string    // Device and inode of a file with other hardlinks, with DetectHardlinks (empty otherwise)
 }
 
 // ComparisonResult represents the result of comparing a single file/directory
 type ComparisonResult struct {
-	RelativePath string     // Path relative to comparison root
-	Status       FileStatus // Comparison status
-	LeftInfo     *FileInfo  // Info from left directory (nil if not present)
-	RightInfo    *FileInfo  // Info from right directory (nil if not present)
+	RelativePath string           // Path relative to comparison root
+	Status       FileStatus       // Comparison status
+	LeftInfo     *FileInfo        // Info from left directory (nil if not present)
+	RightInfo    *FileInfo        // Info from right directory (nil if not present)
+	Changes      ChangeFlags      // What differs when both sides exist
+	Method       ComparisonMethod // How file contents were compared
+}
+
+// ComparisonMethod records how two files were judged identical or modified
+type ComparisonMethod int
+
+const (
+	ComparisonHash     ComparisonMethod = iota // Content hashes
+	ComparisonMetadata                         // Size and modification time only (MetadataOnly)
+	ComparisonSampled                          // Size and sampled blocks only (SampledHashThreshold); probabilistic
+)
+
+// LeftPath returns the relative path of the left entry, which differs from
+// RelativePath only in case when paths were matched case-insensitively
+func (r ComparisonResult) LeftPath() string {
+	if r.LeftInfo != nil {
+		return r.LeftInfo.Path
+	}
+	return r.RelativePath
+}
+
+// RightPath returns the relative path of the right entry
+func (r ComparisonResult) RightPath() string {
+	if r.RightInfo != nil {
+		return r.RightInfo.Path
+	}
+	return r.RelativePath
+}
+
+// SortResults orders results canonically by RelativePath. Compare returns
+// them in worker completion order, which differs from run to run.
+func SortResults(results []ComparisonResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelativePath < results[j].RelativePath
+	})
 }
 
 // ComparisonOptions contains options for directory comparison
@@ -53,21 +141,125 @@ type ComparisonOptions struct {
 	ExcludeNames      []string // File/directory names or glob patterns to exclude
 	ExcludePaths      []string // Relative paths to exclude
 	ExcludeExtensions []string // File extensions to exclude (without dot)
+	ExcludeRegex      []string // Regular expressions matched against the relative path
+	IncludePaths      []string // If set, only these relative paths and their contents are compared
+	ListedPaths       []string // If set, exactly these relative paths are compared, without walking the trees or filtering
+
+	// GitignoreRules are the rules of the compared directories' .gitignore
+	// files. Their negations only re-include what an earlier gitignore rule
+	// excluded; the other exclusions always apply.
+	GitignoreRules []config.IgnoreRule
+
+	// ExcludeContentRegex excludes files whose first bytes match one of these
+	// regular expressions, e.g. generated-code markers. Reading the start of
+	// every file makes it slower than the path-based filters.
+	ExcludeContentRegex []string
+
+	// NoIgnoreFiles turns off reading .dovetailignore files while walking
+	NoIgnoreFiles bool
 
 	// Comparison options
-	IgnorePermissions bool // Whether to ignore permission differences
-	FollowSymlinks    bool // Whether to follow symbolic links
+	IgnorePermissions bool   // Whether to ignore permission differences
+	FollowSymlinks    bool   // Whether to follow symbolic links
+	HashAlgorithm     string // Hash algorithm for file content ("sha256", "blake3", "xxhash"; default "sha256")
+	MetadataOnly      bool   // Treat files as identical when size and modification time match, without hashing
+	MaxDepth          int    // Compare paths of at most this many segments (0 = unlimited)
+	IgnoreLineEndings bool   // Treat files differing only in CRLF vs LF line endings as identical
+	DetectHardlinks   bool   // Report files hardlinked together on one side but not the other
+
+	// DetectRenames pairs a file found only on the left with one found only
+	// on the right when their content hashes match, reporting them as one
+	// StatusRenamed result at the left path instead of two one-sided ones
+	DetectRenames bool
+
+	// NormalizeRules are substituted, in order, into the text of files whose
+	// hashes differ; files that then match are treated as identical
+	NormalizeRules []NormalizeRule
+
+	// TimeToleranceSeconds treats modification times at most this many
+	// seconds apart as equal, for filesystems with coarse timestamps such as
+	// FAT's 2 seconds. It applies to ChangeTime and to MetadataOnly.
+	TimeToleranceSeconds int
+
+	// CaseInsensitivePaths pairs left and right paths that differ only in
+	// letter case, reporting them as MODIFIED with ChangeCase
+	CaseInsensitivePaths bool
+
+	// MinFileSize and MaxCompareSize exclude regular files outside a size
+	// range from the comparison altogether. Each side is filtered by its own
+	// size, so a file that crossed a limit shows up on one side only.
+	MinFileSize    int64 // Exclude files smaller than this (0 = no limit)
+	MaxCompareSize int64 // Exclude files larger than this (0 = no limit)
 
 	// Performance options
 	MaxFileSize     int64 // Maximum file size to hash (0 = no limit)
 	ParallelWorkers int   // Number of parallel workers for hashing (0 = auto)
+
+	// SampledHashThreshold makes files of at least this many bytes hashed
+	// from their size and first, middle and last SampleBlockSize bytes only.
+	// Much faster for huge files, but changes elsewhere go unnoticed (0 = off).
+	SampledHashThreshold int64
+	SampleBlockSize      int64 // Bytes hashed from each sampled region (0 = DefaultSampleBlockSize)
+}
+
+// Validate checks the options that don't need compiling. NewEngine calls it
+// and also reports invalid regular expressions.
+func (o ComparisonOptions) Validate() error {
+	if o.SampledHashThreshold < 0 || o.SampleBlockSize < 0 {
+		return fmt.Errorf("invalid sampled hash settings: threshold and block size must not be negative")
+	}
+	if o.MinFileSize < 0 || o.MaxCompareSize < 0 {
+		return fmt.Errorf("invalid size range: sizes must not be negative")
+	}
+	if o.MaxCompareSize > 0 && o.MinFileSize > o.MaxCompareSize {
+		return fmt.Errorf("invalid size range: minimum size %d is larger than maximum size %d", o.MinFileSize, o.MaxCompareSize)
+	}
+	if o.TimeToleranceSeconds < 0 {
+		return fmt.Errorf("invalid time tolerance %d: must not be negative", o.TimeToleranceSeconds)
+	}
+	if o.DetectRenames && o.MetadataOnly {
+		return fmt.Errorf("rename detection needs content hashes and can't be combined with metadata-only comparison")
+	}
+	if o.MaxDepth < 0 {
+		return fmt.Errorf("invalid max depth %d: must be 0 (unlimited) or more", o.MaxDepth)
+	}
+	for _, includePath := range o.IncludePaths {
+		cleaned := filepath.Clean(includePath)
+		if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid include path %q: must be relative to the compared directories", includePath)
+		}
+	}
+	for _, listedPath := range o.ListedPaths {
+		if !isContained(filepath.Clean(listedPath)) {
+			return fmt.Errorf("invalid listed path %q: must be a path inside the compared directories", listedPath)
+		}
+	}
+	return nil
+}
+
+// isContained reports whether a cleaned relative path names an entry inside
+// the compared directories, rather than the root itself or something outside
+func isContained(cleaned string) bool {
+	return cleaned != "." && !filepath.IsAbs(cleaned) && cleaned != ".." && !strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
 }
 
 // Engine represents the directory comparison engine
 type Engine struct {
 	options      ComparisonOptions
 	filter       *Filter
+	normalizers  []normalizer // Compiled NormalizeRules
 	verboseLevel int
+	progressFunc util.ProgressFunc // Optional progress callback while comparing
+	hashCache    *HashCache        // Hashes from earlier runs (nil = always hash)
+
+	// Counters for the -vv timing breakdown, reset by each comparison
+	hashedBytes atomic.Int64 // Bytes read to compute content hashes
+	cacheHits   atomic.Int64 // Hashes taken from the hash cache
+
+	// .dovetailignore files read by the current comparison
+	ignoreRoots  []string        // Compared directories to look for them in
+	ignoreLoaded map[string]bool // Paths already looked up
+	ignoreFiles  []ignoreFile
 }
 
 // ComparisonSummary contains statistics about the comparison
@@ -77,9 +269,56 @@ type ComparisonSummary struct {
 	ModifiedFiles     int
 	OnlyLeftFiles     int
 	OnlyRightFiles    int
+	RenamedFiles      int // Left-only and right-only files paired by DetectRenames, counted once
 	TotalDirs         int
 	IdenticalDirs     int
 	OnlyLeftDirs      int
 	OnlyRightDirs     int
+	HashAlgorithm     string // Hash algorithm used for content comparison
 	ErrorsEncountered []string
+
+	OnlyLeftBytes      int64 // Total size of files only on the left
+	OnlyRightBytes     int64 // Total size of files only on the right
+	ModifiedBytesDelta int64 // Left size minus right size, summed over modified files
+
+	DetectedPatchFiles []PatchFile // Leftovers of earlier patch runs, sorted by side and path
+	MissingPaths       []string    // ListedPaths found on neither side, sorted
+
+	HardlinkDifferences []HardlinkDifference // Link groups not mirrored on the other side, with DetectHardlinks
+	SkippedPaths        []SkippedPath        // Entries that could not be read, sorted by side and path
+}
+
+// SkippedPath is an entry the scan could not read, so the comparison is
+// incomplete there
+type SkippedPath struct {
+	Side   string // "left" or "right"
+	Path   string // Relative path of the entry
+	IsDir  bool   // The directory's contents could not be listed
+	Reason string // Why it was skipped, e.g. "permission denied"
+}
+
+// PatchFile is a leftover of a patch run (.orig backup or .rej reject) found
+// while comparing
+type PatchFile struct {
+	Side string // "left" or "right"
+	Path string // Relative path of the leftover
+}
+
+// patchFileExtensions are the files patch and git apply --reject leave behind
+var patchFileExtensions = []string{".orig", ".rej"}
+
+// IsPatchFile reports whether a file name looks like a patch run leftover
+func IsPatchFile(name string) bool {
+	ext := filepath.Ext(name)
+	for _, patchExt := range patchFileExtensions {
+		if ext == patchExt {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDifferences reports whether the comparison found any modified, renamed, left-only or right-only entries
+func (s *ComparisonSummary) HasDifferences() bool {
+	return s.ModifiedFiles+s.RenamedFiles+s.OnlyLeftFiles+s.OnlyRightFiles+s.OnlyLeftDirs+s.OnlyRightDirs > 0
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -20,21 +21,26 @@ func NewGitignoreParser(verboseLevel int) *GitignoreParser {
 	}
 }
 
-// GitignoreResult contains the parsed exclusions from .gitignore files
+// IgnoreRule is one pattern of a .gitignore or .dovetailignore file,
+// translated to a regular expression matched against slash-separated
+// relative paths. Rules are evaluated in file order and the last one matching
+// a path decides: a Negate rule re-includes it, any other excludes it.
+type IgnoreRule struct {
+	Regex  string
+	Negate bool
+}
+
+// GitignoreResult contains the parsed rules from .gitignore files
 type GitignoreResult struct {
-	Names      []string // Patterns for --exclude-name
-	Paths      []string // Patterns for --exclude-path
-	Extensions []string // Patterns for --exclude-ext
-	Sources    []string // Source files for debugging
+	Rules   []IgnoreRule // In file order, left .gitignore first
+	Sources []string     // Source files for debugging
 }
 
 // ParseGitignoreFiles reads and parses .gitignore files from the specified directories
 func (p *GitignoreParser) ParseGitignoreFiles(leftDir, rightDir string, checkBothSides bool) (*GitignoreResult, error) {
 	result := &GitignoreResult{
-		Names:      []string{},
-		Paths:      []string{},
-		Extensions: []string{},
-		Sources:    []string{},
+		Rules:   []IgnoreRule{},
+		Sources: []string{},
 	}
 
 	// Parse left directory .gitignore
@@ -75,6 +81,40 @@ func (p *GitignoreParser) ParseGitignoreFiles(leftDir, rightDir string, checkBot
 	return result, nil
 }
 
+// IgnoreFileName is the per-directory ignore file honored at any depth while
+// comparing. Its patterns are relative to the directory holding it.
+const IgnoreFileName = ".dovetailignore"
+
+// ParseIgnoreFile reads a gitignore-style file into rules matched against
+// slash-separated paths relative to the file's directory
+func (p *GitignoreParser) ParseIgnoreFile(path string) ([]IgnoreRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []IgnoreRule
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := p.validatePattern(line, path, lineNumber); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, parseRule(line))
+		if p.verboseLevel >= 3 {
+			fmt.Fprintf(os.Stderr, "%s pattern: '%s'\n", path, line)
+		}
+	}
+	return rules, scanner.Err()
+}
+
 // parseGitignoreFile parses a single .gitignore file
 func (p *GitignoreParser) parseGitignoreFile(path string, result *GitignoreResult) error {
 	file, err := os.Open(path)
@@ -100,8 +140,10 @@ func (p *GitignoreParser) parseGitignoreFile(path string, result *GitignoreResul
 			return err
 		}
 
-		// Parse supported patterns
-		p.parsePattern(line, result)
+		result.Rules = append(result.Rules, parseRule(line))
+		if p.verboseLevel >= 3 {
+			fmt.Fprintf(os.Stderr, "Gitignore pattern: '%s'\n", line)
+		}
 	}
 
 	return scanner.Err()
@@ -109,36 +151,14 @@ func (p *GitignoreParser) parseGitignoreFile(path string, result *GitignoreResul
 
 // validatePattern checks if a pattern is supported and fails loudly if not
 func (p *GitignoreParser) validatePattern(pattern, filePath string, lineNumber int) error {
-	// Unsupported: Negation patterns
-	if strings.HasPrefix(pattern, "!") {
+	// Unsupported: Unterminated character classes
+	if strings.Contains(pattern, "[") && !strings.Contains(pattern, "]") {
 		return &UnsupportedPatternError{
 			Pattern:    pattern,
 			FilePath:   filePath,
 			LineNumber: lineNumber,
-			Reason:     "Negation patterns (!) are not supported",
-			Suggestion: "Remove the negation pattern or disable --use-gitignore",
-		}
-	}
-
-	// Unsupported: Complex glob patterns
-	if strings.Contains(pattern, "**") {
-		return &UnsupportedPatternError{
-			Pattern:    pattern,
-			FilePath:   filePath,
-			LineNumber: lineNumber,
-			Reason:     "Double-asterisk (**) glob patterns are not supported",
-			Suggestion: "Use simpler patterns like 'dirname/' or '*.ext'",
-		}
-	}
-
-	// Unsupported: Character classes
-	if strings.Contains(pattern, "[") && strings.Contains(pattern, "]") {
-		return &UnsupportedPatternError{
-			Pattern:    pattern,
-			FilePath:   filePath,
-			LineNumber: lineNumber,
-			Reason:     "Character class patterns ([abc]) are not supported",
-			Suggestion: "Use specific patterns or wildcard patterns",
+			Reason:     "Unterminated character class ([) in pattern",
+			Suggestion: "Close the character class or escape the bracket",
 		}
 	}
 
@@ -156,65 +176,80 @@ func (p *GitignoreParser) validatePattern(pattern, filePath string, lineNumber i
 	return nil
 }
 
-// parsePattern converts a gitignore pattern to dovetail exclusion patterns
-func (p *GitignoreParser) parsePattern(pattern string, result *GitignoreResult) {
-	original := pattern
-
-	// Remove leading slash for root-relative patterns
-	if strings.HasPrefix(pattern, "/") {
-		pattern = pattern[1:]
-	}
-
-	// Directory patterns (end with /)
-	if strings.HasSuffix(pattern, "/") {
-		// This is a directory exclusion
-		dirName := strings.TrimSuffix(pattern, "/")
-		if strings.Contains(dirName, "/") {
-			// Path-based exclusion: "path/to/dir/" -> --exclude-path "path/to/dir/"
-			result.Paths = append(result.Paths, pattern)
-		} else {
-			// Name-based exclusion: "dirname/" -> --exclude-name "dirname"
-			result.Names = append(result.Names, dirName)
-		}
-		return
+// parseRule converts a gitignore pattern to a rule. Every pattern goes
+// through gitignoreToRegex, so that rules keep their file order and anchoring.
+func parseRule(pattern string) IgnoreRule {
+	if strings.HasPrefix(pattern, "!") {
+		return IgnoreRule{Regex: gitignoreToRegex(pattern[1:]), Negate: true}
 	}
+	return IgnoreRule{Regex: gitignoreToRegex(pattern)}
+}
 
-	// File extension patterns
-	if strings.HasPrefix(pattern, "*.") && !strings.Contains(pattern[2:], "/") && !strings.Contains(pattern[2:], "*") {
-		// Simple extension pattern: "*.log" -> --exclude-name "*.log"
-		result.Names = append(result.Names, pattern)
-		return
-	}
+// gitignoreToRegex translates a gitignore glob into a regular expression
+// matched against slash-separated relative paths. A match on a directory also
+// matches everything below it.
+func gitignoreToRegex(pattern string) string {
+	pattern = strings.TrimSuffix(pattern, "/")
 
-	// Path-based patterns (contains /)
+	// Patterns with a slash before the end are relative to the root;
+	// others match at any depth
+	var b strings.Builder
 	if strings.Contains(pattern, "/") {
-		// Path exclusion: "build/output" -> --exclude-path "build/output"
-		result.Paths = append(result.Paths, pattern)
-		return
+		b.WriteString("^")
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		b.WriteString("(^|/)")
 	}
 
-	// Simple filename patterns
-	result.Names = append(result.Names, pattern)
-
-	if p.verboseLevel >= 3 {
-		fmt.Fprintf(os.Stderr, "Gitignore pattern: '%s' -> dovetail exclusion\n", original)
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			// "**/" matches zero or more leading directories
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
 	}
+
+	b.WriteString("(/.*)?$")
+	return b.String()
 }
 
 // logParsedPatterns logs the patterns that were parsed (for debugging)
 func (p *GitignoreParser) logParsedPatterns(result *GitignoreResult) {
-	if len(result.Names) > 0 {
-		fmt.Fprintf(os.Stderr, "  Names: %s\n", strings.Join(result.Names, ", "))
-	}
-	if len(result.Paths) > 0 {
-		fmt.Fprintf(os.Stderr, "  Paths: %s\n", strings.Join(result.Paths, ", "))
-	}
-	if len(result.Extensions) > 0 {
-		fmt.Fprintf(os.Stderr, "  Extensions: %s\n", strings.Join(result.Extensions, ", "))
+	for _, rule := range result.Rules {
+		if rule.Negate {
+			fmt.Fprintf(os.Stderr, "  Re-include: %s\n", rule.Regex)
+		} else {
+			fmt.Fprintf(os.Stderr, "  Exclude: %s\n", rule.Regex)
+		}
 	}
 }
 
-// UnsupportedPatternError represents an unsupported .gitignore pattern
+// UnsupportedPatternError represents an unsupported .gitignore or .dovetailignore pattern
 type UnsupportedPatternError struct {
 	Pattern    string
 	FilePath   string
@@ -224,24 +259,24 @@ type UnsupportedPatternError struct {
 }
 
 func (e *UnsupportedPatternError) Error() string {
-	return fmt.Sprintf(`Unsupported .gitignore pattern in %s:%d
+	return fmt.Sprintf(`Unsupported ignore pattern in %s:%d
   Pattern: "%s"
   Reason: %s
   Suggestion: %s
 
-Supported .gitignore patterns:
+Supported .gitignore and .dovetailignore patterns:
   ✓ filename          (file/directory name exclusion)
   ✓ *.ext             (file extension exclusion)  
   ✓ dirname/          (directory exclusion)
   ✓ path/to/file      (path-based exclusion)
   ✓ /root-relative    (root-relative path exclusion)
-  
+  ✓ **/*.ext, a/**/b  (double-asterisk globs)
+  ✓ [abc].txt         (character classes)
+  ✓ !negation         (re-include a path excluded by an earlier pattern)
+
 Unsupported patterns:
-  ✗ !negation         (negation patterns)
-  ✗ **/*.ext          (double-asterisk globs)
-  ✗ [abc].txt         (character classes)
   ✗ {a,b}.txt         (brace expansion)
 
-Either remove the unsupported pattern or disable --use-gitignore`,
+Either remove the unsupported pattern or stop reading the file (--use-gitignore, --no-dovetailignore)`,
 		e.FilePath, e.LineNumber, e.Pattern, e.Reason, e.Suggestion)
 }
@@ -3,9 +3,12 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/harikb/dovetail/internal/theme"
 )
 
 // Loader handles loading and parsing configuration files
@@ -43,6 +46,12 @@ func (l *Loader) Load(explicitConfigPath string) (*Config, error) {
 				continue
 			}
 
+			// A hook in a project's config would run whatever the project says
+			if fileConfig.General.PostApplyHook != "" && !configPath.UserLevel {
+				fmt.Fprintf(os.Stderr, "Warning: ignoring post_apply_hook in %s: it is only read from ~/.dovetail.toml; use --post-apply to run a hook\n", configPath.Path)
+				fileConfig.General.PostApplyHook = ""
+			}
+
 			// Merge this config into the base config
 			config.MergeWith(fileConfig)
 			loadedConfigs = append(loadedConfigs, fmt.Sprintf("%s (%s)", configPath.Path, configPath.Source))
@@ -95,6 +104,28 @@ func (l *Loader) validateConfig(config *Config, path string) error {
 		return fmt.Errorf("invalid verbose level %d in %s: must be 0-3", config.General.Verbose, path)
 	}
 
+	// Validate diff context
+	if config.General.Context != nil && *config.General.Context < 0 {
+		return fmt.Errorf("invalid context %d in %s: must be >= 0", *config.General.Context, path)
+	}
+
+	// Validate I/O retries
+	if config.General.IORetries < 0 {
+		return fmt.Errorf("invalid io_retries %d in %s: must be >= 0", config.General.IORetries, path)
+	}
+
+	// Validate the theme and color overrides
+	if _, err := theme.New(config.General.Theme, config.Colors); err != nil {
+		return fmt.Errorf("invalid colors in %s: %w", path, err)
+	}
+
+	// Validate normalize patterns
+	for _, rule := range config.Normalize {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid normalize pattern %q in %s: %w", rule.Pattern, path, err)
+		}
+	}
+
 	// Validate parallel workers
 	if config.Performance.ParallelWorkers < 0 {
 		return fmt.Errorf("invalid parallel_workers %d in %s: must be >= 0", config.Performance.ParallelWorkers, path)
@@ -135,11 +166,45 @@ func ApplyCLIOverrides(config *Config, cliConfig CLIConfig) {
 	config.Exclusions.Names = append(config.Exclusions.Names, cliConfig.ExcludeNames...)
 	config.Exclusions.Paths = append(config.Exclusions.Paths, cliConfig.ExcludePaths...)
 	config.Exclusions.Extensions = append(config.Exclusions.Extensions, cliConfig.ExcludeExtensions...)
+	config.Exclusions.Regex = append(config.Exclusions.Regex, cliConfig.ExcludeRegex...)
+	config.Exclusions.Content = append(config.Exclusions.Content, cliConfig.ContentRegex...)
+	config.Exclusions.Include = append(config.Exclusions.Include, cliConfig.IncludePaths...)
+
+	// Add the built-in exclusions unless turned off for this run
+	if config.Exclusions.UseDefaults && !cliConfig.NoDefaultExcludes {
+		config.Exclusions.Names = append(config.Exclusions.Names, DefaultExclusions.Names...)
+		config.Exclusions.Extensions = append(config.Exclusions.Extensions, DefaultExclusions.Extensions...)
+	}
 
 	// Override gitignore settings if set via CLI
 	if cliConfig.UseGitignore {
 		config.Gitignore.Enabled = true
 	}
+
+	// Override metadata preservation if set via CLI
+	if cliConfig.PreserveMetadata {
+		config.General.PreserveMetadata = true
+	}
+
+	// Override case-insensitive matching if set via CLI
+	if cliConfig.IgnoreCase {
+		config.General.IgnoreCase = true
+	}
+
+	// Override diff context if set via CLI
+	if cliConfig.Context != nil {
+		config.General.Context = cliConfig.Context
+	}
+
+	// Override the external diff program if set via CLI
+	if cliConfig.DiffCommand != "" {
+		config.General.DiffCommand = cliConfig.DiffCommand
+	}
+
+	// Override the post-apply hook if set via CLI
+	if cliConfig.PostApplyHook != "" {
+		config.General.PostApplyHook = cliConfig.PostApplyHook
+	}
 }
 
 // CLIConfig represents configuration values from CLI flags
@@ -149,5 +214,14 @@ type CLIConfig struct {
 	ExcludeNames      []string
 	ExcludePaths      []string
 	ExcludeExtensions []string
+	ExcludeRegex      []string
+	ContentRegex      []string // Regular expressions excluding files by their first bytes
+	IncludePaths      []string
 	UseGitignore      bool
+	PreserveMetadata  bool
+	IgnoreCase        bool
+	NoDefaultExcludes bool   // Skip DefaultExclusions even if exclusions.use_defaults is set
+	Context           *int   // Lines of diff context (nil = not set)
+	DiffCommand       string // External diff program and arguments (empty = not set)
+	PostApplyHook     string // Shell command run after a successful apply (empty = not set)
 }
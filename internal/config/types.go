@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Config represents the complete configuration for dovetail
@@ -10,27 +13,110 @@ type Config struct {
 	Performance PerformanceConfig `toml:"performance"`
 	Exclusions  ExclusionsConfig  `toml:"exclusions"`
 	Gitignore   GitignoreConfig   `toml:"gitignore"`
+
+	// Colors overrides individual colors of the theme, keyed by the names
+	// in theme.ColorNames
+	Colors map[string]string `toml:"colors"`
+
+	// Normalize lists substitutions applied to the text of differing files
+	// before comparing them again, as [[normalize]] tables
+	Normalize []NormalizeRule `toml:"normalize"`
+}
+
+// NormalizeRule is a regular expression substitution from a [[normalize]] table
+type NormalizeRule struct {
+	Pattern     string `toml:"pattern"`     // Regular expression
+	Replacement string `toml:"replacement"` // Replacement text; $1 refers to a submatch
 }
 
 // GeneralConfig contains general application settings
 type GeneralConfig struct {
-	Verbose           int  `toml:"verbose"`            // Verbosity level (0-3)
-	NoColor           bool `toml:"no_color"`           // Disable colored output
-	FollowSymlinks    bool `toml:"follow_symlinks"`    // Follow symbolic links
-	IgnorePermissions bool `toml:"ignore_permissions"` // Ignore file permission differences
+	Verbose           int    `toml:"verbose"`            // Verbosity level (0-3)
+	NoColor           bool   `toml:"no_color"`           // Disable colored output
+	FollowSymlinks    bool   `toml:"follow_symlinks"`    // Follow symbolic links
+	IgnorePermissions bool   `toml:"ignore_permissions"` // Ignore file permission differences
+	PreserveMetadata  bool   `toml:"preserve_metadata"`  // Preserve modification times and ownership on copy
+	IgnoreCase        bool   `toml:"ignore_case"`        // Match paths that differ only in letter case
+	Context           *int   `toml:"context"`            // Lines of diff context (nil = DefaultContextLines)
+	IORetries         int    `toml:"io_retries"`         // Retries for transient copy and delete failures
+	DiffCommand       string `toml:"diff_command"`       // External diff program and arguments (empty = diff or colordiff)
+	Theme             string `toml:"theme"`              // Color theme: dark, light or nocolor (empty = dark)
+	PostApplyHook     string `toml:"post_apply_hook"`    // Shell command run after a successful apply (empty = none)
+	TempDir           string `toml:"temp_dir"`           // Directory for temporary files (empty = system default)
+
+	// ActionHeaderTemplate is a preamble written at the top of generated
+	// action files: a path to a file holding it, or the text itself
+	ActionHeaderTemplate string `toml:"action_header_template"`
+}
+
+// DefaultContextLines is the number of unified diff context lines used when
+// general.context is not configured
+const DefaultContextLines = 3
+
+// TempDirEnv names the environment variable that overrides general.temp_dir
+const TempDirEnv = "DOVETAIL_TMPDIR"
+
+// TempRoot returns the directory temporary files are created in: $DOVETAIL_TMPDIR
+// if set, then general.temp_dir, or "" for the system default
+func (g GeneralConfig) TempRoot() string {
+	if dir := os.Getenv(TempDirEnv); dir != "" {
+		return dir
+	}
+	return g.TempDir
+}
+
+// ContextLines returns the configured number of diff context lines
+func (g GeneralConfig) ContextLines() int {
+	if g.Context == nil {
+		return DefaultContextLines
+	}
+	return *g.Context
+}
+
+// ActionHeader returns the action file preamble: the contents of the file
+// named by action_header_template if there is one, or else its text as is
+func (g GeneralConfig) ActionHeader() (string, error) {
+	template := g.ActionHeaderTemplate
+	if template == "" || strings.Contains(template, "\n") {
+		return template, nil
+	}
+	info, err := os.Stat(template)
+	if err != nil || info.IsDir() {
+		return template, nil
+	}
+	data, err := os.ReadFile(template)
+	if err != nil {
+		return "", fmt.Errorf("failed to read action header template: %w", err)
+	}
+	return string(data), nil
 }
 
 // PerformanceConfig contains performance-related settings
 type PerformanceConfig struct {
-	ParallelWorkers int   `toml:"parallel_workers"` // Number of parallel workers (0 = auto)
-	MaxFileSize     int64 `toml:"max_file_size"`    // Maximum file size to hash in bytes (0 = no limit)
+	ParallelWorkers int    `toml:"parallel_workers"` // Number of parallel workers (0 = auto)
+	MaxFileSize     int64  `toml:"max_file_size"`    // Maximum file size to hash in bytes (0 = no limit)
+	HashCacheDir    string `toml:"hash_cache_dir"`   // Where hashes are cached between runs (empty = user cache dir)
 }
 
 // ExclusionsConfig contains file/directory exclusion patterns
 type ExclusionsConfig struct {
-	Names      []string `toml:"names"`      // File/directory names or glob patterns to exclude
-	Paths      []string `toml:"paths"`      // Relative paths to exclude
-	Extensions []string `toml:"extensions"` // File extensions to exclude (without dot)
+	Names       []string `toml:"names"`         // File/directory names or glob patterns to exclude
+	Paths       []string `toml:"paths"`         // Relative paths to exclude
+	Extensions  []string `toml:"extensions"`    // File extensions to exclude (without dot)
+	Regex       []string `toml:"regex"`         // Regular expressions matched against relative paths
+	Content     []string `toml:"content_regex"` // Regular expressions matched against the first bytes of files
+	Include     []string `toml:"include"`       // If set, only these relative paths (and their contents) are compared
+	UseDefaults bool     `toml:"use_defaults"`  // Also exclude DefaultExclusions
+}
+
+// DefaultExclusions lists the version control directories, OS metadata and
+// editor leftovers excluded when exclusions.use_defaults is enabled
+var DefaultExclusions = ExclusionsConfig{
+	Names: []string{
+		".git", ".hg", ".svn", // Version control metadata
+		".DS_Store", "._*", "Thumbs.db", "desktop.ini", // OS metadata
+		"*.swp", "*.swo", "*~", ".#*", // Editor swap and backup files
+	},
 }
 
 // GitignoreConfig contains gitignore-related settings
@@ -47,6 +133,7 @@ func NewDefaultConfig() *Config {
 			NoColor:           false,
 			FollowSymlinks:    false,
 			IgnorePermissions: false,
+			PreserveMetadata:  false,
 		},
 		Performance: PerformanceConfig{
 			ParallelWorkers: 0,       // Auto-detect CPU cores
@@ -56,6 +143,8 @@ func NewDefaultConfig() *Config {
 			Names:      []string{},
 			Paths:      []string{},
 			Extensions: []string{},
+			Regex:      []string{},
+			Include:    []string{},
 		},
 		Gitignore: GitignoreConfig{
 			Enabled:        false,
@@ -84,6 +173,44 @@ func (c *Config) MergeWith(other *Config) {
 	if other.General.IgnorePermissions {
 		c.General.IgnorePermissions = other.General.IgnorePermissions
 	}
+	if other.General.PreserveMetadata {
+		c.General.PreserveMetadata = other.General.PreserveMetadata
+	}
+	if other.General.IgnoreCase {
+		c.General.IgnoreCase = other.General.IgnoreCase
+	}
+	if other.General.Context != nil {
+		c.General.Context = other.General.Context
+	}
+	if other.General.IORetries != 0 {
+		c.General.IORetries = other.General.IORetries
+	}
+	if other.General.DiffCommand != "" {
+		c.General.DiffCommand = other.General.DiffCommand
+	}
+	if other.General.ActionHeaderTemplate != "" {
+		c.General.ActionHeaderTemplate = other.General.ActionHeaderTemplate
+	}
+	if other.General.Theme != "" {
+		c.General.Theme = other.General.Theme
+	}
+	if other.General.PostApplyHook != "" {
+		c.General.PostApplyHook = other.General.PostApplyHook
+	}
+	if other.General.TempDir != "" {
+		c.General.TempDir = other.General.TempDir
+	}
+
+	// Merge color overrides key by key
+	for name, color := range other.Colors {
+		if c.Colors == nil {
+			c.Colors = make(map[string]string)
+		}
+		c.Colors[name] = color
+	}
+
+	// Normalize rules from every file apply, in load order
+	c.Normalize = append(c.Normalize, other.Normalize...)
 
 	// Merge performance settings
 	if other.Performance.ParallelWorkers != 0 {
@@ -92,11 +219,20 @@ func (c *Config) MergeWith(other *Config) {
 	if other.Performance.MaxFileSize != 0 {
 		c.Performance.MaxFileSize = other.Performance.MaxFileSize
 	}
+	if other.Performance.HashCacheDir != "" {
+		c.Performance.HashCacheDir = other.Performance.HashCacheDir
+	}
 
 	// Merge exclusions (append, don't replace)
 	c.Exclusions.Names = append(c.Exclusions.Names, other.Exclusions.Names...)
 	c.Exclusions.Paths = append(c.Exclusions.Paths, other.Exclusions.Paths...)
 	c.Exclusions.Extensions = append(c.Exclusions.Extensions, other.Exclusions.Extensions...)
+	c.Exclusions.Regex = append(c.Exclusions.Regex, other.Exclusions.Regex...)
+	c.Exclusions.Content = append(c.Exclusions.Content, other.Exclusions.Content...)
+	c.Exclusions.Include = append(c.Exclusions.Include, other.Exclusions.Include...)
+	if other.Exclusions.UseDefaults {
+		c.Exclusions.UseDefaults = other.Exclusions.UseDefaults
+	}
 
 	// Merge gitignore settings
 	if other.Gitignore.Enabled {
@@ -113,8 +249,11 @@ func (c *Config) ToComparisonOptions() ComparisonOptions {
 		ExcludeNames:      c.Exclusions.Names,
 		ExcludePaths:      c.Exclusions.Paths,
 		ExcludeExtensions: c.Exclusions.Extensions,
+		ExcludeRegex:      c.Exclusions.Regex,
+		IncludePaths:      c.Exclusions.Include,
 		FollowSymlinks:    c.General.FollowSymlinks,
 		IgnorePermissions: c.General.IgnorePermissions,
+		IgnoreCase:        c.General.IgnoreCase,
 		MaxFileSize:       c.Performance.MaxFileSize,
 		ParallelWorkers:   c.Performance.ParallelWorkers,
 	}
@@ -127,8 +266,11 @@ type ComparisonOptions struct {
 	ExcludeNames      []string
 	ExcludePaths      []string
 	ExcludeExtensions []string
+	ExcludeRegex      []string
+	IncludePaths      []string
 	FollowSymlinks    bool
 	IgnorePermissions bool
+	IgnoreCase        bool
 	MaxFileSize       int64
 	ParallelWorkers   int
 }
@@ -138,6 +280,11 @@ type ConfigPath struct {
 	Path     string
 	Priority int // Lower numbers = higher priority
 	Source   string
+
+	// UserLevel marks files the user chose (an explicit path or the home
+	// directory) rather than ones found around the working directory, which may come
+	// with a checked-out project. Only these may set post_apply_hook.
+	UserLevel bool
 }
 
 // GetConfigSearchPaths returns the paths to search for config files in priority order
@@ -147,19 +294,29 @@ func GetConfigSearchPaths(explicitPath string) []ConfigPath {
 	// 1. Explicit path from --config flag (highest priority)
 	if explicitPath != "" {
 		paths = append(paths, ConfigPath{
-			Path:     explicitPath,
-			Priority: 1,
-			Source:   "command line --config",
+			Path:      explicitPath,
+			Priority:  1,
+			Source:    "command line --config",
+			UserLevel: true,
 		})
 	}
 
+	// The home file is only read as user-level config, even when the working
+	// directory or one of its parents is the home directory
+	homePath := ""
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		homePath = filepath.Join(homeDir, ".dovetail.toml")
+	}
+
 	// 2. Current directory .dovetail.toml
 	if cwd, err := filepath.Abs("."); err == nil {
-		paths = append(paths, ConfigPath{
-			Path:     filepath.Join(cwd, ".dovetail.toml"),
-			Priority: 2,
-			Source:   "current directory",
-		})
+		if path := filepath.Join(cwd, ".dovetail.toml"); path != homePath {
+			paths = append(paths, ConfigPath{
+				Path:     path,
+				Priority: 2,
+				Source:   "current directory",
+			})
+		}
 	}
 
 	// 3. Walk up parent directories looking for .dovetail.toml
@@ -172,21 +329,24 @@ func GetConfigSearchPaths(explicitPath string) []ConfigPath {
 				break // Reached root
 			}
 			dir = parent
-			paths = append(paths, ConfigPath{
-				Path:     filepath.Join(dir, ".dovetail.toml"),
-				Priority: priority,
-				Source:   "parent directory",
-			})
+			if path := filepath.Join(dir, ".dovetail.toml"); path != homePath {
+				paths = append(paths, ConfigPath{
+					Path:     path,
+					Priority: priority,
+					Source:   "parent directory",
+				})
+			}
 			priority++
 		}
 	}
 
 	// 4. Home directory ~/.dovetail.toml (lowest priority)
-	if homeDir, err := filepath.Abs("~"); err == nil {
+	if homePath != "" {
 		paths = append(paths, ConfigPath{
-			Path:     filepath.Join(homeDir, ".dovetail.toml"),
-			Priority: 100,
-			Source:   "home directory",
+			Path:      homePath,
+			Priority:  100,
+			Source:    "home directory",
+			UserLevel: true,
 		})
 	}
 
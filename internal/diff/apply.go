@@ -0,0 +1,90 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ApplyHunks applies unified diff hunks to text, as patch would. Every
+// context and removed line must match exactly; a hunk whose lines have moved
+// since the diff was made is applied at the nearest position where they match.
+// A hunk ending at the end of the text with a no-newline marker decides
+// whether the result ends with a newline.
+func ApplyHunks(text string, hunks []Hunk) (string, error) {
+	trailingNewline := text == "" || strings.HasSuffix(text, "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+
+	var out []string
+	pos := 0 // Next unconsumed line of text
+	var oldNoNewline, newNoNewline bool
+	for n, hunk := range hunks {
+		var old, replacement []string
+		oldNoNewline, newNoNewline = false, false
+		for _, line := range hunk.Lines {
+			if line.Kind != LineAdded {
+				old = append(old, line.Text)
+				oldNoNewline = line.NoNewline
+			}
+			if line.Kind != LineRemoved {
+				replacement = append(replacement, line.Text)
+				newNoNewline = line.NoNewline
+			}
+		}
+
+		// A hunk that removes nothing starts after its start line
+		want := hunk.LeftStart - 1
+		if hunk.LeftCount == 0 {
+			want = hunk.LeftStart
+		}
+		start, ok := findLines(lines, old, pos, want)
+		if !ok {
+			return "", fmt.Errorf("hunk %d (%s) does not apply: its lines were not found", n+1, hunk.Header)
+		}
+
+		out = append(out, lines[pos:start]...)
+		out = append(out, replacement...)
+		pos = start + len(old)
+	}
+	if pos == len(lines) && (oldNoNewline || newNoNewline) {
+		trailingNewline = !newNoNewline
+	}
+	out = append(out, lines[pos:]...)
+
+	if len(out) == 0 {
+		return "", nil
+	}
+	result := strings.Join(out, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// findLines returns the index at or after from where want appears in lines,
+// preferring the index closest to near
+func findLines(lines, want []string, from, near int) (int, bool) {
+	matches := func(start int) bool {
+		if start < from || start+len(want) > len(lines) {
+			return false
+		}
+		for i, line := range want {
+			if lines[start+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+
+	for offset := 0; near-offset >= from || near+offset <= len(lines); offset++ {
+		if matches(near + offset) {
+			return near + offset, true
+		}
+		if offset > 0 && matches(near-offset) {
+			return near - offset, true
+		}
+	}
+	return 0, false
+}
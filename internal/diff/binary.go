@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// binarySniffSize is how much of a file is inspected when detecting binary content
+const binarySniffSize = 8000
+
+// IsBinary reports whether the file at path looks like binary content, using
+// the same heuristic as git: a NUL byte within the first few kilobytes
+func IsBinary(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return bytes.IndexByte(buf[:n], 0) >= 0, nil
+}
+
+// BinaryStat summarizes how two binary files differ
+type BinaryStat struct {
+	LeftSize        int64
+	RightSize       int64
+	FirstDiffOffset int64 // Offset of the first differing byte, -1 if the common prefix is identical
+	DifferingBytes  int64 // Differing bytes within the first min(LeftSize, RightSize) bytes
+	Compared        int64 // Bytes compared, min(LeftSize, RightSize)
+}
+
+// ErrTooLarge is returned by CompareBinary when a file exceeds the size limit
+var ErrTooLarge = errors.New("file exceeds the maximum size for byte comparison")
+
+// CompareBinary streams both files and counts the bytes that differ at the
+// same offset. Files larger than maxSize (0 = no limit) are not read.
+func CompareBinary(leftPath, rightPath string, maxSize int64) (*BinaryStat, error) {
+	left, err := os.Open(leftPath)
+	if err != nil {
+		return nil, err
+	}
+	defer left.Close()
+	right, err := os.Open(rightPath)
+	if err != nil {
+		return nil, err
+	}
+	defer right.Close()
+
+	leftInfo, err := left.Stat()
+	if err != nil {
+		return nil, err
+	}
+	rightInfo, err := right.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	stat := &BinaryStat{
+		LeftSize:        leftInfo.Size(),
+		RightSize:       rightInfo.Size(),
+		FirstDiffOffset: -1,
+	}
+	if maxSize > 0 && (stat.LeftSize > maxSize || stat.RightSize > maxSize) {
+		return stat, ErrTooLarge
+	}
+
+	leftReader := bufio.NewReader(left)
+	rightReader := bufio.NewReader(right)
+	for {
+		a, errA := leftReader.ReadByte()
+		b, errB := rightReader.ReadByte()
+		if errA == io.EOF || errB == io.EOF {
+			return stat, nil
+		}
+		if errA != nil {
+			return nil, errA
+		}
+		if errB != nil {
+			return nil, errB
+		}
+		if a != b {
+			if stat.FirstDiffOffset < 0 {
+				stat.FirstDiffOffset = stat.Compared
+			}
+			stat.DifferingBytes++
+		}
+		stat.Compared++
+	}
+}
@@ -0,0 +1,79 @@
+package diff
+
+import "strings"
+
+// Conflict marker lines, as git writes them
+const (
+	conflictStart  = "<<<<<<< "
+	conflictMiddle = "======="
+	conflictEnd    = ">>>>>>> "
+)
+
+// ConflictMarked rebuilds left with every changed region of the unified diff
+// hunks replaced by a git-style conflict block holding both versions. It
+// returns the merged text and the number of conflict blocks.
+func ConflictMarked(left string, hunks []Hunk, leftLabel, rightLabel string) (string, int) {
+	leftLines := strings.Split(strings.TrimSuffix(left, "\n"), "\n")
+	if left == "" {
+		leftLines = nil
+	}
+
+	var b strings.Builder
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+
+	conflicts := 0
+	pos := 0 // Next left line to copy
+	for _, hunk := range hunks {
+		// A hunk that removes nothing starts after its start line
+		start := hunk.LeftStart - 1
+		if hunk.LeftCount == 0 {
+			start = hunk.LeftStart
+		}
+		if start > len(leftLines) {
+			start = len(leftLines)
+		}
+		if start > pos {
+			writeLines(leftLines[pos:start])
+			pos = start
+		}
+
+		var removed, added []string
+		flush := func() {
+			if len(removed) == 0 && len(added) == 0 {
+				return
+			}
+			b.WriteString(conflictStart + leftLabel + "\n")
+			writeLines(removed)
+			b.WriteString(conflictMiddle + "\n")
+			writeLines(added)
+			b.WriteString(conflictEnd + rightLabel + "\n")
+			conflicts++
+			pos += len(removed)
+			removed, added = nil, nil
+		}
+
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case LineRemoved:
+				removed = append(removed, line.Text)
+			case LineAdded:
+				added = append(added, line.Text)
+			default:
+				flush()
+				writeLines([]string{line.Text})
+				pos++
+			}
+		}
+		flush()
+	}
+	if pos < len(leftLines) {
+		writeLines(leftLines[pos:])
+	}
+
+	return b.String(), conflicts
+}
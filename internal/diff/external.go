@@ -0,0 +1,33 @@
+package diff
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CheckCommand reports an error if a configured diff command, such as
+// "delta --side-by-side", can't be found. An empty command is valid.
+func CheckCommand(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return fmt.Errorf("diff command %q not found: %w", fields[0], err)
+	}
+	return nil
+}
+
+// ConfiguredCommand builds the configured diff command with args appended to
+// its own, or returns nil if command is empty or the program can't be found
+func ConfiguredCommand(command string, args ...string) *exec.Cmd {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil
+	}
+	return exec.Command(fields[0], append(fields[1:], args...)...)
+}
@@ -0,0 +1,189 @@
+package diff
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ANSIEscape matches ANSI color/style escape sequences, such as those
+// emitted by colordiff
+var ANSIEscape = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+// StripANSI removes ANSI escape sequences from a string
+func StripANSI(s string) string {
+	return ANSIEscape.ReplaceAllString(s, "")
+}
+
+// hunkHeaderRegex matches unified diff hunk headers: @@ -l,s +r,s @@
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// LineKind identifies the type of a line within a hunk
+type LineKind int
+
+const (
+	LineContext LineKind = iota // Unchanged line present on both sides
+	LineRemoved                 // Line only on the left side
+	LineAdded                   // Line only on the right side
+)
+
+// Line is a single line of a hunk, without its leading marker
+type Line struct {
+	Kind      LineKind
+	Text      string
+	NoNewline bool // Followed by "\ No newline at end of file": the line ends its file without a newline
+}
+
+// noNewlineMarker follows a line that isn't terminated by a newline
+const noNewlineMarker = "\\ No newline at end of file"
+
+// marker returns the unified diff prefix of a line of this kind
+func (k LineKind) marker() string {
+	switch k {
+	case LineRemoved:
+		return "-"
+	case LineAdded:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Hunk is one hunk of a unified diff
+type Hunk struct {
+	Header     string // Full "@@ ... @@" header line
+	LeftStart  int    // First line number in the left file
+	LeftCount  int    // Number of left lines covered
+	RightStart int    // First line number in the right file
+	RightCount int    // Number of right lines covered
+	Lines      []Line
+}
+
+// String reconstructs the hunk as unified diff text, including its header
+// and any no-newline markers
+func (h Hunk) String() string {
+	var b strings.Builder
+	b.WriteString(h.Header + "\n")
+	for _, line := range h.Lines {
+		b.WriteString(line.Kind.marker() + line.Text + "\n")
+		if line.NoNewline {
+			b.WriteString(noNewlineMarker + "\n")
+		}
+	}
+	return b.String()
+}
+
+// ParseHunkHeader parses a "@@ -l,s +r,s @@" line (without ANSI colors) into
+// a hunk with no lines yet
+func ParseHunkHeader(line string) (Hunk, bool) {
+	matches := hunkHeaderRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return Hunk{}, false
+	}
+	return Hunk{
+		Header:     line,
+		LeftStart:  atoiDefault(matches[1], 0),
+		LeftCount:  atoiDefault(matches[2], 1),
+		RightStart: atoiDefault(matches[3], 0),
+		RightCount: atoiDefault(matches[4], 1),
+	}, true
+}
+
+// ParseHunks parses unified diff output (ANSI colors are ignored) into hunks.
+// Lines are read only while the header's line counts say the hunk continues,
+// so file headers that follow a hunk aren't mistaken for removed lines. Inside
+// a hunk, an empty line is an empty context line whose leading space was
+// dropped, as some tools do.
+func ParseHunks(diffText string) []Hunk {
+	var hunks []Hunk
+	var current *Hunk
+	left, right := 0, 0 // Lines still expected in the current hunk
+
+	for _, rawLine := range strings.Split(diffText, "\n") {
+		line := StripANSI(rawLine)
+
+		// The marker may follow the last line of a hunk, after the counts run out
+		if current != nil && strings.HasPrefix(line, "\\") {
+			if n := len(current.Lines); n > 0 {
+				current.Lines[n-1].NoNewline = true
+			}
+			continue
+		}
+
+		if left == 0 && right == 0 {
+			if hunk, ok := ParseHunkHeader(line); ok {
+				hunks = append(hunks, hunk)
+				current = &hunks[len(hunks)-1]
+				left, right = current.LeftCount, current.RightCount
+			}
+			// Anything else between hunks is a file header or noise
+			continue
+		}
+
+		kind := LineContext
+		text := line
+		if line != "" {
+			text = line[1:]
+			switch line[0] {
+			case ' ':
+			case '-':
+				kind = LineRemoved
+			case '+':
+				kind = LineAdded
+			default:
+				continue // Not part of the diff
+			}
+		}
+
+		if kind != LineAdded {
+			left--
+		}
+		if kind != LineRemoved {
+			right--
+		}
+		current.Lines = append(current.Lines, Line{Kind: kind, Text: text})
+		if left < 0 || right < 0 {
+			// More lines than the header promised: stop here rather than desync
+			left, right = 0, 0
+		}
+	}
+
+	return hunks
+}
+
+// CountFiles counts the files a unified diff changes, by their "+++" headers.
+// Hunk bodies are skipped using the line counts in their headers, so content
+// lines that look like headers aren't counted.
+func CountFiles(diffText string) int {
+	files := 0
+	left, right := 0, 0 // Lines still expected in the current hunk
+	for _, line := range strings.Split(diffText, "\n") {
+		if left > 0 || right > 0 {
+			switch {
+			case strings.HasPrefix(line, "-"):
+				left--
+			case strings.HasPrefix(line, "+"):
+				right--
+			case strings.HasPrefix(line, "\\"): // "\ No newline at end of file"
+			default:
+				left--
+				right--
+			}
+			continue
+		}
+		if matches := hunkHeaderRegex.FindStringSubmatch(line); matches != nil {
+			left, right = atoiDefault(matches[2], 1), atoiDefault(matches[4], 1)
+		} else if strings.HasPrefix(line, "+++ ") {
+			files++
+		}
+	}
+	return files
+}
+
+// atoiDefault converts s to an int, returning def when s is empty or invalid
+func atoiDefault(s string, def int) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAndApplyHunksMissingFinalNewline(t *testing.T) {
+	tests := []struct {
+		name  string
+		old   string
+		new   string
+		diff  string // diff -u output for old and new
+		lines []Line
+	}{
+		{
+			name: "old side only",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			diff: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+			lines: []Line{
+				{Kind: LineContext, Text: "a"},
+				{Kind: LineRemoved, Text: "b", NoNewline: true},
+				{Kind: LineAdded, Text: "b"},
+			},
+		},
+		{
+			name: "new side only",
+			old:  "a\nb\n",
+			new:  "a\nb",
+			diff: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+			lines: []Line{
+				{Kind: LineContext, Text: "a"},
+				{Kind: LineRemoved, Text: "b"},
+				{Kind: LineAdded, Text: "b", NoNewline: true},
+			},
+		},
+		{
+			name: "both sides, last line changed",
+			old:  "a\nb",
+			new:  "a\nc",
+			diff: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+			lines: []Line{
+				{Kind: LineContext, Text: "a"},
+				{Kind: LineRemoved, Text: "b", NoNewline: true},
+				{Kind: LineAdded, Text: "c", NoNewline: true},
+			},
+		},
+		{
+			name: "both sides, last line unchanged",
+			old:  "a\nb\nc",
+			new:  "x\nb\nc",
+			diff: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n-a\n+x\n b\n c\n\\ No newline at end of file\n",
+			lines: []Line{
+				{Kind: LineRemoved, Text: "a"},
+				{Kind: LineAdded, Text: "x"},
+				{Kind: LineContext, Text: "b"},
+				{Kind: LineContext, Text: "c", NoNewline: true},
+			},
+		},
+		{
+			name: "neither side",
+			old:  "a\nb\n",
+			new:  "a\nc\n",
+			diff: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			lines: []Line{
+				{Kind: LineContext, Text: "a"},
+				{Kind: LineRemoved, Text: "b"},
+				{Kind: LineAdded, Text: "c"},
+			},
+		},
+		{
+			name: "removed line looking like a file header",
+			old:  "a\n-- b\n",
+			new:  "a\n",
+			diff: "--- old\n+++ new\n@@ -1,2 +1 @@\n a\n--- b\n",
+			lines: []Line{
+				{Kind: LineContext, Text: "a"},
+				{Kind: LineRemoved, Text: "-- b"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := ParseHunks(tt.diff)
+			if len(hunks) != 1 {
+				t.Fatalf("ParseHunks returned %d hunks, want 1", len(hunks))
+			}
+			if !reflect.DeepEqual(hunks[0].Lines, tt.lines) {
+				t.Errorf("ParseHunks lines = %+v, want %+v", hunks[0].Lines, tt.lines)
+			}
+
+			got, err := ApplyHunks(tt.old, hunks)
+			if err != nil {
+				t.Fatalf("ApplyHunks: %v", err)
+			}
+			if got != tt.new {
+				t.Errorf("ApplyHunks = %q, want %q", got, tt.new)
+			}
+
+			// Hunk.String keeps the markers, so the hunk reparses the same
+			if reparsed := ParseHunks(hunks[0].String()); !reflect.DeepEqual(reparsed, hunks) {
+				t.Errorf("reparsing Hunk.String gave %+v, want %+v", reparsed, hunks)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Package diff provides helpers for rendering file differences.
+package diff
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// ANSI escape sequences used for word-level highlighting
+const (
+	colorReset        = "\033[0m"
+	colorRemoved      = "\033[31m"
+	colorAdded        = "\033[32m"
+	colorHeader       = "\033[1m"
+	colorHunk         = "\033[36m"
+	colorRemovedWords = "\033[1;97;41m"
+	colorAddedWords   = "\033[1;97;42m"
+)
+
+// minSimilarity is the fraction of unchanged characters below which a line
+// pair is treated as a full-line change rather than highlighted word by word
+const minSimilarity = 0.4
+
+// HighlightWords renders unified diff output with changed runs inside paired
+// removed/added lines highlighted. Within each hunk, consecutive removed
+// lines are paired in order with the added lines that follow them.
+// When noColor is set, changes are marked as [-removed-] and {+added+}.
+func HighlightWords(unified string, noColor bool) string {
+	var b strings.Builder
+	var removed, added []string
+
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			switch {
+			case i < len(removed) && i < len(added):
+				oldLine, newLine := highlightPair(removed[i], added[i], noColor)
+				writeLine(&b, "-", oldLine, colorRemoved, noColor)
+				writeLine(&b, "+", newLine, colorAdded, noColor)
+			case i < len(removed):
+				writeLine(&b, "-", removed[i], colorRemoved, noColor)
+			default:
+				writeLine(&b, "+", added[i], colorAdded, noColor)
+			}
+		}
+		removed, added = nil, nil
+	}
+
+	left, right := 0, 0 // Lines still expected in the current hunk
+	for _, line := range strings.Split(strings.TrimSuffix(unified, "\n"), "\n") {
+		// Hunks end when their header's line counts run out, as in
+		// ParseHunks, so a removed "--..." line isn't taken for a file header
+		if left <= 0 && right <= 0 {
+			flush()
+			if hunk, ok := ParseHunkHeader(line); ok {
+				left, right = hunk.LeftCount, hunk.RightCount
+				writeLine(&b, "", line, colorHunk, noColor)
+			} else if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
+				writeLine(&b, "", line, colorHeader, noColor)
+			} else {
+				writeLine(&b, "", line, "", noColor)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "-"):
+			if len(added) > 0 {
+				flush()
+			}
+			removed = append(removed, line[1:])
+			left--
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+			right--
+		case strings.HasPrefix(line, "\\"):
+			// A no-newline marker isn't a line of either file
+			flush()
+			writeLine(&b, "", line, "", noColor)
+		default:
+			flush()
+			writeLine(&b, "", line, "", noColor)
+			left--
+			right--
+		}
+	}
+	flush()
+
+	return b.String()
+}
+
+// highlightPair computes a character-level diff between two lines and returns
+// both lines with their changed runs marked
+func highlightPair(oldLine, newLine string, noColor bool) (string, string) {
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(oldLine, newLine, false))
+
+	common := 0
+	for _, d := range diffs {
+		if d.Type == diffmatchpatch.DiffEqual {
+			common += len(d.Text)
+		}
+	}
+	longest := len(oldLine)
+	if len(newLine) > longest {
+		longest = len(newLine)
+	}
+	if longest == 0 || float64(common)/float64(longest) < minSimilarity {
+		// Mostly different; word highlighting would just be noise
+		return oldLine, newLine
+	}
+
+	var oldOut, newOut strings.Builder
+	for _, d := range diffs {
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			oldOut.WriteString(d.Text)
+			newOut.WriteString(d.Text)
+		case diffmatchpatch.DiffDelete:
+			if noColor {
+				oldOut.WriteString("[-" + d.Text + "-]")
+			} else {
+				oldOut.WriteString(colorRemovedWords + d.Text + colorReset + colorRemoved)
+			}
+		case diffmatchpatch.DiffInsert:
+			if noColor {
+				newOut.WriteString("{+" + d.Text + "+}")
+			} else {
+				newOut.WriteString(colorAddedWords + d.Text + colorReset + colorAdded)
+			}
+		}
+	}
+
+	return oldOut.String(), newOut.String()
+}
+
+// writeLine writes a diff line with its marker, wrapped in color unless disabled
+func writeLine(b *strings.Builder, marker, text, color string, noColor bool) {
+	if noColor || color == "" {
+		b.WriteString(marker + text + "\n")
+		return
+	}
+	b.WriteString(color + marker + text + colorReset + "\n")
+}
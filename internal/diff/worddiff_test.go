@@ -0,0 +1,40 @@
+package diff
+
+import "testing"
+
+func TestHighlightWordsCountsHunkLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		unified string
+		want    string
+	}{
+		{
+			name:    "removed line starting with dashes",
+			unified: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n--- x = 1\n+-- x = 2\n",
+			want:    "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n--- x = [-1-]\n+-- x = {+2+}\n",
+		},
+		{
+			name:    "added line starting with plus signs",
+			unified: "--- old\n+++ new\n@@ -1 +1 @@\n-++ count\n+++ counter\n",
+			want:    "--- old\n+++ new\n@@ -1 +1 @@\n-++ count\n+++ count{+er+}\n",
+		},
+		{
+			name:    "file header after a hunk",
+			unified: "--- a\n+++ a\n@@ -1 +1 @@\n-one\n+two\n--- b\n+++ b\n@@ -1 +1 @@\n-three\n+four\n",
+			want:    "--- a\n+++ a\n@@ -1 +1 @@\n-one\n+two\n--- b\n+++ b\n@@ -1 +1 @@\n-three\n+four\n",
+		},
+		{
+			name:    "no newline marker",
+			unified: "--- a\n+++ b\n@@ -1 +1 @@\n-value one\n\\ No newline at end of file\n+value two\n",
+			want:    "--- a\n+++ b\n@@ -1 +1 @@\n-value one\n\\ No newline at end of file\n+value two\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HighlightWords(tt.unified, true); got != tt.want {
+				t.Errorf("HighlightWords =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
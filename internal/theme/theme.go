@@ -0,0 +1,204 @@
+// Package theme defines the color palettes used by the TUI and the diff
+// command.
+package theme
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Palette holds the colors dovetail renders with. Each color is an ANSI
+// 256-color index ("0" to "255") or a "#rrggbb" hex value; an empty color
+// leaves the text in the terminal's default color.
+type Palette struct {
+	// File statuses
+	Modified  string
+	OnlyLeft  string
+	OnlyRight string
+	Identical string
+
+	// Actions
+	Copy   string // Copies and permission changes
+	Delete string
+	Ignore string
+
+	// Text
+	Header   string // Titles and section headings
+	Path     string // File headings in diff output
+	Muted    string // Secondary information and help
+	Warning  string // Prompts and warnings
+	Error    string
+	Mark     string // Marked files in the TUI
+	Added    string // Added diff lines
+	Removed  string // Removed diff lines
+	Hunk     string // Diff hunk headers
+	Newer    string // Size of the more recently modified side
+	Selected string // Text of the selected row
+	Cursor   string // Background of the selected row
+
+	// Plain is set for the nocolor theme, where no colors should be
+	// emitted at all
+	Plain bool
+}
+
+// DefaultName is the theme used when none is configured
+const DefaultName = "dark"
+
+// Names lists the built-in themes
+var Names = []string{"dark", "light", "nocolor"}
+
+// dark suits terminals with a dark background
+var dark = Palette{
+	Modified:  "11", // Yellow
+	OnlyLeft:  "9",  // Red
+	OnlyRight: "10", // Green
+	Identical: "8",  // Gray
+	Copy:      "12", // Blue
+	Delete:    "9",
+	Ignore:    "8",
+	Header:    "12",
+	Path:      "11",
+	Muted:     "8",
+	Warning:   "11",
+	Error:     "9",
+	Mark:      "13", // Magenta
+	Added:     "10",
+	Removed:   "9",
+	Hunk:      "14", // Cyan
+	Newer:     "10",
+	Selected:  "15", // White
+	Cursor:    "8",
+}
+
+// light uses the darker normal-intensity colors, which stay readable on a
+// light background where the bright ones wash out
+var light = Palette{
+	Modified:  "130", // Dark orange
+	OnlyLeft:  "1",   // Red
+	OnlyRight: "2",   // Green
+	Identical: "244", // Gray
+	Copy:      "4",   // Blue
+	Delete:    "1",
+	Ignore:    "244",
+	Header:    "4",
+	Path:      "130",
+	Muted:     "242",
+	Warning:   "130",
+	Error:     "1",
+	Mark:      "5", // Magenta
+	Added:     "2",
+	Removed:   "1",
+	Hunk:      "6", // Cyan
+	Newer:     "2",
+	Selected:  "0",   // Black
+	Cursor:    "252", // Light gray
+}
+
+// Default returns the palette of the default theme
+func Default() Palette {
+	return dark
+}
+
+// New returns the named built-in theme ("" for DefaultName) with overrides
+// applied. Overrides map color names, as listed by ColorNames, to colors.
+func New(name string, overrides map[string]string) (Palette, error) {
+	var palette Palette
+	switch name {
+	case "", "dark":
+		palette = dark
+	case "light":
+		palette = light
+	case "nocolor":
+		palette = Palette{Plain: true}
+	default:
+		return Palette{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(Names, ", "))
+	}
+
+	fields := palette.fields()
+	for key, color := range overrides {
+		field, ok := fields[key]
+		if !ok {
+			return Palette{}, fmt.Errorf("unknown color %q (available: %s)", key, strings.Join(ColorNames(), ", "))
+		}
+		if !validColor(color) {
+			return Palette{}, fmt.Errorf("invalid color %q for %s: use 0-255, #rrggbb or \"\"", color, key)
+		}
+		*field = color
+	}
+	return palette, nil
+}
+
+// fields maps each overridable color name to its field in p
+func (p *Palette) fields() map[string]*string {
+	return map[string]*string{
+		"modified":   &p.Modified,
+		"only_left":  &p.OnlyLeft,
+		"only_right": &p.OnlyRight,
+		"identical":  &p.Identical,
+		"copy":       &p.Copy,
+		"delete":     &p.Delete,
+		"ignore":     &p.Ignore,
+		"header":     &p.Header,
+		"path":       &p.Path,
+		"muted":      &p.Muted,
+		"warning":    &p.Warning,
+		"error":      &p.Error,
+		"mark":       &p.Mark,
+		"added":      &p.Added,
+		"removed":    &p.Removed,
+		"hunk":       &p.Hunk,
+		"newer":      &p.Newer,
+		"selected":   &p.Selected,
+		"cursor":     &p.Cursor,
+	}
+}
+
+// ColorNames returns the names accepted as color overrides, sorted
+func ColorNames() []string {
+	var names []string
+	for name := range (&Palette{}).fields() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// validColor reports whether color is empty, a 256-color index or hex value
+func validColor(color string) bool {
+	if color == "" || hexColor.MatchString(color) {
+		return true
+	}
+	n, err := strconv.Atoi(color)
+	return err == nil && n >= 0 && n <= 255
+}
+
+// Paint wraps text in the ANSI escape sequence for color, bold if asked.
+// The text is returned as is, without bold, for the plain palette.
+func (p Palette) Paint(color, text string, bold bool) string {
+	if p.Plain {
+		return text
+	}
+	var params []string
+	if bold {
+		params = append(params, "1")
+	}
+	if color != "" {
+		if strings.HasPrefix(color, "#") {
+			r, _ := strconv.ParseUint(color[1:3], 16, 8)
+			g, _ := strconv.ParseUint(color[3:5], 16, 8)
+			b, _ := strconv.ParseUint(color[5:7], 16, 8)
+			params = append(params, fmt.Sprintf("38;2;%d;%d;%d", r, g, b))
+		} else {
+			params = append(params, "38;5;"+color)
+		}
+	}
+	if len(params) == 0 {
+		return text
+	}
+	return "\033[" + strings.Join(params, ";") + "m" + text + "\033[0m"
+}
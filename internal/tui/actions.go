@@ -0,0 +1,217 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/theme"
+	"github.com/harikb/dovetail/internal/util"
+)
+
+// bulkActionPrompt is a bulk action waiting for confirmation
+type bulkActionPrompt struct {
+	label       string                       // Action shown in the prompt, e.g. "[>]"
+	assignments map[string]action.ActionType // Visible paths where the action is valid
+	skipped     int                          // Visible paths where it is not valid
+}
+
+// initializeDefaultActions sets every file to ignore, the safe default
+func (m *Model) initializeDefaultActions() {
+	m.fileActions = make(map[string]action.ActionType, len(m.results))
+	for _, result := range m.results {
+		m.fileActions[result.RelativePath] = action.ActionIgnore
+	}
+}
+
+// isActionValid reports whether an action makes sense for a file status.
+// Deletes are only offered when one side is missing, so a file that exists
+// on both sides can never be deleted from the TUI.
+func isActionValid(act action.ActionType, status compare.FileStatus) bool {
+	switch act {
+	case action.ActionCopyToRight, action.ActionSymlinkToRight:
+		return status == compare.StatusOnlyLeft || status == compare.StatusModified
+	case action.ActionCopyToLeft, action.ActionSymlinkToLeft:
+		return status == compare.StatusOnlyRight || status == compare.StatusModified
+	case action.ActionDeleteLeft:
+		return status == compare.StatusOnlyLeft
+	case action.ActionDeleteRight:
+		return status == compare.StatusOnlyRight
+	case action.ActionChmodToRight, action.ActionChmodToLeft:
+		return status == compare.StatusModified
+	case action.ActionMoveRight, action.ActionMoveLeft:
+		return status == compare.StatusRenamed
+	case action.ActionIgnore:
+		return true
+	default:
+		return false
+	}
+}
+
+// deleteActionFor returns the delete action for the side that holds the file
+func deleteActionFor(status compare.FileStatus) action.ActionType {
+	if status == compare.StatusOnlyRight {
+		return action.ActionDeleteRight
+	}
+	return action.ActionDeleteLeft
+}
+
+// ResumeActions pre-populates actions from a previously saved action file.
+// Ignored entries are skipped; entries whose path no longer differs, or whose
+// action is no longer valid for the path's status, are dropped and described
+// in the returned list.
+func (a *App) ResumeActions(items []action.ActionItem) (restored int, dropped []string) {
+	m := &a.model
+	statuses := make(map[string]compare.FileStatus, len(m.results))
+	for _, result := range m.results {
+		statuses[result.RelativePath] = result.Status
+	}
+
+	for _, item := range items {
+		if item.Action == action.ActionIgnore {
+			continue
+		}
+		status, ok := statuses[item.RelativePath]
+		if !ok {
+			dropped = append(dropped, fmt.Sprintf("%s (no longer differs)", item.RelativePath))
+			continue
+		}
+		if !isActionValid(item.Action, status) {
+			dropped = append(dropped, fmt.Sprintf("%s ([%s] is not valid for %s)", item.RelativePath, item.Action, status))
+			continue
+		}
+		m.fileActions[item.RelativePath] = item.Action
+		restored++
+	}
+
+	return restored, dropped
+}
+
+// setAction sets the action for the selected file if it is valid for its status
+func (m *Model) setAction(act action.ActionType) {
+	result, ok := m.selectedResult()
+	if !ok {
+		return
+	}
+	act = action.SymlinkCopyAction(act, result)
+	if !isActionValid(act, result.Status) {
+		m.statusMessage = fmt.Sprintf("[%s] is not valid for %s files", act, result.Status)
+		return
+	}
+	// Acting on outdated results could copy or delete the wrong content
+	if act != action.ActionIgnore {
+		if warning := m.staleWarning(result); warning != "" {
+			m.statusMessage = warning
+			return
+		}
+	}
+	if m.fileActions[result.RelativePath] != act {
+		m.fileActions[result.RelativePath] = act
+		m.hasUnsavedChanges = true
+	}
+}
+
+// prepareBulkAction builds a confirmation prompt for applying an action to
+// every visible file. resolve returns the concrete action for a file status.
+func (m *Model) prepareBulkAction(label string, resolve func(compare.FileStatus) action.ActionType) {
+	prompt := &bulkActionPrompt{
+		label:       label,
+		assignments: make(map[string]action.ActionType),
+	}
+	for _, index := range m.visible {
+		result := m.results[index]
+		act := action.SymlinkCopyAction(resolve(result.Status), result)
+		if isActionValid(act, result.Status) {
+			prompt.assignments[result.RelativePath] = act
+		} else {
+			prompt.skipped++
+		}
+	}
+
+	if len(prompt.assignments) == 0 {
+		m.statusMessage = fmt.Sprintf("%s is not valid for any of the %d visible files", label, len(m.visible))
+		return
+	}
+	m.pendingBulk = prompt
+}
+
+// confirmBulkAction applies the pending bulk action
+func (m *Model) confirmBulkAction() {
+	prompt := m.pendingBulk
+	m.pendingBulk = nil
+
+	for path, act := range prompt.assignments {
+		if m.fileActions[path] != act {
+			m.fileActions[path] = act
+			m.hasUnsavedChanges = true
+		}
+	}
+
+	m.statusMessage = fmt.Sprintf("Set %s on %d files", prompt.label, len(prompt.assignments))
+	if prompt.skipped > 0 {
+		m.statusMessage += fmt.Sprintf(", skipped %d where it is not valid", prompt.skipped)
+	}
+}
+
+// saveActionFile writes the current actions to dovetail_actions_<timestamp>.txt
+// in the working directory and returns its path
+func (m *Model) saveActionFile() (string, error) {
+	path, err := filepath.Abs(fmt.Sprintf("dovetail_actions_%s.txt", time.Now().Format("20060102_150405")))
+	if err != nil {
+		return "", err
+	}
+
+	generator := action.NewGenerator(m.version)
+	generator.SetActions(m.fileActions)
+	generator.SetHeaderTemplate(m.actionHeader)
+	err = util.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		return generator.GenerateActionFile(w, m.results, m.leftDir, m.rightDir, m.summary, false)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to write action file: %w", err)
+	}
+
+	m.hasUnsavedChanges = false
+	return path, nil
+}
+
+// saveAndQuit saves the action file and quits, or stays open to show the
+// error if saving fails
+func (m Model) saveAndQuit() (tea.Model, tea.Cmd) {
+	path, err := m.saveActionFile()
+	if err != nil {
+		m.statusMessage = fmt.Sprintf("Error: %v", err)
+		return m, nil
+	}
+	m.statusMessage = fmt.Sprintf("Saved action file: %s", path)
+	m.exitMessage = m.statusMessage
+	return m, tea.Quit
+}
+
+// countActions returns the number of files with an action other than ignore
+func (m Model) countActions() int {
+	count := 0
+	for _, act := range m.fileActions {
+		if act != action.ActionIgnore {
+			count++
+		}
+	}
+	return count
+}
+
+// getActionColor returns the palette color for an action
+func getActionColor(palette theme.Palette, act action.ActionType) string {
+	switch act {
+	case action.ActionCopyToRight, action.ActionCopyToLeft, action.ActionChmodToRight, action.ActionChmodToLeft,
+		action.ActionSymlinkToRight, action.ActionSymlinkToLeft, action.ActionMoveRight, action.ActionMoveLeft:
+		return palette.Copy
+	case action.ActionDeleteLeft, action.ActionDeleteRight, action.ActionDeleteBoth:
+		return palette.Delete
+	default:
+		return palette.Ignore
+	}
+}
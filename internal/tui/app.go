@@ -2,13 +2,21 @@ package tui
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/harikb/dovetail/internal/action"
 	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/config"
+	"github.com/harikb/dovetail/internal/diff"
+	"github.com/harikb/dovetail/internal/theme"
+	"github.com/harikb/dovetail/internal/util"
 )
 
 // App represents the main TUI application
@@ -18,28 +26,30 @@ type App struct {
 
 // NewApp creates a new TUI application
 func NewApp(results []compare.ComparisonResult, summary *compare.ComparisonSummary, leftDir, rightDir string) *App {
-	// Filter out identical files for the UI (focus on differences)
-	var filteredResults []compare.ComparisonResult
-	for _, result := range results {
-		if result.Status != compare.StatusIdentical {
-			filteredResults = append(filteredResults, result)
-		}
-	}
+	// Identical files are hidden until toggled on (focus on differences)
+	filteredResults, identicalResults := splitResults(results)
 
 	// Sort results with directory-aware sorting for better organization
 	sortResultsByDirectory(filteredResults)
 
 	model := Model{
-		results:      filteredResults,
-		summary:      summary,
-		leftDir:      leftDir,
-		rightDir:     rightDir,
-		cursor:       0,
-		showingDiff:  false,
-		currentDiff:  "",
-		windowWidth:  80,
-		windowHeight: 24,
+		results:          filteredResults,
+		identicalResults: identicalResults,
+		summary:          summary,
+		leftDir:          leftDir,
+		rightDir:         rightDir,
+		leftContent:      leftDir,
+		rightContent:     rightDir,
+		cursor:           0,
+		showingDiff:      false,
+		currentDiff:      "",
+		windowWidth:      80,
+		windowHeight:     24,
+		contextLines:     config.DefaultContextLines,
+		palette:          theme.Default(),
 	}
+	model.rebuildVisible()
+	model.initializeDefaultActions()
 
 	return &App{model: model}
 }
@@ -73,10 +83,193 @@ func sortResultsByDirectory(results []compare.ComparisonResult) {
 	})
 }
 
+// maxSkippedShown caps how many unreadable paths the header lists
+const maxSkippedShown = 3
+
+// SortMode controls the ordering of the file list
+type SortMode int
+
+const (
+	SortByPath    SortMode = iota // Directory-aware path order
+	SortByStatus                  // Grouped by comparison status
+	SortBySize                    // Largest left-side file first
+	SortByModTime                 // Most recently modified first
+)
+
+func (s SortMode) String() string {
+	switch s {
+	case SortByPath:
+		return "path"
+	case SortByStatus:
+		return "status"
+	case SortBySize:
+		return "size"
+	case SortByModTime:
+		return "mtime"
+	default:
+		return "unknown"
+	}
+}
+
+// next returns the sort mode that follows s in the cycle
+func (s SortMode) next() SortMode {
+	return (s + 1) % (SortByModTime + 1)
+}
+
+// StatusFilter restricts the file list to a single comparison status
+type StatusFilter int
+
+const (
+	FilterAll       StatusFilter = iota // Show every difference
+	FilterModified                      // Only MODIFIED entries
+	FilterOnlyLeft                      // Only ONLY_IN_LEFT entries
+	FilterOnlyRight                     // Only ONLY_IN_RIGHT entries
+)
+
+func (f StatusFilter) String() string {
+	switch f {
+	case FilterAll:
+		return "all"
+	case FilterModified:
+		return "modified"
+	case FilterOnlyLeft:
+		return "only-left"
+	case FilterOnlyRight:
+		return "only-right"
+	default:
+		return "unknown"
+	}
+}
+
+// next returns the filter that follows f in the cycle
+func (f StatusFilter) next() StatusFilter {
+	return (f + 1) % (FilterOnlyRight + 1)
+}
+
+// matches reports whether a result with the given status passes the filter
+func (f StatusFilter) matches(status compare.FileStatus) bool {
+	switch f {
+	case FilterModified:
+		return status == compare.StatusModified
+	case FilterOnlyLeft:
+		return status == compare.StatusOnlyLeft
+	case FilterOnlyRight:
+		return status == compare.StatusOnlyRight
+	default:
+		return true
+	}
+}
+
+// sortResults sorts comparison results in place according to the sort mode.
+// Ties are broken by directory-aware path order so the result is stable.
+func sortResults(results []compare.ComparisonResult, mode SortMode) {
+	sortResultsByDirectory(results)
+	if mode == SortByPath {
+		return
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		switch mode {
+		case SortByStatus:
+			return results[i].Status < results[j].Status
+		case SortBySize:
+			return resultSize(results[i]) > resultSize(results[j])
+		case SortByModTime:
+			return resultModTime(results[i]).After(resultModTime(results[j]))
+		}
+		return false
+	})
+}
+
+// resultSize returns the left file size, falling back to the right side when missing
+func resultSize(result compare.ComparisonResult) int64 {
+	if result.LeftInfo != nil {
+		return result.LeftInfo.Size
+	}
+	if result.RightInfo != nil {
+		return result.RightInfo.Size
+	}
+	return 0
+}
+
+// resultModTime returns the newest modification time across both sides
+func resultModTime(result compare.ComparisonResult) time.Time {
+	var modTime time.Time
+	if result.LeftInfo != nil {
+		modTime = result.LeftInfo.ModTime
+	}
+	if result.RightInfo != nil && result.RightInfo.ModTime.After(modTime) {
+		modTime = result.RightInfo.ModTime
+	}
+	return modTime
+}
+
+// SetIgnoreWhitespace controls whether diffs ignore whitespace changes
+func (a *App) SetIgnoreWhitespace(ignore bool) {
+	a.model.ignoreWhitespace = ignore
+}
+
+// SetContextLines sets the number of unified diff context lines
+func (a *App) SetContextLines(lines int) {
+	if lines < 0 {
+		lines = 0
+	}
+	a.model.contextLines = lines
+}
+
+// SetDiffCommand sets the external diff program, with any arguments, used in
+// place of colordiff or diff
+func (a *App) SetDiffCommand(command string) {
+	a.model.diffCommand = command
+}
+
+// SetActionHeader sets the custom preamble of saved action files
+func (a *App) SetActionHeader(template string) {
+	a.model.actionHeader = template
+}
+
+// SetContentDirs sets the directories diffs read file content from, for
+// sides whose files aren't on disk under leftDir and rightDir
+func (a *App) SetContentDirs(left, right string) {
+	a.model.leftContent = left
+	a.model.rightContent = right
+}
+
+// SetComparisonOptions records the options the results were produced with,
+// so refreshing compares with the same exclusions and engine settings
+func (a *App) SetComparisonOptions(options compare.ComparisonOptions) {
+	a.model.compareOptions = options
+}
+
+// SetTheme sets the colors the TUI is rendered with
+func (a *App) SetTheme(palette theme.Palette) {
+	a.model.palette = palette
+}
+
+// SetVersion sets the version recorded in saved action files
+func (a *App) SetVersion(version string) {
+	a.model.version = version
+}
+
+// SetApplySettings sets the [general] settings applying actions honors, as
+// apply does: preserve_metadata, io_retries and post_apply_hook
+func (a *App) SetApplySettings(general config.GeneralConfig) {
+	a.model.applySettings = general
+}
+
+// SetJournalDir sets the directory undo journals of applied actions go to
+func (a *App) SetJournalDir(dir string) {
+	a.model.journalDir = dir
+}
+
 // Run starts the TUI application
 func (a *App) Run() error {
 	p := tea.NewProgram(a.model, tea.WithAltScreen())
-	_, err := p.Run()
+	final, err := p.Run()
+	if model, ok := final.(Model); ok && model.exitMessage != "" {
+		// The alternate screen is gone by now, so repeat the message on stderr
+		fmt.Fprintln(os.Stderr, model.exitMessage)
+	}
 	return err
 }
 
@@ -86,12 +279,67 @@ type Model struct {
 	summary      *compare.ComparisonSummary
 	leftDir      string
 	rightDir     string
-	cursor       int    // Currently selected file index
-	showingDiff  bool   // Whether we're showing a diff or file list
-	currentDiff  string // Current diff content
-	windowWidth  int
-	windowHeight int
-	err          error
+	leftContent  string // Directory diffs read left files from (differs from leftDir for archives)
+	rightContent string // Directory diffs read right files from
+	palette      theme.Palette
+
+	compareOptions compare.ComparisonOptions // Options of the original comparison, reused on refresh
+	cursor         int                       // Currently selected index into visible
+	showingDiff    bool                      // Whether we're showing a diff or file list
+	currentDiff    string                    // Current diff content
+	windowWidth    int
+	windowHeight   int
+	err            error
+	sortMode       SortMode // Current file list ordering
+
+	statusFilter     StatusFilter               // Current file list status filter
+	showIdentical    bool                       // List identical entries too, dimmed
+	identicalResults []compare.ComparisonResult // Identical entries, while they are hidden
+	visible          []int                      // Indices into results that pass statusFilter, in display order
+
+	// Per-file actions
+	version           string                       // Tool version for saved action files
+	journalDir        string                       // Where applying writes its undo journal
+	applySettings     config.GeneralConfig         // Settings for the executor and post-apply hook
+	fileActions       map[string]action.ActionType // Action per relative path
+	hasUnsavedChanges bool                         // Whether actions changed since the last save
+	pendingBulk       *bulkActionPrompt            // Bulk action awaiting confirmation
+	marked            map[string]bool              // Paths marked with m; action keys apply to all of them
+	confirmQuit       bool                         // Whether the unsaved-changes quit prompt is shown
+	applyArmedAt      time.Time                    // When a was pressed once to apply; zero when not waiting
+	applying          bool                         // Whether actions are being applied in the background
+	statusMessage     string                       // One-shot message shown in the file list footer
+	exitMessage       string                       // Printed to stderr after the TUI exits
+
+	ignoreWhitespace bool   // Pass -w to diff
+	sideBySide       bool   // Render the diff as two columns
+	contextLines     int    // Lines of unified diff context (-U)
+	diffCommand      string // External diff program and arguments (empty = colordiff or diff)
+	actionHeader     string // Custom preamble of saved action files
+	reloadingDiff    bool   // Keep the scroll position when the next diff arrives
+	editPrompt       bool   // Waiting for l or r after e in the diff view
+
+	diffCache map[diffCacheKey][]byte // Output of diff runs, reused when revisiting a file
+
+	// Diff view scrolling
+	diffLines       []string // currentDiff split into lines
+	diffViewportTop int      // Index of the first visible diff line
+	diffRows        []sideBySideRow
+	diffGutters     []lineGutter // Line numbers beside each of diffLines
+	hideLineNumbers bool         // Leave out the line-number gutters
+
+	// Search within the diff view
+	searchActive   bool   // Whether the search prompt is accepting input
+	searchInput    string // Text typed into the search prompt
+	searchQuery    string // Last executed search query
+	diffMatches    []int  // Indices of diff lines (in the current layout) matching searchQuery
+	diffMatchIndex int    // Index into diffMatches of the current match
+
+	// Search within the file list
+	pathQuery       string      // Last executed path search
+	pathMatches     []pathMatch // Visible entries matching pathQuery, best first
+	pathMatchIndex  int         // Index into pathMatches of the current match
+	substringSearch bool        // Match paths by substring instead of fuzzily
 }
 
 // Init initializes the model (required by bubbletea)
@@ -111,11 +359,52 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleKeyPress(msg)
 
 	case diffLoadedMsg:
-		m.currentDiff = string(msg)
+		if msg.cacheable {
+			m.cacheDiff(msg.key, msg.output)
+		}
+		m.currentDiff = string(msg.output)
+		m.diffLines = strings.Split(strings.TrimRight(m.currentDiff, "\n"), "\n")
+		m.diffRows = buildSideBySideRows(diff.ParseHunks(m.currentDiff))
+		m.diffGutters = unifiedGutters(m.diffLines)
+		if m.reloadingDiff {
+			// Same file with different context: stay roughly in place
+			m.reloadingDiff = false
+			m.executeDiffSearch()
+			m.clampDiffViewport()
+		} else {
+			m.diffViewportTop = 0
+			m.searchQuery = ""
+			m.diffMatches = nil
+			m.diffMatchIndex = 0
+		}
 		m.showingDiff = true
 		return m, nil
 
+	case resultsRefreshedMsg:
+		m.applyRefresh(msg)
+		return m, nil
+
+	case applyExpiredMsg:
+		if m.applyArmedAt.Equal(msg.armedAt) {
+			m.applyArmedAt = time.Time{}
+		}
+		return m, nil
+
+	case applyFinishedMsg:
+		m.finishApply(msg)
+		return m, nil
+
+	case editorFinishedMsg:
+		if msg.err != nil {
+			m.statusMessage = fmt.Sprintf("Error: %s failed: %v", msg.name, msg.err)
+		}
+		// Show the edited file's new diff in place; its recorded hash is stale
+		m.invalidateDiffCache()
+		m.reloadingDiff = true
+		return m, m.loadDiff()
+
 	case diffErrorMsg:
+		m.reloadingDiff = false
 		m.err = error(msg)
 		m.showingDiff = true // Show the error in diff view
 		return m, nil
@@ -126,103 +415,488 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // handleKeyPress processes keyboard input
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "ctrl+c":
-		return m, tea.Quit
+	// Quitting or changing actions mid-apply could leave a copy half done
+	if m.applying {
+		m.statusMessage = "Applying actions, please wait..."
+		return m, nil
+	}
 
-	case "q":
-		if m.showingDiff {
-			// In diff view, q goes back to file list (same as esc)
-			m.showingDiff = false
-			m.currentDiff = ""
-			m.err = nil
+	if m.searchActive {
+		return m.handleSearchInput(msg)
+	}
+
+	if m.pendingBulk != nil {
+		if key := msg.String(); key == "y" || key == "Y" {
+			m.confirmBulkAction()
 		} else {
-			// In file list, q quits the application
+			m.pendingBulk = nil
+			m.statusMessage = "Bulk action cancelled"
+		}
+		return m, nil
+	}
+
+	if m.editPrompt {
+		return m.handleEditPrompt(msg)
+	}
+
+	if m.confirmQuit {
+		m.confirmQuit = false
+		switch msg.String() {
+		case "y", "Y", "ctrl+c":
 			return m, tea.Quit
+		case "s", "S":
+			return m.saveAndQuit()
 		}
+		return m, nil
+	}
+
+	if !m.applyArmedAt.IsZero() {
+		armedAt := m.applyArmedAt
+		m.applyArmedAt = time.Time{}
+		if msg.String() == "a" && time.Since(armedAt) <= applyConfirmWindow {
+			return m, m.startApply()
+		}
+		m.statusMessage = "Apply cancelled"
+		return m, nil
+	}
+
+	m.statusMessage = ""
 
-	case "esc":
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "q", "esc":
 		if m.showingDiff {
-			// Return to file list
-			m.showingDiff = false
-			m.currentDiff = ""
-			m.err = nil
+			// In diff view, q and esc go back to the file list
+			m.closeDiff()
+		} else if m.hasUnsavedChanges {
+			m.confirmQuit = true
 		} else {
+			// In file list, q quits the application
 			return m, tea.Quit
 		}
 
 	case "up", "k":
-		if !m.showingDiff && m.cursor > 0 {
+		if m.showingDiff {
+			m.diffViewportTop--
+			m.clampDiffViewport()
+		} else if m.cursor > 0 {
 			m.cursor--
 		}
 
 	case "down", "j":
-		if !m.showingDiff && m.cursor < len(m.results)-1 {
+		if m.showingDiff {
+			m.diffViewportTop++
+			m.clampDiffViewport()
+		} else if m.cursor < len(m.visible)-1 {
 			m.cursor++
 		}
 
+	case "pgup":
+		if m.showingDiff {
+			m.diffViewportTop -= m.diffViewHeight()
+			m.clampDiffViewport()
+		}
+
+	case "pgdown":
+		if m.showingDiff {
+			m.diffViewportTop += m.diffViewHeight()
+			m.clampDiffViewport()
+		}
+
+	case "g", "home":
+		if m.showingDiff {
+			m.diffViewportTop = 0
+		} else {
+			m.cursor = 0
+		}
+
+	case "G", "end":
+		if m.showingDiff {
+			// Clamping turns this into the top of the last page
+			m.diffViewportTop = m.diffLineCount()
+			m.clampDiffViewport()
+		} else if len(m.visible) > 0 {
+			m.cursor = len(m.visible) - 1
+		}
+
+	case "/":
+		m.searchActive = true
+		m.searchInput = ""
+
+	case "n":
+		if m.showingDiff {
+			m.nextDiffMatch(true)
+		} else {
+			m.nextPathMatch(true)
+		}
+
+	case "p", "N":
+		if m.showingDiff {
+			m.nextDiffMatch(false)
+		} else {
+			m.nextPathMatch(false)
+		}
+
+	case "b":
+		if m.showingDiff {
+			// Toggle side-by-side; line indices differ between layouts so redo the search
+			m.sideBySide = !m.sideBySide
+			m.diffViewportTop = 0
+			m.executeDiffSearch()
+			m.clampDiffViewport()
+		}
+
+	case "#":
+		if m.showingDiff {
+			m.hideLineNumbers = !m.hideLineNumbers
+		}
+
+	case "e":
+		if m.showingDiff {
+			m.startEdit()
+		}
+
+	case "m":
+		if m.showingDiff {
+			return m.startMerge()
+		}
+		m.toggleMark()
+
+	case "M":
+		if !m.showingDiff {
+			m.clearMarks()
+		}
+
+	case "w":
+		if m.showingDiff {
+			m.ignoreWhitespace = !m.ignoreWhitespace
+			m.reloadingDiff = true
+			return m, m.loadDiff()
+		}
+
+	case "+", "=":
+		if m.showingDiff {
+			m.contextLines++
+			m.reloadingDiff = true
+			return m, m.loadDiff()
+		} else if msg.String() == "=" {
+			m.toggleIdentical()
+		}
+
+	case "-":
+		if m.showingDiff && m.contextLines > 0 {
+			m.contextLines--
+			m.reloadingDiff = true
+			return m, m.loadDiff()
+		}
+
 	case "enter", "space":
-		if !m.showingDiff && len(m.results) > 0 {
-			// Load diff for selected file
+		if result, ok := m.selectedResult(); ok && !m.showingDiff {
+			// The diff reads the files as they are now, which may not match the list
+			m.statusMessage = m.staleWarning(result)
 			return m, m.loadDiff()
 		}
 
+	case "u":
+		if !m.showingDiff {
+			m.updateSelected()
+		}
+
+	case "o":
+		if !m.showingDiff && len(m.results) > 0 {
+			// Cycle sort order and re-sort in place
+			m.sortMode = m.sortMode.next()
+			sortResults(m.results, m.sortMode)
+			m.rebuildVisible()
+			m.cursor = 0
+		}
+
+	case "f":
+		if !m.showingDiff && len(m.results) > 0 {
+			// Cycle the status filter, keeping the selection if it is still visible
+			selected, hasSelection := m.selectedResult()
+			m.statusFilter = m.statusFilter.next()
+			m.rebuildVisible()
+			m.cursor = 0
+			if hasSelection {
+				m.selectPath(selected.RelativePath)
+			}
+		}
+
+	case ">":
+		if !m.showingDiff && len(m.marked) > 0 {
+			m.setMarkedAction("[>]", func(compare.FileStatus) action.ActionType { return action.ActionCopyToRight })
+		} else if !m.showingDiff {
+			m.setAction(action.ActionCopyToRight)
+		}
+
+	case "<":
+		if !m.showingDiff && len(m.marked) > 0 {
+			m.setMarkedAction("[<]", func(compare.FileStatus) action.ActionType { return action.ActionCopyToLeft })
+		} else if !m.showingDiff {
+			m.setAction(action.ActionCopyToLeft)
+		}
+
+	case "i":
+		if !m.showingDiff && len(m.marked) > 0 {
+			m.setMarkedAction("[i]", func(compare.FileStatus) action.ActionType { return action.ActionIgnore })
+		} else if !m.showingDiff {
+			m.setAction(action.ActionIgnore)
+		}
+
+	case "x":
+		if !m.showingDiff && len(m.marked) > 0 {
+			m.setMarkedAction("delete", deleteActionFor)
+		} else if result, ok := m.selectedResult(); ok && !m.showingDiff {
+			m.setAction(deleteActionFor(result.Status))
+		}
+
+	case "}":
+		if !m.showingDiff {
+			m.prepareBulkAction("[>]", func(compare.FileStatus) action.ActionType { return action.ActionCopyToRight })
+		}
+
+	case "{":
+		if !m.showingDiff {
+			m.prepareBulkAction("[<]", func(compare.FileStatus) action.ActionType { return action.ActionCopyToLeft })
+		}
+
+	case "I":
+		if !m.showingDiff {
+			m.prepareBulkAction("[i]", func(compare.FileStatus) action.ActionType { return action.ActionIgnore })
+		}
+
+	case "X":
+		if !m.showingDiff {
+			m.prepareBulkAction("delete", deleteActionFor)
+		}
+
+	case "s":
+		if !m.showingDiff && len(m.results) > 0 {
+			if path, err := m.saveActionFile(); err != nil {
+				m.statusMessage = fmt.Sprintf("Error: %v", err)
+			} else {
+				m.statusMessage = fmt.Sprintf("Saved action file: %s", path)
+			}
+		}
+
+	case "Z", "ctrl+s":
+		if len(m.results) == 0 {
+			return m, tea.Quit
+		}
+		return m.saveAndQuit()
+
+	case "a":
+		if !m.showingDiff {
+			return m, m.armApply()
+		}
+
+	case "A":
+		if !m.showingDiff {
+			return m, m.startApply()
+		}
+
 	case "r":
-		// Refresh/reload (future feature)
-		// For now just clear any error
-		m.err = nil
+		if !m.showingDiff {
+			// Compare again, e.g. after files were changed outside the TUI
+			m.err = nil
+			m.statusMessage = "Refreshing..."
+			return m, m.refreshResults()
+		}
 	}
 
 	return m, nil
 }
 
+// rebuildVisible recomputes the filtered view over results. The underlying
+// results are never modified, so clearing the filter restores every entry.
+func (m *Model) rebuildVisible() {
+	visible := make([]int, 0, len(m.results))
+	for i, result := range m.results {
+		if m.statusFilter.matches(result.Status) {
+			visible = append(visible, i)
+		}
+	}
+	m.visible = visible
+	if m.cursor >= len(m.visible) {
+		m.cursor = 0
+	}
+}
+
+// selectedResult returns the result under the cursor, if any
+func (m Model) selectedResult() (compare.ComparisonResult, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return compare.ComparisonResult{}, false
+	}
+	return m.results[m.visible[m.cursor]], true
+}
+
+// selectPath moves the cursor to the visible entry with the given path, if present
+func (m *Model) selectPath(relativePath string) bool {
+	for i, index := range m.visible {
+		if m.results[index].RelativePath == relativePath {
+			m.cursor = i
+			return true
+		}
+	}
+	return false
+}
+
+// closeDiff leaves the diff view and clears diff and search state
+func (m *Model) closeDiff() {
+	m.showingDiff = false
+	m.currentDiff = ""
+	m.diffLines = nil
+	m.diffRows = nil
+	m.diffGutters = nil
+	m.diffViewportTop = 0
+	m.searchQuery = ""
+	m.diffMatches = nil
+	m.diffMatchIndex = 0
+	m.reloadingDiff = false
+	m.err = nil
+}
+
+// diffViewHeight returns the number of diff lines that fit on screen
+func (m Model) diffViewHeight() int {
+	// Reserve lines for the header, search/status line and footer
+	height := m.windowHeight - 6
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+// clampDiffViewport keeps the diff viewport within the bounds of the diff content
+func (m *Model) clampDiffViewport() {
+	maxTop := m.diffLineCount() - m.diffViewHeight()
+	if maxTop < 0 {
+		maxTop = 0
+	}
+	if m.diffViewportTop > maxTop {
+		m.diffViewportTop = maxTop
+	}
+	if m.diffViewportTop < 0 {
+		m.diffViewportTop = 0
+	}
+}
+
+// showSideBySide reports whether the diff should be rendered in two columns.
+// Falls back to unified output for non-diff content or narrow terminals.
+func (m Model) showSideBySide() bool {
+	return m.sideBySide && len(m.diffRows) > 0 && m.windowWidth >= minSideBySideWidth
+}
+
+// diffLineCount returns the number of lines in the current diff layout
+func (m Model) diffLineCount() int {
+	if m.showSideBySide() {
+		return len(m.diffRows)
+	}
+	return len(m.diffLines)
+}
+
+// diffLineText returns the plain text of line i in the current diff layout
+func (m Model) diffLineText(i int) string {
+	if m.showSideBySide() {
+		return m.diffRows[i].text()
+	}
+	return diff.StripANSI(m.diffLines[i])
+}
+
 // Custom message types for async operations
-type diffLoadedMsg []byte
+type diffLoadedMsg struct {
+	output    []byte
+	key       diffCacheKey
+	cacheable bool // Output of diff for key, rather than an info page
+}
 type diffErrorMsg error
 
 // loadDiff loads the diff for the currently selected file
 func (m Model) loadDiff() tea.Cmd {
-	if m.cursor >= len(m.results) {
+	result, ok := m.selectedResult()
+	if !ok {
 		return nil
 	}
 
-	result := m.results[m.cursor]
+	// Only try to diff actual files, not directories or missing files
+	diffable := result.Status == compare.StatusModified &&
+		result.Changes.Has(compare.ChangeContent) &&
+		result.LeftInfo != nil && !result.LeftInfo.IsDir && !result.LeftInfo.IsSymlink &&
+		result.RightInfo != nil && !result.RightInfo.IsDir && !result.RightInfo.IsSymlink
+
+	var key diffCacheKey
+	if diffable {
+		key = m.diffKey(result)
+		if output, ok := m.diffCache[key]; ok {
+			return func() tea.Msg {
+				return diffLoadedMsg{output: output}
+			}
+		}
+	}
 
 	return func() tea.Msg {
-		// Only try to diff actual files, not directories or missing files
-		if result.Status == compare.StatusModified &&
-			result.LeftInfo != nil && !result.LeftInfo.IsDir &&
-			result.RightInfo != nil && !result.RightInfo.IsDir {
+		if diffable {
 
-			leftPath := fmt.Sprintf("%s/%s", m.leftDir, result.RelativePath)
-			rightPath := fmt.Sprintf("%s/%s", m.rightDir, result.RelativePath)
+			leftPath := fmt.Sprintf("%s/%s", m.leftContent, result.LeftPath())
+			rightPath := fmt.Sprintf("%s/%s", m.rightContent, result.RightPath())
+
+			// Unified format with the configured lines of context
+			args := []string{"-U", strconv.Itoa(m.contextLines)}
+			if m.ignoreWhitespace {
+				args = append(args, "-w")
+			}
+			args = append(args, leftPath, rightPath)
 
 			// Use Unix diff command with enhanced colorization and formatting
 			var cmd *exec.Cmd
-			if _, err := exec.LookPath("colordiff"); err == nil {
-				// Use colordiff with color output and unified format with 3 lines of context
-				cmd = exec.Command("colordiff", "--color=always", "-u", "-U3", leftPath, rightPath)
+			if configured := diff.ConfiguredCommand(m.diffCommand, args...); configured != nil {
+				cmd = configured
+			} else if _, err := exec.LookPath("colordiff"); err == nil {
+				// Use colordiff with color output
+				cmd = exec.Command("colordiff", append([]string{"--color=always"}, args...)...)
 			} else {
-				// Fall back to regular diff with unified format and 3 lines of context
-				cmd = exec.Command("diff", "-u", "-U3", leftPath, rightPath)
+				// Fall back to regular diff
+				cmd = exec.Command("diff", args...)
 			}
 
 			output, err := cmd.Output()
 			if err != nil {
 				// diff returns exit code 1 when files differ (normal case)
 				if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-					return diffLoadedMsg(output)
+					return diffLoadedMsg{output: output, key: key, cacheable: true}
 				}
 				return diffErrorMsg(fmt.Errorf("failed to generate diff: %w", err))
 			}
 
-			return diffLoadedMsg(output)
+			// diff exits 0 when -w hides every change
+			if len(output) == 0 && m.ignoreWhitespace {
+				output = []byte("Files differ only in whitespace (press w to show all changes)\n")
+			}
+			return diffLoadedMsg{output: output, key: key, cacheable: true}
 		}
 
 		// For non-diff-able items, show basic info
 		info := fmt.Sprintf("File: %s\nStatus: %s\n\n", result.RelativePath, result.Status.String())
 
 		switch result.Status {
+		case compare.StatusModified:
+			for _, side := range []struct {
+				name string
+				info *compare.FileInfo
+			}{{"LEFT", result.LeftInfo}, {"RIGHT", result.RightInfo}} {
+				if side.info == nil {
+					continue
+				}
+				if side.info.IsSymlink {
+					info += fmt.Sprintf("%s: symlink -> %s\n", side.name, side.info.LinkTarget)
+				} else if side.info.IsDir {
+					info += fmt.Sprintf("%s: directory\n", side.name)
+				} else {
+					info += fmt.Sprintf("%s: file (%d bytes, %s)\n", side.name, side.info.Size, side.info.Permissions)
+				}
+			}
 		case compare.StatusOnlyLeft:
 			if result.LeftInfo != nil {
 				info += fmt.Sprintf("Only exists in LEFT directory\nSize: %d bytes\n", result.LeftInfo.Size)
@@ -239,7 +913,7 @@ func (m Model) loadDiff() tea.Cmd {
 			}
 		}
 
-		return diffLoadedMsg([]byte(info))
+		return diffLoadedMsg{output: []byte(info)}
 	}
 }
 
@@ -256,12 +930,12 @@ func (m Model) viewFileList() string {
 	var b strings.Builder
 
 	// Header
-	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	headerStyle := m.fg(m.palette.Header).Bold(true)
 	b.WriteString(headerStyle.Render("Dovetail Directory Comparison"))
 	b.WriteString("\n\n")
 
 	// Directory info
-	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	infoStyle := m.fg(m.palette.Muted)
 	b.WriteString(infoStyle.Render(fmt.Sprintf("Left:  %s", m.leftDir)))
 	b.WriteString("\n")
 	b.WriteString(infoStyle.Render(fmt.Sprintf("Right: %s", m.rightDir)))
@@ -273,28 +947,106 @@ func (m Model) viewFileList() string {
 			m.summary.TotalFiles,
 			m.summary.ModifiedFiles+m.summary.OnlyLeftFiles+m.summary.OnlyRightFiles,
 			m.summary.IdenticalFiles)))
-		b.WriteString("\n\n")
+		b.WriteString("\n")
+		b.WriteString(infoStyle.Render(fmt.Sprintf("Sizes: %s left only, %s right only, %s modified (left minus right)",
+			util.FormatSize(m.summary.OnlyLeftBytes),
+			util.FormatSize(m.summary.OnlyRightBytes),
+			util.FormatSizeDelta(m.summary.ModifiedBytesDelta))))
+		b.WriteString("\n")
+		if skipped := m.summary.SkippedPaths; len(skipped) > 0 {
+			warningStyle := m.fg(m.palette.Warning)
+			b.WriteString(warningStyle.Render(fmt.Sprintf("Incomplete: %d path(s) could not be read", len(skipped))))
+			b.WriteString("\n")
+			for i, skip := range skipped {
+				if i == maxSkippedShown {
+					b.WriteString(warningStyle.Render(fmt.Sprintf("  ... and %d more", len(skipped)-i)))
+					b.WriteString("\n")
+					break
+				}
+				b.WriteString(warningStyle.Render(fmt.Sprintf("  %s: %s (%s)", skip.Side, skip.Path, skip.Reason)))
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString("\n")
 	}
 
 	// File list
 	if len(m.results) == 0 {
 		b.WriteString(infoStyle.Render("No differences found."))
 	} else {
-		b.WriteString(lipgloss.NewStyle().Bold(true).Render("Files with differences:"))
+		if count := m.countActions(); count > 0 || m.hasUnsavedChanges || len(m.marked) > 0 {
+			actionsLine := fmt.Sprintf("Actions: %d set", count)
+			if m.hasUnsavedChanges {
+				actionsLine += " (unsaved)"
+			}
+			if len(m.marked) > 0 {
+				actionsLine += fmt.Sprintf(", %d marked (action keys apply to all marked)", len(m.marked))
+			}
+			b.WriteString(infoStyle.Render(actionsLine))
+			b.WriteString("\n\n")
+		}
+
+		listTitle := "Files with differences:"
+		if m.showIdentical {
+			listTitle = "Files, identical included:"
+		}
+		b.WriteString(lipgloss.NewStyle().Bold(true).Render(listTitle))
+		if m.statusFilter != FilterAll {
+			b.WriteString(infoStyle.Render(fmt.Sprintf("  (filter: %s, %d of %d shown, sort: %s)",
+				m.statusFilter, len(m.visible), len(m.results), m.sortMode)))
+		} else {
+			b.WriteString(infoStyle.Render(fmt.Sprintf("  (sort: %s)", m.sortMode)))
+		}
 		b.WriteString("\n\n")
 
-		for i, result := range m.results {
-			statusColor := getStatusColor(result.Status)
-			statusStyle := lipgloss.NewStyle().Foreground(statusColor)
+		if len(m.visible) == 0 {
+			b.WriteString(infoStyle.Render(fmt.Sprintf("No %s entries. Press f to change the filter.", m.statusFilter)))
+			b.WriteString("\n")
+		}
+
+		if len(m.visible) > 0 && m.showDetailColumns() {
+			b.WriteString(infoStyle.Render(m.detailColumnsHeader()))
+			b.WriteString("\n")
+		}
 
-			line := fmt.Sprintf("  %-12s %s", result.Status.String(), result.RelativePath)
+		for i, index := range m.visible {
+			result := m.results[index]
+			statusStyle := m.fg(getStatusColor(m.palette, result.Status))
 
+			act := m.fileActions[result.RelativePath]
+			actionLabel := fmt.Sprintf("%-4s", "["+act.String()+"]")
+			mark := " "
+			if m.marked[result.RelativePath] {
+				mark = "*"
+			}
+
+			var line string
 			if i == m.cursor {
 				// Highlight selected line
-				selectedStyle := lipgloss.NewStyle().Background(lipgloss.Color("8")).Foreground(lipgloss.Color("15"))
-				line = selectedStyle.Render(fmt.Sprintf("▶ %-12s %s", result.Status.String(), result.RelativePath))
+				selectedStyle := m.selectedStyle()
+				text := fmt.Sprintf("▶%s%s %-12s %s", mark, actionLabel, result.Status.String(), m.highlightPath(result.RelativePath))
+				if note := changeNote(result); note != "" {
+					text += " " + note
+				}
+				if m.showDetailColumns() {
+					text += m.renderDetailColumns(result, true)
+				}
+				line = selectedStyle.Render(text)
 			} else {
-				line = statusStyle.Render(fmt.Sprintf("  %-12s", result.Status.String())) + " " + result.RelativePath
+				actionStyle := m.fg(getActionColor(m.palette, act))
+				markStyle := m.fg(m.palette.Mark).Bold(true)
+				path := m.highlightPath(result.RelativePath)
+				if result.Status == compare.StatusIdentical {
+					path = statusStyle.Render(path)
+				}
+				line = " " + markStyle.Render(mark) + actionStyle.Render(actionLabel) + " " +
+					statusStyle.Render(fmt.Sprintf("%-12s", result.Status.String())) + " " + path
+				if note := changeNote(result); note != "" {
+					line += " " + infoStyle.Render(note)
+				}
+				if m.showDetailColumns() {
+					line += m.renderDetailColumns(result, false)
+				}
 			}
 
 			b.WriteString(line)
@@ -302,13 +1054,39 @@ func (m Model) viewFileList() string {
 		}
 	}
 
-	// Footer/Help
+	// Prompts and messages
 	b.WriteString("\n")
-	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	promptStyle := m.fg(m.palette.Warning).Bold(true)
+	if m.pendingBulk != nil {
+		prompt := fmt.Sprintf("Set %s on %d visible files", m.pendingBulk.label, len(m.pendingBulk.assignments))
+		if m.pendingBulk.skipped > 0 {
+			prompt += fmt.Sprintf(" (%d skipped: not valid)", m.pendingBulk.skipped)
+		}
+		b.WriteString(promptStyle.Render(prompt + "? [y/N]"))
+		b.WriteString("\n")
+	} else if m.confirmQuit {
+		b.WriteString(promptStyle.Render("You have unsaved action changes. s: save and quit  y: quit without saving  any other key: go back"))
+		b.WriteString("\n")
+	} else if !m.applyArmedAt.IsZero() {
+		b.WriteString(promptStyle.Render(m.applyPrompt()))
+		b.WriteString("\n")
+	} else if m.searchActive {
+		b.WriteString(fmt.Sprintf("/%s█", m.searchInput))
+		b.WriteString(infoStyle.Render(fmt.Sprintf("  (%s; Tab: toggle)", m.searchModeName())))
+		b.WriteString("\n")
+	} else if m.statusMessage != "" {
+		b.WriteString(infoStyle.Render(m.statusMessage))
+		b.WriteString("\n")
+	}
+
+	// Footer/Help
+	helpStyle := m.fg(m.palette.Muted)
 	if len(m.results) > 0 {
-		b.WriteString(helpStyle.Render("↑/↓ or j/k: navigate  Enter: show diff  q: quit"))
+		b.WriteString(helpStyle.Render("↑/↓ or j/k: navigate  g/G: first/last  Enter: show diff  o: change sort  f: filter status  /: search  n/N: next/prev match  =: show identical  r: refresh  q: quit"))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render(">/</i/x: set action (on marked files if any)  m/M: mark/clear marks  }/{/I/X: set action on all visible  u: update entry  s: save actions  Z/Ctrl+S: save and quit  a a/A: apply actions"))
 	} else {
-		b.WriteString(helpStyle.Render("q: quit"))
+		b.WriteString(helpStyle.Render("=: show identical  r: refresh  q: quit"))
 	}
 
 	return b.String()
@@ -318,42 +1096,119 @@ func (m Model) viewFileList() string {
 func (m Model) viewDiff() string {
 	var b strings.Builder
 
-	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	headerStyle := m.fg(m.palette.Header).Bold(true)
 
-	if m.cursor < len(m.results) {
-		result := m.results[m.cursor]
+	if result, ok := m.selectedResult(); ok {
 		b.WriteString(headerStyle.Render(fmt.Sprintf("Diff: %s", result.RelativePath)))
+		whitespace := "shown"
+		if m.ignoreWhitespace {
+			whitespace = "ignored"
+		}
+		b.WriteString(m.fg(m.palette.Muted).Render(fmt.Sprintf("  (context: %d, whitespace: %s)", m.contextLines, whitespace)))
 		b.WriteString("\n\n")
 
 		if m.err != nil {
-			errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+			errorStyle := m.fg(m.palette.Error)
 			b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
 		} else {
-			// Display diff content
-			b.WriteString(m.currentDiff)
+			// Display the visible window of diff content
+			query := ""
+			if len(m.diffMatches) > 0 {
+				query = m.searchQuery
+			}
+			columnWidth := (m.windowWidth - 3) / 2
+
+			// Gutters are sized for the whole diff, so they don't shift while scrolling
+			gutter := 0
+			if !m.hideLineNumbers {
+				if m.showSideBySide() {
+					gutter = sideBySideGutterWidth(m.diffRows)
+				} else {
+					gutter = gutterWidth(m.diffGutters)
+				}
+			}
+
+			end := m.diffViewportTop + m.diffViewHeight()
+			if end > m.diffLineCount() {
+				end = m.diffLineCount()
+			}
+			for i := m.diffViewportTop; i < end; i++ {
+				if m.showSideBySide() {
+					b.WriteString(renderSideBySideRow(m.palette, m.diffRows[i], columnWidth, gutter, query))
+				} else {
+					if gutter > 0 {
+						numbers := m.diffGutters[i]
+						b.WriteString(m.fg(m.palette.Muted).Render(gutterNumber(numbers.left, gutter) + " " + gutterNumber(numbers.right, gutter) + " │ "))
+					}
+					b.WriteString(highlightSearch(m.diffLines[i], query))
+				}
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	// Search prompt or search status
+	b.WriteString("\n")
+	infoStyle := m.fg(m.palette.Muted)
+	if m.searchActive {
+		b.WriteString(fmt.Sprintf("/%s█", m.searchInput))
+	} else if m.searchQuery != "" {
+		if len(m.diffMatches) == 0 {
+			b.WriteString(infoStyle.Render(fmt.Sprintf("No matches for \"%s\"", m.searchQuery)))
+		} else {
+			b.WriteString(infoStyle.Render(fmt.Sprintf("Match %d/%d for \"%s\"",
+				m.diffMatchIndex+1, len(m.diffMatches), m.searchQuery)))
 		}
+	} else if m.statusMessage != "" {
+		b.WriteString(infoStyle.Render(m.statusMessage))
+	} else if m.sideBySide && len(m.diffRows) > 0 && !m.showSideBySide() {
+		b.WriteString(infoStyle.Render(fmt.Sprintf("Terminal too narrow for side-by-side view (need %d columns)", minSideBySideWidth)))
 	}
 
 	// Footer
-	b.WriteString("\n\n")
-	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
-	b.WriteString(helpStyle.Render("Esc/q: back to file list  Ctrl+C: quit"))
+	b.WriteString("\n")
+	helpStyle := m.fg(m.palette.Muted)
+	b.WriteString(helpStyle.Render("↑/↓ PgUp/PgDn: scroll  g/G: top/bottom  /: search  n/p: next/prev match  b: side-by-side  #: line numbers  +/-: context  w: whitespace  e: edit  m: merge tool  Esc/q: back to file list  Ctrl+C: quit"))
 
 	return b.String()
 }
 
-// getStatusColor returns the appropriate color for a file status
-func getStatusColor(status compare.FileStatus) lipgloss.Color {
+// getStatusColor returns the palette color for a file status
+func getStatusColor(palette theme.Palette, status compare.FileStatus) string {
 	switch status {
 	case compare.StatusModified:
-		return lipgloss.Color("11") // Yellow
+		return palette.Modified
 	case compare.StatusOnlyLeft:
-		return lipgloss.Color("9") // Red
+		return palette.OnlyLeft
 	case compare.StatusOnlyRight:
-		return lipgloss.Color("10") // Green
+		return palette.OnlyRight
 	case compare.StatusIdentical:
-		return lipgloss.Color("8") // Gray
+		return palette.Identical
 	default:
-		return lipgloss.Color("15") // White
+		return ""
+	}
+}
+
+// paletteStyle returns a style with the given foreground color, or no color
+// at all for the plain palette
+func paletteStyle(palette theme.Palette, color string) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if palette.Plain || color == "" {
+		return style
+	}
+	return style.Foreground(lipgloss.Color(color))
+}
+
+// fg returns a style with the given color of the model's palette
+func (m Model) fg(color string) lipgloss.Style {
+	return paletteStyle(m.palette, color)
+}
+
+// selectedStyle returns the style of the row under the cursor, shown in
+// reverse video when there are no colors to highlight it with
+func (m Model) selectedStyle() lipgloss.Style {
+	if m.palette.Plain {
+		return lipgloss.NewStyle().Reverse(true)
 	}
+	return m.fg(m.palette.Selected).Background(lipgloss.Color(m.palette.Cursor))
 }
@@ -0,0 +1,185 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/compare"
+)
+
+// applyConfirmWindow is how long the first press of a waits for the second
+const applyConfirmWindow = 2 * time.Second
+
+// applyExpiredMsg disarms a first press of a that was not repeated in time
+type applyExpiredMsg struct {
+	armedAt time.Time
+}
+
+// applyFinishedMsg carries the outcome of applying the chosen actions and
+// the comparison that followed it
+type applyFinishedMsg struct {
+	summary *action.ExecutionSummary
+	results []action.ExecutionResult
+	journal string // Undo journal path, empty if nothing changed
+	hookErr error  // Why the post-apply hook failed, with the end of its output
+	err     error
+	refresh resultsRefreshedMsg
+}
+
+// armApply handles the first press of a: nothing runs until it is pressed
+// again within applyConfirmWindow
+func (m *Model) armApply() tea.Cmd {
+	if !m.canApply() {
+		return nil
+	}
+	armedAt := time.Now()
+	m.applyArmedAt = armedAt
+	return tea.Tick(applyConfirmWindow, func(time.Time) tea.Msg {
+		return applyExpiredMsg{armedAt: armedAt}
+	})
+}
+
+// canApply reports whether the chosen actions can be applied, setting the
+// status message to the reason when they can't
+func (m *Model) canApply() bool {
+	switch {
+	case m.applying:
+		m.statusMessage = "Already applying actions"
+	case m.leftContent != m.leftDir || m.rightContent != m.rightDir:
+		m.statusMessage = "Actions can't be applied to archives; save them and apply after unpacking"
+	case m.countActions() == 0:
+		m.statusMessage = "No actions to apply"
+	default:
+		for _, result := range m.results {
+			if m.fileActions[result.RelativePath] == action.ActionIgnore {
+				continue
+			}
+			// Acting on outdated results could copy or delete the wrong content
+			if warning := m.staleWarning(result); warning != "" {
+				m.statusMessage = warning
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// startApply runs the chosen actions in the background, recording an undo
+// journal as apply does, then compares again
+func (m *Model) startApply() tea.Cmd {
+	if !m.canApply() {
+		return nil
+	}
+
+	actionFile := &action.ActionFile{
+		Header: action.ActionFileHeader{LeftDir: m.leftDir, RightDir: m.rightDir, Version: m.version},
+	}
+	for _, result := range m.results {
+		act := m.fileActions[result.RelativePath]
+		if act == action.ActionIgnore {
+			continue
+		}
+		item := action.ActionItem{
+			Action:       act,
+			Status:       result.Status,
+			RelativePath: result.RelativePath,
+			LeftInfo:     result.LeftInfo,
+			RightInfo:    result.RightInfo,
+		}
+		if result.Status == compare.StatusRenamed {
+			item.Destination = result.RightPath()
+		} else if result.Changes.Has(compare.ChangeCase) {
+			item.RightCase = result.RightPath()
+		}
+		actionFile.Actions = append(actionFile.Actions, item)
+	}
+	// Parent directories before their contents, as in action files
+	sort.SliceStable(actionFile.Actions, func(i, j int) bool {
+		return actionFile.Actions[i].RelativePath < actionFile.Actions[j].RelativePath
+	})
+
+	m.applying = true
+	m.statusMessage = fmt.Sprintf("Applying %d actions...", len(actionFile.Actions))
+	leftDir, rightDir, journalDir, settings := m.leftDir, m.rightDir, m.journalDir, m.applySettings
+	refresh := m.refreshResults()
+	return func() tea.Msg {
+		journal, err := action.NewJournal(journalDir, leftDir, rightDir)
+		if err != nil {
+			return applyFinishedMsg{err: fmt.Errorf("failed to create undo journal: %w", err)}
+		}
+		executor := action.NewConfiguredExecutor(settings)
+		executor.SetJournal(journal)
+		summary, results, err := executor.ExecuteActions(actionFile, leftDir, rightDir)
+
+		msg := applyFinishedMsg{summary: summary, results: results, err: err}
+		if err == nil && len(summary.Errors) == 0 && !summary.Aborted && settings.PostApplyHook != "" {
+			// The hook can't share the terminal with the TUI, so its output
+			// is only shown when it fails
+			var output bytes.Buffer
+			env := action.PostApplyEnv(leftDir, rightDir, "", len(results), summary, journal)
+			if hookErr := action.RunPostApplyHook(settings.PostApplyHook, env, nil, &output, &output); hookErr != nil {
+				msg.hookErr = hookErr
+				if lines := strings.Split(strings.TrimSpace(output.String()), "\n"); lines[len(lines)-1] != "" {
+					msg.hookErr = fmt.Errorf("%w: %s", hookErr, lines[len(lines)-1])
+				}
+			}
+		}
+		if len(journal.Entries) == 0 {
+			os.Remove(journal.Path())
+		} else {
+			msg.journal = journal.Path()
+		}
+		msg.refresh = refresh().(resultsRefreshedMsg)
+		return msg
+	}
+}
+
+// finishApply resets the actions that succeeded, takes the new comparison
+// and reports the outcome
+func (m *Model) finishApply(msg applyFinishedMsg) {
+	m.applying = false
+	if msg.err != nil && msg.summary == nil {
+		m.statusMessage = fmt.Sprintf("Error: apply failed: %v", msg.err)
+		return
+	}
+
+	for _, result := range msg.results {
+		if result.Success && !result.Skipped {
+			m.fileActions[result.Action.RelativePath] = action.ActionIgnore
+		}
+	}
+	m.applyRefresh(msg.refresh)
+	m.hasUnsavedChanges = m.countActions() > 0
+
+	status := fmt.Sprintf("Applied %d actions: %d succeeded, %d failed",
+		len(msg.results), msg.summary.SuccessfulActions, msg.summary.FailedActions)
+	for _, result := range msg.results {
+		if !result.Success {
+			status += fmt.Sprintf(" (first failure: %s)", result.Message)
+			break
+		}
+	}
+	if msg.journal != "" {
+		status += fmt.Sprintf(". Undo with: dovetail undo %s", msg.journal)
+	}
+	if msg.hookErr != nil {
+		status += fmt.Sprintf(". Post-apply hook failed: %v", msg.hookErr)
+	}
+	if msg.refresh.err != nil {
+		status += fmt.Sprintf(". Refresh failed: %v", msg.refresh.err)
+	}
+	m.statusMessage = status
+}
+
+// applyPrompt is the message shown while a is waiting for its second press
+func (m Model) applyPrompt() string {
+	return fmt.Sprintf("Press a again to apply %d actions to %s and %s (any other key cancels)",
+		m.countActions(), m.leftDir, m.rightDir)
+}
@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/harikb/dovetail/internal/compare"
+	"github.com/harikb/dovetail/internal/util"
+)
+
+const (
+	// minDetailColumnsWidth is the narrowest terminal that shows size columns
+	minDetailColumnsWidth = 80
+
+	// sizeCellWidth fits "1023.9 MB*"
+	sizeCellWidth = 10
+
+	// fileListPrefixWidth is "▶ [>]  MODIFIED     " before the path
+	fileListPrefixWidth = 2 + 4 + 1 + 12 + 1
+)
+
+// showDetailColumns reports whether the window is wide enough for size columns
+func (m Model) showDetailColumns() bool {
+	return m.windowWidth >= minDetailColumnsWidth
+}
+
+// sizeCell formats one side's size, with a trailing * when it is the newer side
+func sizeCell(info *compare.FileInfo, newer bool) string {
+	text := "-"
+	if info != nil && !info.IsDir {
+		text = util.FormatSize(info.Size)
+	}
+	if newer {
+		text += "*"
+	} else {
+		text += " "
+	}
+	return fmt.Sprintf("%*s", sizeCellWidth, text)
+}
+
+// newerSide returns whether the left and right entries are the newer one.
+// Both are false when either side is missing or the times are equal.
+func newerSide(result compare.ComparisonResult) (leftNewer, rightNewer bool) {
+	if result.LeftInfo == nil || result.RightInfo == nil {
+		return false, false
+	}
+	left, right := result.LeftInfo.ModTime, result.RightInfo.ModTime
+	return left.After(right), right.After(left)
+}
+
+// changeNote labels modified entries whose difference isn't their content
+func changeNote(result compare.ComparisonResult) string {
+	switch {
+	case result.Status != compare.StatusModified:
+		return ""
+	case result.Changes.Has(compare.ChangeType):
+		return "(type changed)"
+	case result.Changes.PermsOnly():
+		return "(perms only)"
+	case result.Changes.Has(compare.ChangeLineEndings):
+		return "(line endings)"
+	case result.Changes.Has(compare.ChangeCase):
+		return "(case: " + result.RightPath() + ")"
+	default:
+		return ""
+	}
+}
+
+// renderDetailColumns pads the path and appends right-aligned left/right size
+// columns. The newer side is colored unless plain is set (the selected
+// row is rendered in a single style). Paths too long to fit push the columns
+// right rather than being truncated.
+func (m Model) renderDetailColumns(result compare.ComparisonResult, plain bool) string {
+	leftNewer, rightNewer := newerSide(result)
+	left := sizeCell(result.LeftInfo, leftNewer)
+	right := sizeCell(result.RightInfo, rightNewer)
+	if !plain {
+		// Marks the size of the side with the more recent modification time
+		newerStyle := m.fg(m.palette.Newer)
+		if leftNewer {
+			left = newerStyle.Render(left)
+		}
+		if rightNewer {
+			right = newerStyle.Render(right)
+		}
+	}
+
+	pathWidth := m.windowWidth - fileListPrefixWidth - 2*sizeCellWidth - 2
+	labelWidth := lipgloss.Width(result.RelativePath)
+	if note := changeNote(result); note != "" {
+		labelWidth += 1 + lipgloss.Width(note)
+	}
+	padding := pathWidth - labelWidth
+	if padding < 1 {
+		padding = 1
+	}
+	return strings.Repeat(" ", padding) + left + " " + right
+}
+
+// detailColumnsHeader labels the size columns, aligned with renderDetailColumns
+func (m Model) detailColumnsHeader() string {
+	headerWidth := m.windowWidth - 2*sizeCellWidth - 2
+	if headerWidth < 0 {
+		headerWidth = 0
+	}
+	return strings.Repeat(" ", headerWidth) + fmt.Sprintf("%*s %*s", sizeCellWidth, "Left ", sizeCellWidth, "Right ")
+}
@@ -0,0 +1,45 @@
+package tui
+
+import "github.com/harikb/dovetail/internal/compare"
+
+// maxCachedDiffs bounds the diff cache; it is emptied when full
+const maxCachedDiffs = 64
+
+// diffCacheKey identifies generated diff output: the content of both sides
+// plus the options passed to diff. The paths are part of the key because
+// they appear in the diff header.
+type diffCacheKey struct {
+	leftPath         string
+	rightPath        string
+	leftHash         string
+	rightHash        string
+	ignoreWhitespace bool
+	contextLines     int
+}
+
+// diffKey returns the cache key for diffing a result's files with the current
+// options
+func (m Model) diffKey(result compare.ComparisonResult) diffCacheKey {
+	return diffCacheKey{
+		leftPath:         result.LeftPath(),
+		rightPath:        result.RightPath(),
+		leftHash:         result.LeftInfo.Hash,
+		rightHash:        result.RightInfo.Hash,
+		ignoreWhitespace: m.ignoreWhitespace,
+		contextLines:     m.contextLines,
+	}
+}
+
+// cacheDiff remembers the output of diff for key
+func (m *Model) cacheDiff(key diffCacheKey, output []byte) {
+	if m.diffCache == nil || len(m.diffCache) >= maxCachedDiffs {
+		m.diffCache = make(map[diffCacheKey][]byte)
+	}
+	m.diffCache[key] = output
+}
+
+// invalidateDiffCache drops all cached diffs, e.g. after files were edited
+// in place so their recorded hashes no longer describe the content
+func (m *Model) invalidateDiffCache() {
+	m.diffCache = nil
+}
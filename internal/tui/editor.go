@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorFinishedMsg is sent when the external editor or merge tool exits
+type editorFinishedMsg struct {
+	err  error
+	name string // Command that ran, for error messages
+}
+
+// editorCommand returns the user's editor command split into words, from
+// $VISUAL or else $EDITOR
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return nil
+}
+
+// startEdit asks which side of the diff to open in the editor
+func (m *Model) startEdit() {
+	if editorCommand() == nil {
+		m.statusMessage = "Error: $EDITOR is not set"
+		return
+	}
+	m.editPrompt = true
+	m.statusMessage = "Edit which side? l: left  r: right  (any other key cancels)"
+}
+
+// handleEditPrompt opens the chosen side once the side key is pressed
+func (m Model) handleEditPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.editPrompt = false
+	m.statusMessage = ""
+
+	result, ok := m.selectedResult()
+	if !ok {
+		return m, nil
+	}
+
+	var path, root, content, side string
+	switch msg.String() {
+	case "l", "left":
+		path, root, content, side = result.LeftPath(), m.leftDir, m.leftContent, "left"
+	case "r", "right":
+		path, root, content, side = result.RightPath(), m.rightDir, m.rightContent, "right"
+	default:
+		return m, nil
+	}
+
+	// Extracted archive entries are temporary copies; edits would be lost
+	if content != root {
+		m.statusMessage = fmt.Sprintf("Error: the %s side is an archive and can't be edited", side)
+		return m, nil
+	}
+	fullPath := filepath.Join(root, path)
+	if info, err := os.Stat(fullPath); err != nil || info.IsDir() {
+		m.statusMessage = fmt.Sprintf("Error: %s is not a file on the %s side", path, side)
+		return m, nil
+	}
+
+	editor := editorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], fullPath)...)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{err: err, name: "editor"}
+	})
+}
@@ -0,0 +1,152 @@
+package tui
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Fuzzy match scoring, loosely after fzf: every matched character scores,
+// matches at the start of a path segment or word and runs of consecutive
+// matches score extra, and skipped characters inside the match cost a little
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 8
+	bonusConsecutive = 4
+	penaltyGapStart  = 3
+	penaltyGapExtend = 1
+)
+
+// fuzzyMatch reports whether every character of query appears in text in
+// order, ignoring case. It returns the best score (higher is better) over all
+// ways of matching and the rune positions in text of the matched characters.
+func fuzzyMatch(text, query string) (int, []int, bool) {
+	original := []rune(text)
+	textRunes := []rune(strings.ToLower(text))
+	queryRunes := []rune(strings.ToLower(query))
+	if len(queryRunes) == 0 || len(original) != len(textRunes) || len(queryRunes) > len(textRunes) {
+		return 0, nil, false
+	}
+
+	const none = math.MinInt / 2
+	// best[j][i] is the best score with query[j] matched at text[i], and
+	// from[j][i] the position query[j-1] was matched at for that score
+	best := make([][]int, len(queryRunes))
+	from := make([][]int, len(queryRunes))
+	for j := range queryRunes {
+		best[j] = make([]int, len(textRunes))
+		from[j] = make([]int, len(textRunes))
+		// Best earlier position to continue from after a gap, with the gap
+		// penalty's per-character part folded in
+		gapBest, gapFrom := none, -1
+		for i, r := range textRunes {
+			best[j][i] = none
+			if j > 0 && i >= 2 && best[j-1][i-2] > none {
+				if candidate := best[j-1][i-2] + (i-2)*penaltyGapExtend; candidate > gapBest {
+					gapBest, gapFrom = candidate, i-2
+				}
+			}
+			if r != queryRunes[j] {
+				continue
+			}
+
+			score := scoreMatch
+			if i == 0 || isWordBoundary(original[i-1], original[i]) {
+				score += bonusBoundary
+			}
+			switch {
+			case j == 0:
+				best[j][i] = score
+			default:
+				if i >= 1 && best[j-1][i-1] > none {
+					best[j][i] = best[j-1][i-1] + score + bonusConsecutive
+					from[j][i] = i - 1
+				}
+				if gapBest > none {
+					if candidate := gapBest - (i-2)*penaltyGapExtend - penaltyGapStart + score; candidate > best[j][i] {
+						best[j][i] = candidate
+						from[j][i] = gapFrom
+					}
+				}
+			}
+		}
+	}
+
+	last := len(queryRunes) - 1
+	end := -1
+	for i, score := range best[last] {
+		if score > none && (end < 0 || score > best[last][end]) {
+			end = i
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	positions := make([]int, len(queryRunes))
+	for j, i := last, end; j >= 0; j-- {
+		positions[j] = i
+		i = from[j][i]
+	}
+	return best[last][end], positions, true
+}
+
+// isWordBoundary reports whether cur starts a new path segment or word
+func isWordBoundary(prev, cur rune) bool {
+	switch prev {
+	case '/', '\\', '_', '-', '.', ' ':
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// substringMatch finds the first case-insensitive occurrence of query in
+// text, returning the rune positions it covers
+func substringMatch(text, query string) ([]int, bool) {
+	textRunes := []rune(strings.ToLower(text))
+	queryRunes := []rune(strings.ToLower(query))
+	if len(queryRunes) == 0 || len([]rune(text)) != len(textRunes) {
+		return nil, false
+	}
+
+	for i := 0; i+len(queryRunes) <= len(textRunes); i++ {
+		if string(textRunes[i:i+len(queryRunes)]) == string(queryRunes) {
+			positions := make([]int, len(queryRunes))
+			for n := range positions {
+				positions[n] = i + n
+			}
+			return positions, true
+		}
+	}
+	return nil, false
+}
+
+// highlightPositions marks the runes of text at positions, merging adjacent
+// ones into a single highlighted run
+func highlightPositions(text string, positions []int) string {
+	if len(positions) == 0 {
+		return text
+	}
+	marked := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		marked[pos] = true
+	}
+
+	var b strings.Builder
+	on := false
+	for i, r := range []rune(text) {
+		if marked[i] != on {
+			on = marked[i]
+			if on {
+				b.WriteString(highlightOn)
+			} else {
+				b.WriteString(highlightOff)
+			}
+		}
+		b.WriteRune(r)
+	}
+	if on {
+		b.WriteString(highlightOff)
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/harikb/dovetail/internal/diff"
+)
+
+// lineGutter holds the file line numbers shown beside one diff line.
+// Zero means the line has no number on that side.
+type lineGutter struct {
+	left  int
+	right int
+}
+
+// unifiedGutters numbers the lines of unified diff output from the hunk
+// headers, counting context, removed and added lines the way diff.ParseHunks
+// does. Lines outside hunks (file headers, or output of a diff command that
+// has no hunks) get an empty gutter.
+func unifiedGutters(lines []string) []lineGutter {
+	gutters := make([]lineGutter, len(lines))
+	leftLine, rightLine := 0, 0
+	leftRemaining, rightRemaining := 0, 0 // Lines still expected in the current hunk
+	for i, raw := range lines {
+		line := diff.StripANSI(raw)
+		if leftRemaining == 0 && rightRemaining == 0 {
+			if hunk, ok := diff.ParseHunkHeader(line); ok {
+				leftLine, rightLine = hunk.LeftStart, hunk.RightStart
+				leftRemaining, rightRemaining = hunk.LeftCount, hunk.RightCount
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "\\"):
+			continue
+		case strings.HasPrefix(line, "-"):
+			gutters[i].left = leftLine
+			leftLine++
+			leftRemaining--
+		case strings.HasPrefix(line, "+"):
+			gutters[i].right = rightLine
+			rightLine++
+			rightRemaining--
+		case line == "" || strings.HasPrefix(line, " "):
+			gutters[i] = lineGutter{left: leftLine, right: rightLine}
+			leftLine++
+			rightLine++
+			leftRemaining--
+			rightRemaining--
+		}
+		if leftRemaining < 0 || rightRemaining < 0 {
+			leftRemaining, rightRemaining = 0, 0
+		}
+	}
+	return gutters
+}
+
+// gutterWidth returns the digits needed for the largest line number, or 0
+// when no line is numbered and the gutter should be left out
+func gutterWidth(gutters []lineGutter) int {
+	largest := 0
+	for _, gutter := range gutters {
+		largest = max(largest, gutter.left, gutter.right)
+	}
+	if largest == 0 {
+		return 0
+	}
+	return len(strconv.Itoa(largest))
+}
+
+// gutterNumber formats one line number right-aligned in width, blank for 0
+func gutterNumber(number, width int) string {
+	if number == 0 {
+		return strings.Repeat(" ", width)
+	}
+	return fmt.Sprintf("%*d", width, number)
+}
@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/compare"
+)
+
+// splitResults separates the identical entries, which are only listed while
+// showIdentical is on, from the differences
+func splitResults(results []compare.ComparisonResult) (differing, identical []compare.ComparisonResult) {
+	for _, result := range results {
+		if result.Status == compare.StatusIdentical {
+			identical = append(identical, result)
+		} else {
+			differing = append(differing, result)
+		}
+	}
+	return differing, identical
+}
+
+// setResults lists the differences of a comparison, and its identical
+// entries too while they are shown; hidden ones are kept aside for the toggle
+func (m *Model) setResults(results []compare.ComparisonResult) {
+	differing, identical := splitResults(results)
+	if m.showIdentical {
+		m.results = append(differing, identical...)
+		m.identicalResults = nil
+	} else {
+		m.results = differing
+		m.identicalResults = identical
+	}
+}
+
+// toggleIdentical shows or hides identical entries, keeping the selection
+// when it stays listed
+func (m *Model) toggleIdentical() {
+	selected, hasSelection := m.selectedResult()
+	m.showIdentical = !m.showIdentical
+	m.setResults(append(m.results, m.identicalResults...))
+	sortResults(m.results, m.sortMode)
+	for _, result := range m.results {
+		if _, ok := m.fileActions[result.RelativePath]; !ok {
+			m.fileActions[result.RelativePath] = action.ActionIgnore
+		}
+	}
+
+	m.pruneMarks()
+	m.rebuildVisible()
+	m.cursor = 0
+	if hasSelection {
+		m.selectPath(selected.RelativePath)
+	}
+	if m.showIdentical {
+		m.statusMessage = fmt.Sprintf("Showing identical files (%d)", m.countIdentical())
+	} else {
+		m.statusMessage = fmt.Sprintf("Hiding identical files (%d)", len(m.identicalResults))
+	}
+}
+
+// countIdentical returns the number of identical entries in the list
+func (m Model) countIdentical() int {
+	count := 0
+	for _, result := range m.results {
+		if result.Status == compare.StatusIdentical {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/compare"
+)
+
+// toggleMark marks or unmarks the selected file and moves to the next one,
+// so a run of files can be marked by pressing m repeatedly
+func (m *Model) toggleMark() {
+	result, ok := m.selectedResult()
+	if !ok {
+		return
+	}
+	if m.marked == nil {
+		m.marked = make(map[string]bool)
+	}
+	if m.marked[result.RelativePath] {
+		delete(m.marked, result.RelativePath)
+	} else {
+		m.marked[result.RelativePath] = true
+	}
+	if m.cursor < len(m.visible)-1 {
+		m.cursor++
+	}
+}
+
+// clearMarks unmarks every file
+func (m *Model) clearMarks() {
+	if len(m.marked) > 0 {
+		m.statusMessage = fmt.Sprintf("Cleared %d marks", len(m.marked))
+	}
+	m.marked = nil
+}
+
+// setMarkedAction sets an action on every marked file where it is valid and
+// then clears the marks. resolve returns the concrete action for a file
+// status. Files changed on disk since the scan only accept ignore.
+func (m *Model) setMarkedAction(label string, resolve func(compare.FileStatus) action.ActionType) {
+	set, invalid, stale := 0, 0, 0
+	for _, result := range m.results {
+		if !m.marked[result.RelativePath] {
+			continue
+		}
+		act := action.SymlinkCopyAction(resolve(result.Status), result)
+		if !isActionValid(act, result.Status) {
+			invalid++
+			continue
+		}
+		if act != action.ActionIgnore && len(m.staleSides(result)) > 0 {
+			stale++
+			continue
+		}
+		if m.fileActions[result.RelativePath] != act {
+			m.fileActions[result.RelativePath] = act
+			m.hasUnsavedChanges = true
+		}
+		set++
+	}
+	m.marked = nil
+
+	m.statusMessage = fmt.Sprintf("Set %s on %d marked files", label, set)
+	if invalid > 0 {
+		m.statusMessage += fmt.Sprintf(", skipped %d where it is not valid", invalid)
+	}
+	if stale > 0 {
+		m.statusMessage += fmt.Sprintf(", skipped %d changed on disk since scan (press u to update)", stale)
+	}
+}
+
+// pruneMarks drops marks on paths no longer in the results
+func (m *Model) pruneMarks() {
+	if len(m.marked) == 0 {
+		return
+	}
+	listed := make(map[string]bool, len(m.results))
+	for _, result := range m.results {
+		listed[result.RelativePath] = true
+	}
+	for path := range m.marked {
+		if !listed[path] {
+			delete(m.marked, path)
+		}
+	}
+}
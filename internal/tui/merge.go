@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultMergeTools are tried in order when $DOVETAIL_MERGE is not set
+var defaultMergeTools = []string{"vimdiff", "meld"}
+
+// mergeToolCommand returns the merge tool command split into words, from
+// $DOVETAIL_MERGE or else the first default tool found in the PATH
+func mergeToolCommand() []string {
+	if fields := strings.Fields(os.Getenv("DOVETAIL_MERGE")); len(fields) > 0 {
+		return fields
+	}
+	for _, tool := range defaultMergeTools {
+		if _, err := exec.LookPath(tool); err == nil {
+			return []string{tool}
+		}
+	}
+	return nil
+}
+
+// startMerge opens both sides of the selected file in the merge tool; the
+// diff is regenerated from the files on disk when the tool exits
+func (m Model) startMerge() (tea.Model, tea.Cmd) {
+	result, ok := m.selectedResult()
+	if !ok {
+		return m, nil
+	}
+	if result.LeftInfo == nil || result.RightInfo == nil ||
+		result.LeftInfo.IsDir || result.RightInfo.IsDir ||
+		result.LeftInfo.IsSymlink || result.RightInfo.IsSymlink {
+		m.statusMessage = fmt.Sprintf("Error: %s is not a file on both sides", result.RelativePath)
+		return m, nil
+	}
+	// Extracted archive entries are temporary copies; merges would be lost
+	if m.leftContent != m.leftDir || m.rightContent != m.rightDir {
+		m.statusMessage = "Error: files inside an archive can't be merged"
+		return m, nil
+	}
+
+	tool := mergeToolCommand()
+	if tool == nil {
+		m.statusMessage = "Error: no merge tool found; set $DOVETAIL_MERGE"
+		return m, nil
+	}
+
+	leftPath := filepath.Join(m.leftDir, result.LeftPath())
+	rightPath := filepath.Join(m.rightDir, result.RightPath())
+	cmd := exec.Command(tool[0], append(tool[1:], leftPath, rightPath)...)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{err: err, name: tool[0]}
+	})
+}
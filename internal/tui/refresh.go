@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/compare"
+)
+
+// resultsRefreshedMsg carries the results of a fresh comparison
+type resultsRefreshedMsg struct {
+	results []compare.ComparisonResult
+	summary *compare.ComparisonSummary
+	err     error
+}
+
+// refreshResults compares the directories again with the options the TUI was
+// started with, so exclusions and engine settings carry over
+func (m Model) refreshResults() tea.Cmd {
+	if m.leftContent != m.leftDir || m.rightContent != m.rightDir {
+		return func() tea.Msg {
+			return resultsRefreshedMsg{err: fmt.Errorf("refresh is not supported when comparing archives")}
+		}
+	}
+
+	options := m.compareOptions
+	leftDir, rightDir := m.leftDir, m.rightDir
+	return func() tea.Msg {
+		engine, err := compare.NewEngine(options)
+		if err != nil {
+			return resultsRefreshedMsg{err: err}
+		}
+		results, summary, err := engine.Compare(leftDir, rightDir)
+		return resultsRefreshedMsg{results: results, summary: summary, err: err}
+	}
+}
+
+// applyRefresh replaces the results, keeping the actions already chosen for
+// paths that still differ and the selection when it is still listed
+func (m *Model) applyRefresh(msg resultsRefreshedMsg) {
+	if msg.err != nil {
+		m.statusMessage = fmt.Sprintf("Error: refresh failed: %v", msg.err)
+		return
+	}
+
+	selected, hasSelection := m.selectedResult()
+	previous := m.fileActions
+	before := len(m.results)
+
+	m.setResults(msg.results)
+	m.summary = msg.summary
+	sortResults(m.results, m.sortMode)
+	m.initializeDefaultActions()
+	for _, result := range m.results {
+		if act, ok := previous[result.RelativePath]; ok && act != action.ActionIgnore && isActionValid(act, result.Status) {
+			m.fileActions[result.RelativePath] = act
+		}
+	}
+
+	m.pruneMarks()
+	m.rebuildVisible()
+	m.cursor = 0
+	if hasSelection {
+		m.selectPath(selected.RelativePath)
+	}
+	m.statusMessage = fmt.Sprintf("Refreshed: %d differences (was %d)", len(m.results), before)
+}
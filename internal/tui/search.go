@@ -0,0 +1,240 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/harikb/dovetail/internal/diff"
+)
+
+// Escape sequences used to highlight search matches without resetting diff colors
+const (
+	highlightOn  = "\x1b[7m"
+	highlightOff = "\x1b[27m"
+)
+
+// handleSearchInput processes keyboard input while the search prompt is active
+func (m Model) handleSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.searchActive = false
+		m.searchInput = ""
+	case tea.KeyEnter:
+		m.searchActive = false
+		if m.showingDiff {
+			m.searchQuery = m.searchInput
+			m.executeDiffSearch()
+		} else {
+			m.pathQuery = m.searchInput
+			m.executePathSearch()
+		}
+		m.searchInput = ""
+	case tea.KeyTab:
+		if !m.showingDiff {
+			m.substringSearch = !m.substringSearch
+		}
+	case tea.KeyBackspace:
+		if len(m.searchInput) > 0 {
+			runes := []rune(m.searchInput)
+			m.searchInput = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		m.searchInput += " "
+	case tea.KeyRunes:
+		m.searchInput += string(msg.Runes)
+	}
+
+	return m, nil
+}
+
+// executeDiffSearch finds all diff lines matching the current query and jumps to the first match
+func (m *Model) executeDiffSearch() {
+	m.diffMatches = nil
+	m.diffMatchIndex = 0
+
+	if m.searchQuery == "" {
+		return
+	}
+
+	query := strings.ToLower(m.searchQuery)
+	for i := 0; i < m.diffLineCount(); i++ {
+		if strings.Contains(strings.ToLower(m.diffLineText(i)), query) {
+			m.diffMatches = append(m.diffMatches, i)
+		}
+	}
+
+	// Start from the first match at or below the current viewport
+	for i, lineIndex := range m.diffMatches {
+		if lineIndex >= m.diffViewportTop {
+			m.diffMatchIndex = i
+			break
+		}
+	}
+
+	m.scrollToCurrentMatch()
+}
+
+// pathMatch is a file list entry matching the path search
+type pathMatch struct {
+	path      string
+	score     int   // Fuzzy score; higher ranks first
+	positions []int // Rune positions of the matched characters in path
+}
+
+// searchModeName names the path search mode for the prompt and status line
+func (m Model) searchModeName() string {
+	if m.substringSearch {
+		return "substring"
+	}
+	return "fuzzy"
+}
+
+// executePathSearch matches the visible paths against pathQuery, ranks them
+// best first (substring matches keep list order) and selects the best one
+func (m *Model) executePathSearch() {
+	m.pathMatches = nil
+	m.pathMatchIndex = 0
+	if m.pathQuery == "" {
+		return
+	}
+
+	for _, index := range m.visible {
+		path := m.results[index].RelativePath
+		if m.substringSearch {
+			if positions, ok := substringMatch(path, m.pathQuery); ok {
+				m.pathMatches = append(m.pathMatches, pathMatch{path: path, positions: positions})
+			}
+		} else if score, positions, ok := fuzzyMatch(path, m.pathQuery); ok {
+			m.pathMatches = append(m.pathMatches, pathMatch{path: path, score: score, positions: positions})
+		}
+	}
+	if !m.substringSearch {
+		// Ties go to the shorter path, then to list order
+		sort.SliceStable(m.pathMatches, func(i, j int) bool {
+			a, b := m.pathMatches[i], m.pathMatches[j]
+			if a.score != b.score {
+				return a.score > b.score
+			}
+			return len(a.path) < len(b.path)
+		})
+	}
+
+	if len(m.pathMatches) == 0 {
+		m.statusMessage = fmt.Sprintf("No %s matches for \"%s\"", m.searchModeName(), m.pathQuery)
+		return
+	}
+	m.selectPathMatch()
+}
+
+// nextPathMatch moves to the next (or previous) ranked path match, wrapping around
+func (m *Model) nextPathMatch(forward bool) {
+	if len(m.pathMatches) == 0 {
+		return
+	}
+	if forward {
+		m.pathMatchIndex = (m.pathMatchIndex + 1) % len(m.pathMatches)
+	} else {
+		m.pathMatchIndex = (m.pathMatchIndex - 1 + len(m.pathMatches)) % len(m.pathMatches)
+	}
+	m.selectPathMatch()
+}
+
+// selectPathMatch moves the cursor to the current path match
+func (m *Model) selectPathMatch() {
+	match := m.pathMatches[m.pathMatchIndex]
+	if !m.selectPath(match.path) {
+		m.statusMessage = fmt.Sprintf("%s is no longer listed", match.path)
+		return
+	}
+	m.statusMessage = fmt.Sprintf("Match %d/%d for \"%s\" (%s)", m.pathMatchIndex+1, len(m.pathMatches), m.pathQuery, m.searchModeName())
+}
+
+// highlightPath marks the characters of a listed path matched by the path search
+func (m Model) highlightPath(path string) string {
+	for _, match := range m.pathMatches {
+		if match.path == path {
+			return highlightPositions(path, match.positions)
+		}
+	}
+	return path
+}
+
+// nextDiffMatch moves to the next (or previous) search match, wrapping around
+func (m *Model) nextDiffMatch(forward bool) {
+	if len(m.diffMatches) == 0 {
+		return
+	}
+
+	if forward {
+		m.diffMatchIndex = (m.diffMatchIndex + 1) % len(m.diffMatches)
+	} else {
+		m.diffMatchIndex = (m.diffMatchIndex - 1 + len(m.diffMatches)) % len(m.diffMatches)
+	}
+
+	m.scrollToCurrentMatch()
+}
+
+// scrollToCurrentMatch sets the diff viewport so the current match is the top visible line
+func (m *Model) scrollToCurrentMatch() {
+	if len(m.diffMatches) == 0 {
+		return
+	}
+	m.diffViewportTop = m.diffMatches[m.diffMatchIndex]
+	m.clampDiffViewport()
+}
+
+// highlightSearch highlights occurrences of query in a (possibly ANSI-colored) line.
+// Matching is done on the stripped text and mapped back onto the colored original.
+func highlightSearch(line, query string) string {
+	if query == "" {
+		return line
+	}
+
+	// Map each visible byte offset to its offset in the raw string
+	var visible strings.Builder
+	var offsets []int
+	for i := 0; i < len(line); {
+		if loc := diff.ANSIEscape.FindStringIndex(line[i:]); loc != nil && loc[0] == 0 {
+			i += loc[1]
+			continue
+		}
+		visible.WriteByte(line[i])
+		offsets = append(offsets, i)
+		i++
+	}
+
+	text := visible.String()
+	lowerText := strings.ToLower(text)
+	lowerQuery := strings.ToLower(query)
+	if len(lowerText) != len(text) || len(lowerQuery) != len(query) {
+		// Case folding changed byte lengths; offsets would not line up
+		return line
+	}
+
+	var b strings.Builder
+	rawPos := 0
+	for searchFrom := 0; ; {
+		idx := strings.Index(lowerText[searchFrom:], lowerQuery)
+		if idx < 0 {
+			break
+		}
+		start := searchFrom + idx
+		end := start + len(lowerQuery)
+		rawStart := offsets[start]
+		rawEnd := offsets[end-1] + 1
+
+		b.WriteString(line[rawPos:rawStart])
+		b.WriteString(highlightOn)
+		b.WriteString(line[rawStart:rawEnd])
+		b.WriteString(highlightOff)
+		rawPos = rawEnd
+		searchFrom = end
+	}
+	b.WriteString(line[rawPos:])
+
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/harikb/dovetail/internal/diff"
+	"github.com/harikb/dovetail/internal/theme"
+)
+
+// minSideBySideWidth is the narrowest terminal that can show two useful columns
+const minSideBySideWidth = 60
+
+// sideBySideRow is one row of the two-column diff view
+type sideBySideRow struct {
+	header    string // Hunk header; when set the row spans both columns
+	left      string
+	right     string
+	leftKind  diff.LineKind
+	rightKind diff.LineKind
+	hasLeft   bool
+	hasRight  bool
+	leftLine  int // Line number in the left file (0 when hasLeft is false)
+	rightLine int
+}
+
+// buildSideBySideRows aligns hunk lines into left/right rows. Context lines
+// appear on both sides; runs of removed lines are paired with the added
+// lines that follow them.
+func buildSideBySideRows(hunks []diff.Hunk) []sideBySideRow {
+	var rows []sideBySideRow
+
+	for _, hunk := range hunks {
+		rows = append(rows, sideBySideRow{header: hunk.Header})
+		leftLine, rightLine := hunk.LeftStart, hunk.RightStart
+
+		var removed, added []diff.Line
+		flush := func() {
+			for i := 0; i < len(removed) || i < len(added); i++ {
+				row := sideBySideRow{}
+				if i < len(removed) {
+					row.left, row.leftKind, row.hasLeft = removed[i].Text, diff.LineRemoved, true
+					row.leftLine = leftLine
+					leftLine++
+				}
+				if i < len(added) {
+					row.right, row.rightKind, row.hasRight = added[i].Text, diff.LineAdded, true
+					row.rightLine = rightLine
+					rightLine++
+				}
+				rows = append(rows, row)
+			}
+			removed, added = nil, nil
+		}
+
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case diff.LineRemoved:
+				if len(added) > 0 {
+					flush()
+				}
+				removed = append(removed, line)
+			case diff.LineAdded:
+				added = append(added, line)
+			default:
+				flush()
+				rows = append(rows, sideBySideRow{
+					left: line.Text, right: line.Text,
+					leftKind: diff.LineContext, rightKind: diff.LineContext,
+					hasLeft: true, hasRight: true,
+					leftLine: leftLine, rightLine: rightLine,
+				})
+				leftLine++
+				rightLine++
+			}
+		}
+		flush()
+	}
+
+	return rows
+}
+
+// text returns the searchable plain text of a row
+func (r sideBySideRow) text() string {
+	if r.header != "" {
+		return r.header
+	}
+	return r.left + "\t" + r.right
+}
+
+// sideBySideGutterWidth returns the digits needed for the largest line number
+// in rows, or 0 when there are none
+func sideBySideGutterWidth(rows []sideBySideRow) int {
+	gutters := make([]lineGutter, len(rows))
+	for i, row := range rows {
+		gutters[i] = lineGutter{left: row.leftLine, right: row.rightLine}
+	}
+	return gutterWidth(gutters)
+}
+
+// renderSideBySideRow renders a row with each column fitted to columnWidth.
+// With a gutter width, each column starts with its line number.
+func renderSideBySideRow(palette theme.Palette, row sideBySideRow, columnWidth, gutter int, query string) string {
+	if row.header != "" {
+		header := fitColumn(row.header, columnWidth*2+3)
+		return paletteStyle(palette, palette.Hunk).Render(highlightSearch(header, query))
+	}
+
+	left := renderColumn(palette, row.left, row.leftKind, row.hasLeft, row.leftLine, columnWidth, gutter, query)
+	right := renderColumn(palette, row.right, row.rightKind, row.hasRight, row.rightLine, columnWidth, gutter, query)
+	separator := paletteStyle(palette, palette.Muted).Render(" │ ")
+
+	return left + separator + right
+}
+
+// renderColumn renders one side of a row, colored by line kind, after a
+// gutter holding its line number when gutter is non-zero
+func renderColumn(palette theme.Palette, text string, kind diff.LineKind, present bool, number, width, gutter int, query string) string {
+	if !present {
+		return strings.Repeat(" ", width)
+	}
+
+	prefix := ""
+	if gutter > 0 && gutter+1 < width {
+		prefix = paletteStyle(palette, palette.Muted).Render(gutterNumber(number, gutter) + " ")
+		width -= gutter + 1
+	}
+	cell := highlightSearch(fitColumn(text, width), query)
+	switch kind {
+	case diff.LineRemoved:
+		return prefix + paletteStyle(palette, palette.Removed).Render(cell)
+	case diff.LineAdded:
+		return prefix + paletteStyle(palette, palette.Added).Render(cell)
+	default:
+		return prefix + cell
+	}
+}
+
+// fitColumn expands tabs, then truncates or pads text to exactly width runes
+func fitColumn(text string, width int) string {
+	runes := []rune(strings.ReplaceAll(text, "\t", "    "))
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harikb/dovetail/internal/action"
+	"github.com/harikb/dovetail/internal/compare"
+)
+
+// staleSides names the sides of result whose entry was created, removed or
+// rewritten on disk since the comparison. Archive sides can't change.
+func (m Model) staleSides(result compare.ComparisonResult) []string {
+	var sides []string
+	for _, side := range []struct {
+		name          string
+		root, content string
+		path          string
+		info          *compare.FileInfo
+	}{
+		{"left", m.leftDir, m.leftContent, result.LeftPath(), result.LeftInfo},
+		{"right", m.rightDir, m.rightContent, result.RightPath(), result.RightInfo},
+	} {
+		if side.content != side.root {
+			continue
+		}
+		fullPath := filepath.Join(side.root, side.path)
+		current, err := os.Lstat(fullPath)
+		if err == nil && current.Mode()&os.ModeSymlink != 0 && m.compareOptions.FollowSymlinks {
+			current, err = os.Stat(fullPath)
+		}
+
+		switch {
+		case side.info == nil:
+			if err == nil {
+				sides = append(sides, side.name)
+			}
+		case err != nil:
+			sides = append(sides, side.name)
+		case side.info.IsDir:
+			// Directory sizes and times change with their contents; only
+			// appearing or disappearing counts
+		case current.Size() != side.info.Size || !current.ModTime().Equal(side.info.ModTime):
+			sides = append(sides, side.name)
+		}
+	}
+	return sides
+}
+
+// staleWarning describes a result that changed on disk since the scan, or
+// returns "" when it is still current
+func (m Model) staleWarning(result compare.ComparisonResult) string {
+	sides := m.staleSides(result)
+	if len(sides) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Warning: %s changed on disk since scan (%s); press u to update it",
+		result.RelativePath, strings.Join(sides, ", "))
+}
+
+// updateSelected compares the selected entry again, replacing its result. An
+// entry that became identical leaves the list unless identical files are
+// shown; an action that no longer fits the new status is reset to ignore.
+func (m *Model) updateSelected() {
+	result, ok := m.selectedResult()
+	if !ok {
+		return
+	}
+	if m.leftContent != m.leftDir || m.rightContent != m.rightDir {
+		m.statusMessage = "Error: update is not supported when comparing archives"
+		return
+	}
+
+	index := m.visible[m.cursor]
+	engine, err := compare.NewEngine(m.compareOptions)
+	if err != nil {
+		m.statusMessage = fmt.Sprintf("Error: update failed: %v", err)
+		return
+	}
+	updated, err := engine.ComparePath(m.leftDir, m.rightDir, result.LeftPath(), result.RightPath())
+	if err != nil {
+		m.statusMessage = fmt.Sprintf("Error: update failed: %v", err)
+		return
+	}
+
+	if updated.Status == compare.StatusIdentical && !m.showIdentical {
+		m.identicalResults = append(m.identicalResults, updated)
+		m.results = append(m.results[:index], m.results[index+1:]...)
+		delete(m.fileActions, result.RelativePath)
+		delete(m.marked, result.RelativePath)
+		m.rebuildVisible()
+		if m.cursor >= len(m.visible) && len(m.visible) > 0 {
+			m.cursor = len(m.visible) - 1
+		}
+		m.statusMessage = fmt.Sprintf("Updated %s: now identical", result.RelativePath)
+		return
+	}
+
+	m.results[index] = updated
+	if act := m.fileActions[updated.RelativePath]; !isActionValid(act, updated.Status) {
+		m.fileActions[updated.RelativePath] = action.ActionIgnore
+	}
+	m.rebuildVisible()
+	m.selectPath(updated.RelativePath)
+	m.statusMessage = fmt.Sprintf("Updated %s: %s", updated.RelativePath, updated.Status)
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes path through write. The content goes to a temporary
+// file in the same directory that is synced and renamed over path only once
+// write succeeds, so a failed or interrupted write never leaves path
+// half-written. An existing file keeps its mode; a new one gets perm less the
+// umask, as os.WriteFile would. The owner is kept where permitted. A symlink is followed and its target written.
+// Targets that aren't regular files, such as /dev/null or a FIFO, can't be
+// replaced and are written directly instead.
+func WriteFileAtomic(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			// A dangling link: writing through it creates its target
+			return writeInPlace(path, perm, write)
+		}
+		path = resolved
+	}
+
+	existing, err := os.Stat(path)
+	if err == nil && !existing.Mode().IsRegular() {
+		return writeInPlace(path, perm, write)
+	}
+	keepMode := err == nil
+
+	tmp, err := createTemp(filepath.Dir(path), filepath.Base(path), perm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if keepMode {
+		// Best effort: only root can give a file to another owner
+		if uid, gid, ok := FileOwner(existing); ok && (uid != os.Getuid() || gid != os.Getgid()) {
+			os.Chown(tmp.Name(), uid, gid)
+		}
+		mode := existing.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+		if err := os.Chmod(tmp.Name(), mode); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeInPlace writes path through write without replacing it
+func writeInPlace(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// createTemp creates a new hidden file in dir named after base. Unlike
+// os.CreateTemp it creates the file with perm, so the umask applies.
+func createTemp(dir, base string, perm os.FileMode) (*os.File, error) {
+	for attempt := 0; ; attempt++ {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d", base, rand.Uint32()))
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) && attempt < 100 {
+			continue
+		}
+		return file, err
+	}
+}
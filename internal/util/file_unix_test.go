@@ -0,0 +1,111 @@
+//go:build unix
+
+package util
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// writeString returns a write function for WriteFileAtomic writing s
+func writeString(s string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func TestWriteFileAtomicDevice(t *testing.T) {
+	if err := WriteFileAtomic(os.DevNull, 0644, writeString("discarded\n")); err != nil {
+		t.Fatalf("WriteFileAtomic(%s): %v", os.DevNull, err)
+	}
+	info, err := os.Lstat(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		t.Fatalf("%s is no longer a character device: %v", os.DevNull, info.Mode())
+	}
+}
+
+func TestWriteFileAtomicFIFO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fifo")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Skipf("cannot create a FIFO: %v", err)
+	}
+
+	read := make(chan string)
+	go func() {
+		data, _ := os.ReadFile(path)
+		read <- string(data)
+	}()
+	if err := WriteFileAtomic(path, 0644, writeString("through the pipe\n")); err != nil {
+		t.Fatalf("WriteFileAtomic: %v", err)
+	}
+	if got := <-read; got != "through the pipe\n" {
+		t.Errorf("reader got %q", got)
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		t.Fatalf("FIFO was replaced: %v", info.Mode())
+	}
+}
+
+func TestWriteFileAtomicSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	link := filepath.Join(dir, "link.txt")
+	if err := os.WriteFile(target, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("target.txt", link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(link, 0644, writeString("new\n")); err != nil {
+		t.Fatalf("WriteFileAtomic: %v", err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link was replaced: %v, %v", info, err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new\n" {
+		t.Errorf("target contains %q, want %q", data, "new\n")
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("target mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileAtomicDanglingSymlink(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink("missing.txt", link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(link, 0644, writeString("created\n")); err != nil {
+		t.Fatalf("WriteFileAtomic: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link was replaced: %v, %v", info, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "missing.txt")); err != nil || string(data) != "created\n" {
+		t.Errorf("target = %q, %v", data, err)
+	}
+}
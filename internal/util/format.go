@@ -1,6 +1,11 @@
 package util
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 // FormatSize formats a file size in human-readable format
 func FormatSize(size int64) string {
@@ -17,3 +22,36 @@ func FormatSize(size int64) string {
 
 	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
 }
+
+// FormatSizeDelta formats a signed size difference, e.g. "+1.5 KB" or "-200 B"
+func FormatSizeDelta(delta int64) string {
+	switch {
+	case delta > 0:
+		return "+" + FormatSize(delta)
+	case delta < 0:
+		return "-" + FormatSize(-delta)
+	default:
+		return FormatSize(0)
+	}
+}
+
+// ParseSize parses a size such as "4096", "64K", "1.5MB" or "2GiB". Units
+// are binary, as in FormatSize, so "1K" and "1KB" are both 1024 bytes.
+func ParseSize(text string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(text))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		if exp := strings.IndexByte("KMGTPE", s[n-1]); exp >= 0 {
+			multiplier = int64(1) << (10 * (exp + 1))
+			s = s[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(value) || value < 0 || value*float64(multiplier) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: use bytes or a K, M, G or T suffix", text)
+	}
+	return int64(value * float64(multiplier)), nil
+}
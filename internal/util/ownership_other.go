@@ -0,0 +1,10 @@
+//go:build !unix
+
+package util
+
+import "os"
+
+// FileOwner returns the uid and gid of a file, if available on this platform
+func FileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
@@ -3,6 +3,8 @@ package util
 import (
 	"fmt"
 	"os"
+	"sync"
+	"time"
 )
 
 // VerboseCallback is a callback function for progress updates
@@ -21,13 +23,25 @@ func VerbosePrintf(currentLevel, requiredLevel int, format string, args ...inter
 	}
 }
 
-// ProgressReporter helps with progress reporting
+// ProgressFunc receives progress updates: items done out of total, and
+// time elapsed since the reporter was created
+type ProgressFunc func(done, total int, elapsed time.Duration)
+
+// progressFuncInterval limits how often a ProgressFunc is called
+const progressFuncInterval = 100 * time.Millisecond
+
+// ProgressReporter helps with progress reporting. It is safe for concurrent use.
 type ProgressReporter struct {
+	mu              sync.Mutex
 	verboseLevel    int
 	currentCount    int
 	totalCount      int
 	lastReportCount int
 	reportInterval  int
+	startTime       time.Time
+	onProgress      ProgressFunc
+	lastProgress    time.Time
+	eachItem        bool // Print every item from verbosity level 1
 }
 
 // NewProgressReporter creates a new progress reporter
@@ -43,16 +57,51 @@ func NewProgressReporter(verboseLevel, totalCount int) *ProgressReporter {
 		verboseLevel:   verboseLevel,
 		totalCount:     totalCount,
 		reportInterval: reportInterval,
+		startTime:      time.Now(),
 	}
 }
 
+// SetProgressFunc registers a callback for progress updates, independent of
+// the verbosity level. Calls are throttled, except the final one. It must be
+// called before reporting starts.
+func (pr *ProgressReporter) SetProgressFunc(fn ProgressFunc) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.onProgress = fn
+}
+
+// ReportEachItem prints a "[n/total]" line for every item from verbosity
+// level 1, for slow items such as file operations where a running log is
+// more useful than periodic counts. It must be called before reporting starts.
+func (pr *ProgressReporter) ReportEachItem() {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.eachItem = true
+}
+
 // Report increments the counter and reports progress if needed
 func (pr *ProgressReporter) Report(format string, args ...interface{}) {
+	// Nothing to report: skip the lock and bookkeeping entirely
+	if pr.verboseLevel <= 0 && pr.onProgress == nil {
+		return
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
 	pr.currentCount++
 
-	// Always report in debug mode (level 3+)
-	if pr.verboseLevel >= 3 {
-		VerbosePrintf(pr.verboseLevel, 3, "[%d/%d] "+format, append([]interface{}{pr.currentCount, pr.totalCount}, args...)...)
+	if pr.onProgress != nil {
+		now := time.Now()
+		if now.Sub(pr.lastProgress) >= progressFuncInterval || pr.currentCount == pr.totalCount {
+			pr.lastProgress = now
+			pr.onProgress(pr.currentCount, pr.totalCount, now.Sub(pr.startTime))
+		}
+	}
+
+	// Always report in debug mode (level 3+), or from level 1 when asked to
+	if pr.verboseLevel >= 3 || (pr.eachItem && pr.verboseLevel >= 1) {
+		VerbosePrintf(pr.verboseLevel, 1, "[%d/%d] "+format, append([]interface{}{pr.currentCount, pr.totalCount}, args...)...)
 		return
 	}
 
@@ -61,19 +110,39 @@ func (pr *ProgressReporter) Report(format string, args ...interface{}) {
 		if pr.verboseLevel >= 2 {
 			VerbosePrintf(pr.verboseLevel, 2, "[%d/%d] "+format, append([]interface{}{pr.currentCount, pr.totalCount}, args...)...)
 		} else if pr.verboseLevel >= 1 && (pr.currentCount%1000 == 0 || pr.currentCount == pr.totalCount) {
-			VerbosePrintf(pr.verboseLevel, 1, "Processed %d/%d files...", pr.currentCount, pr.totalCount)
+			VerbosePrintf(pr.verboseLevel, 1, "Processed files: %s",
+				FormatProgress(pr.currentCount, pr.totalCount, time.Since(pr.startTime)))
 		}
 	}
 }
 
 // SetTotal updates the total count (useful when the total is not known initially)
 func (pr *ProgressReporter) SetTotal(total int) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
 	pr.totalCount = total
 }
 
 // Finish reports completion
 func (pr *ProgressReporter) Finish() {
 	if pr.verboseLevel >= 1 {
-		VerbosePrintf(pr.verboseLevel, 1, "Completed processing %d files", pr.currentCount)
+		VerbosePrintf(pr.verboseLevel, 1, "Completed processing %d files in %s",
+			pr.currentCount, time.Since(pr.startTime).Round(time.Millisecond))
 	}
 }
+
+// FormatProgress formats done/total as "450/1000 (45%, ETA 12s)". The ETA
+// assumes the remaining items take as long on average as those done so far.
+func FormatProgress(done, total int, elapsed time.Duration) string {
+	if total <= 0 {
+		return fmt.Sprintf("%d", done)
+	}
+
+	percent := done * 100 / total
+	if done == 0 || done >= total {
+		return fmt.Sprintf("%d/%d (%d%%)", done, total, percent)
+	}
+
+	eta := time.Duration(float64(elapsed) / float64(done) * float64(total-done))
+	return fmt.Sprintf("%d/%d (%d%%, ETA %s)", done, total, percent, eta.Round(time.Second))
+}